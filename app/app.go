@@ -2,8 +2,10 @@ package app
 
 import (
 	"bankManagement/constants"
+	"bankManagement/middlewares/requestid"
 	"bankManagement/repository"
 	"bankManagement/utils/log"
+	"bankManagement/utils/scheduler"
 	"net/http"
 	"sync"
 	"time"
@@ -22,6 +24,7 @@ type App struct {
 	Log        log.WebLogger
 	WG         *sync.WaitGroup
 	Repository repository.Repository
+	Scheduler  *scheduler.Scheduler
 }
 
 func NewApp(name string, db *gorm.DB, log log.WebLogger, wg *sync.WaitGroup, repository repository.Repository) *App {
@@ -37,9 +40,13 @@ func NewApp(name string, db *gorm.DB, log log.WebLogger, wg *sync.WaitGroup, rep
 func (app *App) Init() {
 	app.initializeRouter()
 	app.initializeServer()
+	app.Scheduler = scheduler.NewScheduler(constants.SchedulerInterval, app.Log, app.WG)
 }
 
+// StartServer starts the background scheduler alongside the HTTP server and stops it when the server exits.
 func (app *App) StartServer() error {
+	app.Scheduler.Start()
+	defer app.Scheduler.Stop()
 	err := app.Server.ListenAndServe()
 	if err != nil {
 		app.Log.Error(err.Error())
@@ -55,8 +62,10 @@ func (app *App) initializeRouter() {
 }
 func (app *App) initializeServer() {
 	headers := handlers.AllowedHeaders([]string{
-		"Content-Type", "X-Total-Count", "token",
+		"Content-Type", "X-Total-Count", "token", "Idempotency-Key", "X-2FA-Code", constants.RequestIDHeader,
 	})
+	// browsers only let scripts read these on cross-origin list responses when exposed
+	exposedHeaders := handlers.ExposedHeaders([]string{"X-Total-Count", "Link", "Authorization", "Refresh-Token", constants.RequestIDHeader})
 	methods := handlers.AllowedMethods([]string{
 		http.MethodPost, http.MethodPut, http.MethodGet, http.MethodDelete, http.MethodOptions,
 	})
@@ -66,7 +75,7 @@ func (app *App) initializeServer() {
 		ReadTimeout:  time.Second * 60,
 		WriteTimeout: time.Second * 60,
 		IdleTimeout:  time.Second * 60,
-		Handler:      handlers.CORS(headers, methods, originOption)(app.Router),
+		Handler:      handlers.CORS(headers, exposedHeaders, methods, originOption)(requestid.RequestIDMiddleware(app.Router)),
 	}
 	app.Log.Info("Server Exposed On 4000")
 }
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"bankManagement/components/audit/service"
+	"bankManagement/constants"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/audit"
+	"bankManagement/models/user"
+	"bankManagement/utils/encrypt"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type AuditController struct {
+	AuditService *service.AuditService
+	log          log.WebLogger
+}
+
+func NewAuditController(
+	AuditService *service.AuditService,
+	log log.WebLogger,
+) *AuditController {
+	return &AuditController{
+		AuditService: AuditService,
+		log:          log,
+	}
+}
+
+var auditListOptions = web.ListOptions{
+	SortFields: map[string]string{"sequence": "sequence", "timestamp": "timestamp"},
+	FilterFields: map[string]string{
+		"actor_user_id": "actor_user_id",
+		"action":        "action",
+		"entity_type":   "entity_type",
+		"entity_id":     "entity_id",
+		"bank_id":       "bank_id",
+		"request_id":    "request_id",
+	},
+	DefaultSort: "-sequence",
+}
+
+func (ctrl *AuditController) RegisterRoutes(router *mux.Router) {
+	auditRouter := router.PathPrefix("/audit").Subrouter()
+	auditRouter.Use(auth.AuthenticationMiddleware)
+	auditRouter.Handle("/entries", auth.Require(ctrl.GetAllEntries, user.PermissionAuditRead)).Methods(http.MethodGet)
+	auditRouter.Handle("/verify", auth.Require(ctrl.VerifyChain, user.PermissionAuditRead)).Methods(http.MethodGet)
+
+	bankRouter := router.PathPrefix("/banks/{bank_id}/audit").Subrouter()
+	bankRouter.Use(auth.AuthenticationMiddleware, auth.ValidateBankPermissionsMiddleware)
+	bankRouter.Handle("/entries", auth.Require(ctrl.GetBankEntries, user.PermissionBankAuditRead)).Methods(http.MethodGet)
+}
+
+func (ctrl *AuditController) GetAllEntries(w http.ResponseWriter, r *http.Request) {
+	ctrl.sendEntries(w, r, 0)
+}
+
+// GetBankEntries lists the entries of the bank the caller works for: changes to the bank,
+// its users, its clients and their rows.
+func (ctrl *AuditController) GetBankEntries(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	ctrl.sendEntries(w, r, claims.BankId)
+}
+
+func (ctrl *AuditController) sendEntries(w http.ResponseWriter, r *http.Request, bankId uint) {
+	query, err := web.ParseListQuery(r, auditListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var entries []audit.AuditEntry
+	err = ctrl.AuditService.GetEntries(bankId, query, &entries)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	web.SetListHeaders(w, r, query)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Audit Entries Retrieved",
+		Data:       entries,
+	})
+}
+
+// VerifyChain walks the whole chain; a broken chain is reported in the data, not as an error.
+func (ctrl *AuditController) VerifyChain(w http.ResponseWriter, r *http.Request) {
+	verification, err := ctrl.AuditService.VerifyChain()
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Audit Chain Verified",
+		Data:       verification,
+	})
+}
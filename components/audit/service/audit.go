@@ -0,0 +1,295 @@
+package service
+
+import (
+	"bankManagement/models/audit"
+	"bankManagement/models/bank"
+	"bankManagement/models/client"
+	"bankManagement/models/idempotency"
+	"bankManagement/models/notification"
+	"bankManagement/models/outbox"
+	"bankManagement/models/user"
+	"bankManagement/models/webhook"
+	"bankManagement/repository"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// AuditService appends every change made through the repository to the hash-chained audit
+// log, in the transaction of the change, and serves and verifies the log.
+type AuditService struct {
+	DB         *gorm.DB
+	repository repository.Repository
+	log        log.WebLogger
+}
+
+func NewAuditService(DB *gorm.DB, repository repository.Repository, log log.WebLogger) *AuditService {
+	return &AuditService{
+		DB:         DB,
+		repository: repository,
+		log:        log,
+	}
+}
+
+// models whose changes are not recorded: the log itself, and bookkeeping that changes on
+// every request without being an operation of anyone
+var unrecordedModels = map[reflect.Type]bool{
+	reflect.TypeOf(audit.AuditEntry{}):              true,
+	reflect.TypeOf(audit.AuditChainHead{}):          true,
+	reflect.TypeOf(user.LoginThrottle{}):            true,
+	reflect.TypeOf(user.RefreshToken{}):             true,
+	reflect.TypeOf(idempotency.IdempotencyRecord{}): true,
+	reflect.TypeOf(outbox.OutboxEvent{}):            true,
+	reflect.TypeOf(outbox.OutboxDelivery{}):         true,
+	reflect.TypeOf(webhook.WebhookDelivery{}):       true,
+	reflect.TypeOf(notification.Notification{}):     true,
+}
+
+// snapshot fields holding credentials, at any depth, are replaced by redacted
+var secretFields = map[string]bool{"password": true, "password_hash": true, "secret": true, "token": true, "token_hash": true, "code_hash": true}
+
+const redacted = "[REDACTED]"
+
+// the fields naming the bank a row belongs to, else the client it belongs to
+var bankFields = []string{"BankID", "BankId", "AuthorizerBankId", "AuthorizedBankID"}
+var clientFields = []string{"ClientID", "ClientId", "SenderClientID"}
+
+func (service *AuditService) Records(value interface{}) bool {
+	modelType := reflect.TypeOf(value)
+	for modelType != nil && modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	return modelType != nil && modelType.Kind() == reflect.Struct && !unrecordedModels[modelType]
+}
+
+// RecordChange appends the entry for one change, locking the chain head so entries are
+// appended one at a time.
+func (service *AuditService) RecordChange(uow *repository.UOW, action string, before interface{}, after interface{}) error {
+	subject := after
+	if subject == nil {
+		subject = before
+	}
+	scope := uow.DB.NewScope(subject)
+	actor, _ := uow.Actor().(*audit.Actor)
+	if actor == nil {
+		actor = &audit.Actor{}
+	}
+	beforeSnapshot, err := snapshot(before)
+	if err != nil {
+		return err
+	}
+	afterSnapshot, err := snapshot(after)
+	if err != nil {
+		return err
+	}
+	bankId, err := service.entryBankId(uow, scope, actor)
+	if err != nil {
+		return err
+	}
+
+	head := audit.AuditChainHead{}
+	err = service.repository.GetByIDForUpdate(uow, &head, audit.ChainHeadID)
+	if err != nil {
+		return fmt.Errorf("audit chain head not found: %w", err)
+	}
+	now := time.Now()
+	entry := audit.AuditEntry{
+		CreatedAt:      now,
+		Sequence:       head.Sequence + 1,
+		Timestamp:      now.UnixMilli(),
+		ActorUserId:    actor.UserId,
+		ActorRoleId:    actor.RoleId,
+		ActorBankId:    actor.BankId,
+		ActorClientId:  actor.ClientId,
+		LoginSessionId: actor.LoginSessionId,
+		IPAddress:      actor.IPAddress,
+		Operation:      actor.Operation,
+		RequestId:      actor.RequestId,
+		Action:         action,
+		EntityType:     scope.TableName(),
+		EntityId:       primaryKey(scope),
+		BankId:         bankId,
+		Before:         beforeSnapshot,
+		After:          afterSnapshot,
+		PrevHash:       head.LastHash,
+	}
+	entry.Hash = entry.ComputeHash()
+	err = service.repository.Add(uow, &entry)
+	if err != nil {
+		return err
+	}
+	head.Sequence = entry.Sequence
+	head.LastHash = entry.Hash
+	return service.repository.Update(uow, &head)
+}
+
+// GetEntries lists the log, only the entries of bankId when it is not zero.
+func (service *AuditService) GetEntries(bankId uint, query *web.ListQuery, entries *[]audit.AuditEntry) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	var queryProcessors []repository.QueryProcessor
+	if bankId != 0 {
+		queryProcessors = append(queryProcessors, service.repository.Filter("bank_id = ?", bankId))
+	}
+	err := service.repository.GetAll(uow, entries, append(queryProcessors, query.QueryProcessors(service.repository)...)...)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+const verifyBatchSize = 500
+
+// VerifyChain recomputes every hash from the first entry and checks the last one is the
+// chain head, reporting the first entry that does not match.
+func (service *AuditService) VerifyChain() (*audit.AuditVerificationDTO, error) {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	result := &audit.AuditVerificationDTO{Valid: true}
+	previousHash := audit.GenesisHash
+	var sequence int64
+	for {
+		var entries []audit.AuditEntry
+		err := service.repository.GetAll(uow, &entries,
+			service.repository.Filter("sequence > ?", sequence),
+			service.repository.Order("sequence asc"),
+			service.repository.Limit(verifyBatchSize),
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			problem := ""
+			if entry.Sequence != sequence+1 {
+				problem = fmt.Sprintf("entry %d is missing", sequence+1)
+			} else if entry.PrevHash != previousHash {
+				problem = "previous hash does not match the previous entry"
+			} else if entry.ComputeHash() != entry.Hash {
+				problem = "content does not match its hash"
+			}
+			if problem != "" {
+				result.Valid = false
+				result.FirstInvalidSequence = sequence + 1
+				result.Problem = problem
+				return result, nil
+			}
+			result.EntriesChecked++
+			sequence = entry.Sequence
+			previousHash = entry.Hash
+		}
+		if len(entries) < verifyBatchSize {
+			break
+		}
+	}
+	head := audit.AuditChainHead{}
+	err := service.repository.GetByID(uow, &head, audit.ChainHeadID)
+	if err != nil {
+		return nil, err
+	}
+	if head.Sequence != sequence || head.LastHash != previousHash {
+		result.Valid = false
+		result.FirstInvalidSequence = sequence + 1
+		result.Problem = "entries after the last valid one have been removed"
+	}
+	uow.Commit()
+	return result, nil
+}
+
+// entryBankId is the bank the changed row belongs to, directly or through its client, else
+// the bank the actor works for.
+func (service *AuditService) entryBankId(uow *repository.UOW, scope *gorm.Scope, actor *audit.Actor) (uint, error) {
+	if scope.GetModelStruct().ModelType == reflect.TypeOf(bank.Bank{}) {
+		if id, ok := scope.PrimaryKeyValue().(uint); ok {
+			return id, nil
+		}
+	}
+	if id := uintField(scope, bankFields); id != 0 {
+		return id, nil
+	}
+	clientId := uintField(scope, clientFields)
+	if clientId == 0 && actor.BankId != 0 {
+		return actor.BankId, nil
+	}
+	if clientId == 0 {
+		clientId = actor.ClientId
+	}
+	if clientId == 0 {
+		return 0, nil
+	}
+	var clients []client.Client
+	err := service.repository.GetAll(uow, &clients, service.repository.Filter("id = ?", clientId))
+	if err != nil || len(clients) == 0 {
+		return actor.BankId, err
+	}
+	return clients[0].BankID, nil
+}
+
+func uintField(scope *gorm.Scope, names []string) uint {
+	for _, name := range names {
+		field, ok := scope.FieldByName(name)
+		if !ok || field.IsBlank {
+			continue
+		}
+		if id, ok := field.Field.Interface().(uint); ok {
+			return id
+		}
+	}
+	return 0
+}
+
+func primaryKey(scope *gorm.Scope) string {
+	var keys []string
+	for _, field := range scope.PrimaryFields() {
+		keys = append(keys, fmt.Sprint(field.Field.Interface()))
+	}
+	return strings.Join(keys, ",")
+}
+
+// snapshot is the row as JSON with the credentials redacted, empty for no row.
+func snapshot(value interface{}) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	// numbers stay as written, amounts and ids must not go through float64
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded interface{}
+	err = decoder.Decode(&decoded)
+	if err != nil {
+		return "", err
+	}
+	encoded, err = json.Marshal(redact(decoded))
+	return string(encoded), err
+}
+
+func redact(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, field := range typed {
+			if secretFields[strings.ToLower(key)] {
+				if field != "" && field != nil {
+					typed[key] = redacted
+				}
+				continue
+			}
+			typed[key] = redact(field)
+		}
+	case []interface{}:
+		for i := range typed {
+			typed[i] = redact(typed[i])
+		}
+	}
+	return value
+}
@@ -2,13 +2,18 @@ package controller
 
 import (
 	"bankManagement/components/auth/service"
+	"bankManagement/constants"
+	"bankManagement/middlewares/auth"
 	"bankManagement/models/user"
 	"bankManagement/utils/encrypt"
 	errorsUtils "bankManagement/utils/errors"
 	"bankManagement/utils/log"
 	"bankManagement/utils/web"
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 )
@@ -33,6 +38,11 @@ func (ctrl *AuthController) RegisterRoutes(
 	subRouter := router.NewRoute().Subrouter()
 	subRouter.HandleFunc("/login", ctrl.LoginApi).Methods(http.MethodPost)
 	subRouter.HandleFunc("/register-admin", ctrl.RegisterAdmin).Methods(http.MethodPost)
+	subRouter.HandleFunc("/.well-known/jwks.json", ctrl.GetJWKS).Methods(http.MethodGet)
+	subRouter.HandleFunc("/auth/refresh", ctrl.RefreshApi).Methods(http.MethodPost)
+	subRouter.Handle("/auth/logout", auth.AuthenticationMiddleware(http.HandlerFunc(ctrl.LogoutApi))).Methods(http.MethodPost)
+	ctrl.registerTwoFactorRoutes(subRouter)
+	ctrl.registerPasswordRoutes(subRouter)
 }
 
 func (ctrl *AuthController) LoginApi(w http.ResponseWriter, r *http.Request) {
@@ -49,7 +59,7 @@ func (ctrl *AuthController) LoginApi(w http.ResponseWriter, r *http.Request) {
 		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), 400)
 		return
 	}
-	//
+	loginCreds.IPAddress = web.ClientIP(r)
 	var authenticatedUser = &user.User{}
 	permissions := user.UserPermissionDTO{
 		BankId:       0,
@@ -57,24 +67,111 @@ func (ctrl *AuthController) LoginApi(w http.ResponseWriter, r *http.Request) {
 		IsSuperAdmin: false,
 	}
 	var loginSessionId uint = 0
-	err = ctrl.AuthService.LoginRequest(loginCreds, authenticatedUser, &permissions, &loginSessionId)
+	var refreshToken string
+	challenge := user.TwoFactorChallengeDTO{}
+	err = ctrl.AuthService.WithActor(auth.ActorFromRequest(r)).LoginRequest(loginCreds, authenticatedUser, &permissions, &loginSessionId, &refreshToken, &challenge)
 	if err != nil {
-		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), 400)
+		sendLoginError(w, err, http.StatusBadRequest)
+		return
+	}
+	if challenge.TwoFactorRequired {
+		challenge.PreAuthToken, err = encrypt.GetPreAuthJwt(authenticatedUser.ID, authenticatedUser.RoleID, permissions.BankId, permissions.ClientId)
+		if err != nil {
+			errorsUtils.SendErrorWithCustomMessage(w, err.Error(), 400)
+			return
+		}
+		challenge.ExpiresIn = int64(constants.PreAuthTokenTTL.Seconds())
+		web.SendResponse(w, web.WebResponse{
+			StatusCode: http.StatusOK,
+			Message:    "Two-factor code required",
+			Data:       challenge,
+		})
 		return
 	}
 	if loginSessionId == 0 {
 		errorsUtils.SendErrorWithCustomMessage(w, "Cannot Created a Session. Error", 400)
 		return
 	}
-	token, err := encrypt.GetJwtFromData(authenticatedUser.ID, authenticatedUser.RoleID, permissions.BankId, permissions.ClientId, permissions.IsSuperAdmin, loginSessionId)
+	token, err := encrypt.GetJwtFromData(authenticatedUser.ID, authenticatedUser.RoleID, permissions.BankId, permissions.ClientId, permissions.IsSuperAdmin, loginSessionId, permissions.Permissions)
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), 400)
 		return
 	}
 	w.Header().Set("authorization", token)
+	w.Header().Set("Refresh-Token", refreshToken)
 	json.NewEncoder(w).Encode(authenticatedUser)
 }
 
+// sendLoginError tells throttled and locked logins apart from wrong credentials, so clients
+// know to wait (Retry-After) or to ask for an unlock.
+func sendLoginError(w http.ResponseWriter, err error, statusCode int) {
+	var blocked *service.LoginBlockedError
+	if errors.As(err, &blocked) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, service.ErrAccountLocked) {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusLocked)
+		return
+	}
+	errorsUtils.SendErrorWithCustomMessage(w, err.Error(), statusCode)
+}
+
+// RefreshApi trades a refresh token for a new access token and the next refresh token.
+func (ctrl *AuthController) RefreshApi(w http.ResponseWriter, r *http.Request) {
+	refreshRequest := &user.RefreshTokenDTO{}
+	err := web.UnMarshalJSON(r, refreshRequest)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), 400)
+		return
+	}
+	err = web.GetValidator().Struct(refreshRequest)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), 400)
+		return
+	}
+	var authenticatedUser = &user.User{}
+	permissions := user.UserPermissionDTO{}
+	var loginSessionId uint = 0
+	var refreshToken string
+	err = ctrl.AuthService.WithActor(auth.ActorFromRequest(r)).RefreshSession(refreshRequest.RefreshToken, authenticatedUser, &permissions, &loginSessionId, &refreshToken)
+	if err != nil {
+		sendLoginError(w, err, http.StatusUnauthorized)
+		return
+	}
+	token, err := encrypt.GetJwtFromData(authenticatedUser.ID, authenticatedUser.RoleID, permissions.BankId, permissions.ClientId, permissions.IsSuperAdmin, loginSessionId, permissions.Permissions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), 400)
+		return
+	}
+	w.Header().Set("authorization", token)
+	w.Header().Set("Refresh-Token", refreshToken)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Token refreshed",
+		Data: user.TokenResponseDTO{
+			AccessToken:  token,
+			RefreshToken: refreshToken,
+			ExpiresIn:    int64(constants.AccessTokenTTL.Seconds()),
+		},
+	})
+}
+
+// LogoutApi closes the caller's login session.
+func (ctrl *AuthController) LogoutApi(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	err := ctrl.AuthService.WithActor(auth.ActorFromRequest(r)).Logout(claims.LoginSessionId)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), 400)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Logged out",
+	})
+}
+
 func (ctrl *AuthController) RegisterAdmin(w http.ResponseWriter, r *http.Request) {
 	//validations
 	adminDetails := &user.User{}
@@ -89,10 +186,22 @@ func (ctrl *AuthController) RegisterAdmin(w http.ResponseWriter, r *http.Request
 		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), 400)
 		return
 	}
-	err = ctrl.AuthService.CreateNewAdmin(adminDetails)
+	err = encrypt.ValidatePassword(adminDetails.Password, adminDetails.Username)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), 400)
+		return
+	}
+	err = ctrl.AuthService.WithActor(auth.ActorFromRequest(r)).CreateNewAdmin(adminDetails)
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), 400)
 		return
 	}
 
 }
+
+// GetJWKS publishes the public keys so other services can verify our access tokens.
+func (ctrl *AuthController) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(encrypt.GetJWKS())
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"bankManagement/constants"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/user"
+	"bankManagement/utils/encrypt"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/web"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (ctrl *AuthController) registerPasswordRoutes(router *mux.Router) {
+	router.Handle("/auth/password/change", auth.AuthenticationMiddleware(http.HandlerFunc(ctrl.ChangePassword))).Methods(http.MethodPost)
+	router.HandleFunc("/auth/password/forgot", ctrl.ForgotPassword).Methods(http.MethodPost)
+	router.HandleFunc("/auth/password/reset", ctrl.ResetPassword).Methods(http.MethodPost)
+}
+
+func (ctrl *AuthController) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	change := &user.ChangePasswordDTO{}
+	err := web.UnMarshalJSON(r, change)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(change)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	err = ctrl.AuthService.WithActor(auth.ActorFromRequest(r)).ChangePassword(claims.UserId, claims.LoginSessionId, change)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Password changed, your other sessions have been logged out",
+	})
+}
+
+// ForgotPassword answers the same for known and unknown emails.
+func (ctrl *AuthController) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	forgot := &user.ForgotPasswordDTO{}
+	err := web.UnMarshalJSON(r, forgot)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(forgot)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	err = ctrl.AuthService.WithActor(auth.ActorFromRequest(r)).RequestPasswordReset(forgot.Email)
+	if err != nil {
+		ctrl.log.Error(err)
+		errorsUtils.SendErrorWithCustomMessage(w, "Cannot send the password reset email, try again later", http.StatusInternalServerError)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "If the email belongs to a user, a password reset token has been sent to it",
+	})
+}
+
+func (ctrl *AuthController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	reset := &user.ResetPasswordDTO{}
+	err := web.UnMarshalJSON(r, reset)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(reset)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	err = ctrl.AuthService.WithActor(auth.ActorFromRequest(r)).ResetPassword(reset)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Password reset, you can now log in",
+	})
+}
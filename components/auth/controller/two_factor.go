@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"bankManagement/constants"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/user"
+	"bankManagement/utils/encrypt"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/web"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (ctrl *AuthController) registerTwoFactorRoutes(router *mux.Router) {
+	router.HandleFunc("/auth/2fa/verify", ctrl.VerifyTwoFactorLogin).Methods(http.MethodPost)
+	// a login the bank holds until the user enrols only has its pre-auth token
+	router.Handle("/auth/2fa/enroll", auth.PreAuthMiddleware(http.HandlerFunc(ctrl.EnrollTwoFactor))).Methods(http.MethodPost)
+	router.Handle("/auth/2fa/confirm", auth.AuthenticationMiddleware(http.HandlerFunc(ctrl.ConfirmTwoFactor))).Methods(http.MethodPost)
+	router.Handle("/auth/2fa/recovery_codes", auth.AuthenticationMiddleware(http.HandlerFunc(ctrl.RegenerateRecoveryCodes))).Methods(http.MethodPost)
+	router.Handle("/auth/2fa", auth.AuthenticationMiddleware(http.HandlerFunc(ctrl.DisableTwoFactor))).Methods(http.MethodDelete)
+}
+
+// EnrollTwoFactor starts an enrolment: the provisioning URI is shown as a QR code and the
+// first code from the app goes to /auth/2fa/confirm, or to /auth/2fa/verify during a login.
+func (ctrl *AuthController) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	enrollment, err := ctrl.AuthService.WithActor(auth.ActorFromRequest(r)).StartTwoFactorEnrollment(claims.UserId)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Scan the QR code and confirm with a code from your authenticator",
+		Data:       enrollment,
+	})
+}
+
+func (ctrl *AuthController) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	code, ok := readTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+	recoveryCodes, err := ctrl.AuthService.WithActor(auth.ActorFromRequest(r)).ConfirmTwoFactor(claims.UserId, code.Code)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Two-factor authentication enabled, keep the recovery codes safe",
+		Data:       user.RecoveryCodesDTO{RecoveryCodes: recoveryCodes},
+	})
+}
+
+// VerifyTwoFactorLogin trades the pre-auth token of a login and a code for the session tokens.
+func (ctrl *AuthController) VerifyTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	loginRequest := &user.TwoFactorLoginDTO{}
+	err := web.UnMarshalJSON(r, loginRequest)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(loginRequest)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	claims, err := encrypt.ValidateJwtToken(loginRequest.PreAuthToken)
+	if err != nil || claims.Purpose != encrypt.PurposePreAuth {
+		errorsUtils.SendErrorWithCustomMessage(w, "Invalid or expired pre-auth token, please login again", http.StatusUnauthorized)
+		return
+	}
+	var authenticatedUser = &user.User{}
+	permissions := user.UserPermissionDTO{}
+	var loginSessionId uint = 0
+	var refreshToken string
+	var recoveryCodes []string
+	err = ctrl.AuthService.WithActor(auth.ActorFromRequest(r)).VerifyTwoFactorLogin(claims.UserId, claims.BankId, claims.ClientId, loginRequest.Code, web.ClientIP(r), authenticatedUser, &permissions, &loginSessionId, &refreshToken, &recoveryCodes)
+	if err != nil {
+		sendLoginError(w, err, http.StatusUnauthorized)
+		return
+	}
+	token, err := encrypt.GetJwtFromData(authenticatedUser.ID, authenticatedUser.RoleID, permissions.BankId, permissions.ClientId, permissions.IsSuperAdmin, loginSessionId, permissions.Permissions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("authorization", token)
+	w.Header().Set("Refresh-Token", refreshToken)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Logged in",
+		Data: user.TwoFactorLoginResponseDTO{
+			TokenResponseDTO: user.TokenResponseDTO{
+				AccessToken:  token,
+				RefreshToken: refreshToken,
+				ExpiresIn:    int64(constants.AccessTokenTTL.Seconds()),
+			},
+			RecoveryCodes: recoveryCodes,
+		},
+	})
+}
+
+func (ctrl *AuthController) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	code, ok := readTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+	recoveryCodes, err := ctrl.AuthService.WithActor(auth.ActorFromRequest(r)).RegenerateRecoveryCodes(claims.UserId, code.Code, web.ClientIP(r))
+	if err != nil {
+		sendLoginError(w, err, http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Recovery codes replaced",
+		Data:       user.RecoveryCodesDTO{RecoveryCodes: recoveryCodes},
+	})
+}
+
+func (ctrl *AuthController) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	code, ok := readTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+	err := ctrl.AuthService.WithActor(auth.ActorFromRequest(r)).DisableTwoFactor(claims.UserId, code.Code, web.ClientIP(r))
+	if err != nil {
+		sendLoginError(w, err, http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Two-factor authentication disabled",
+	})
+}
+
+func readTwoFactorCode(w http.ResponseWriter, r *http.Request) (*user.TwoFactorCodeDTO, bool) {
+	code := &user.TwoFactorCodeDTO{}
+	err := web.UnMarshalJSON(r, code)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return nil, false
+	}
+	err = web.GetValidator().Struct(code)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return nil, false
+	}
+	return code, true
+}
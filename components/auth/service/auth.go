@@ -1,6 +1,9 @@
 package service
 
 import (
+	notificationService "bankManagement/components/notification/service"
+	"bankManagement/constants"
+	"bankManagement/models/audit"
 	"bankManagement/models/bank"
 	"bankManagement/models/client"
 	"bankManagement/models/user"
@@ -8,94 +11,351 @@ import (
 	"bankManagement/utils/encrypt"
 	"bankManagement/utils/log"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
 )
 
 type AuthService struct {
-	DB         *gorm.DB
-	repository repository.Repository
-	log        log.WebLogger
+	DB            *gorm.DB
+	repository    repository.Repository
+	log           log.WebLogger
+	notifications *notificationService.NotificationService
 }
 
 func NewAuthService(
 	DB *gorm.DB,
 	repository repository.Repository,
 	log log.WebLogger,
+	notifications *notificationService.NotificationService,
 ) *AuthService {
 	return &AuthService{
 		DB,
 		repository,
 		log,
+		notifications,
 	}
 }
 
-func (service *AuthService) LoginRequest(requestedUserCredentials *user.UserLoginParamDTO, tempUser *user.User, permissions *user.UserPermissionDTO, loginSessionId *uint) error {
+// WithActor returns a copy of the service whose changes the audit log attributes to actor
+// and whose log lines carry the actor's request ID.
+func (service *AuthService) WithActor(actor *audit.Actor) *AuthService {
+	scoped := *service
+	scoped.DB = repository.BindActor(service.DB, actor)
+	scoped.log = service.log.WithFields(log.Fields{constants.RequestIDKey: actor.RequestId})
+	return &scoped
+}
+
+// LoginRequest checks the credentials and opens a login session with its first refresh token.
+// When the user has enrolled two-factor authentication, or the bank requires it, no session
+// is opened yet: challenge says so and the login goes on with VerifyTwoFactorLogin.
+func (service *AuthService) LoginRequest(requestedUserCredentials *user.UserLoginParamDTO, tempUser *user.User, permissions *user.UserPermissionDTO, loginSessionId *uint, refreshToken *string, challenge *user.TwoFactorChallengeDTO) error {
 	uow := repository.NewUnitOfWork(service.DB)
 	defer uow.RollBack()
-	err := service.repository.GetFirstWhere(uow, &tempUser, "username = ?", requestedUserCredentials.Username)
+	username, ipAddress := requestedUserCredentials.Username, requestedUserCredentials.IPAddress
+	err := service.checkLoginThrottle(uow, username, ipAddress)
 	if err != nil {
+		return err
+	}
+	err = service.repository.GetFirstWhere(uow, tempUser, "username = ?", username)
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
 		service.log.Error(err)
 		return err
 	}
 	if tempUser.ID == 0 {
 		service.log.Error("User Not Found")
-		return errors.New("Invalid User Credentials")
+		return service.failLogin(uow, username, ipAddress, nil, errInvalidCredentials)
+	}
+	if tempUser.LockedAt != nil {
+		return ErrAccountLocked
 	}
 	if !encrypt.CheckHashWithPassword(requestedUserCredentials.Password, tempUser.Password) {
 		service.log.Error("Wrong Password")
-		return errors.New("Invalid User Credentials")
+		return service.failLogin(uow, username, ipAddress, tempUser, errInvalidCredentials)
+	}
+	if !tempUser.IsActive {
+		return errors.New("User is not active, accept your invitation or contact your administrator")
+	}
+	err = service.clearLoginFailures(uow, username)
+	if err != nil {
+		return err
+	}
+	permissions.BankId = requestedUserCredentials.BankId
+	permissions.ClientId = requestedUserCredentials.ClientId
+	err = service.loadPermissions(uow, tempUser, permissions)
+	if err != nil {
+		return err
+	}
+
+	enabled, required, err := service.twoFactorStatus(uow, tempUser.ID, permissions.BankId)
+	if err != nil {
+		return err
+	}
+	if enabled || required {
+		challenge.TwoFactorRequired = true
+		challenge.EnrollmentRequired = !enabled
+		uow.Commit()
+		return nil
+	}
+
+	err = service.openSession(uow, tempUser, permissions, loginSessionId, refreshToken)
+	if err != nil {
+		return err
+	}
+	service.log.Info(*loginSessionId)
+	uow.Commit()
+	return nil
+}
+
+// RefreshSession rotates a refresh token: the presented token is spent and a new one is
+// issued for the same session, with the user's permissions looked up again. A token that
+// was already spent means it leaked, so the whole session is revoked.
+func (service *AuthService) RefreshSession(presentedToken string, tempUser *user.User, permissions *user.UserPermissionDTO, loginSessionId *uint, refreshToken *string) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	storedTokens := []user.RefreshToken{}
+	err := service.repository.GetAll(uow, &storedTokens,
+		service.repository.Filter("token_hash = ?", encrypt.HashToken(presentedToken)),
+		service.repository.ForUpdate(),
+	)
+	if err != nil {
+		return err
+	}
+	if len(storedTokens) == 0 {
+		return errInvalidRefreshToken
+	}
+	storedToken := storedTokens[0]
+
+	session := user.UserLoginInfo{}
+	err = service.repository.GetByIDForUpdate(uow, &session, storedToken.LoginSessionId)
+	if err != nil || !session.IsActive || session.LogoutTime != nil {
+		return errInvalidRefreshToken
+	}
+	if storedToken.UsedAt != nil {
+		service.log.Error("refresh token reused, revoking login session ", session.ID)
+		if err := service.closeSession(uow, &session); err != nil {
+			return err
+		}
+		uow.Commit()
+		return errors.New("Refresh token was already used, the session has been revoked")
+	}
+	now := time.Now()
+	if now.After(storedToken.ExpiresAt) {
+		return errors.New("Refresh token has expired, please login again")
+	}
+
+	err = service.repository.GetByID(uow, tempUser, session.UserId)
+	if err != nil || !tempUser.IsActive {
+		return errInvalidRefreshToken
+	}
+	if tempUser.LockedAt != nil {
+		return ErrAccountLocked
+	}
+	// the session stays on the bank or client it was opened for
+	permissions.BankId = session.BankId
+	permissions.ClientId = session.ClientId
+	err = service.loadPermissions(uow, tempUser, permissions)
+	if err != nil {
+		return err
+	}
+	storedToken.UsedAt = &now
+	err = service.repository.Update(uow, &storedToken)
+	if err != nil {
+		return err
+	}
+	*refreshToken, err = service.issueRefreshToken(uow, session.ID)
+	if err != nil {
+		return err
+	}
+	*loginSessionId = session.ID
+	uow.Commit()
+	return nil
+}
+
+// Logout closes the login session; its access and refresh tokens stop working at once.
+func (service *AuthService) Logout(loginSessionId uint) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	session := user.UserLoginInfo{}
+	err := service.repository.GetByIDForUpdate(uow, &session, loginSessionId)
+	if err != nil {
+		return errors.New("Session not found")
+	}
+	if !session.IsActive {
+		return errors.New("Session is already closed")
+	}
+	err = service.closeSession(uow, &session)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// ValidateSession is checked by the authentication middleware on every request.
+func (service *AuthService) ValidateSession(loginSessionId uint) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	session := user.UserLoginInfo{}
+	err := service.repository.GetByID(uow, &session, loginSessionId)
+	if err != nil || !session.IsActive || session.LogoutTime != nil {
+		return errors.New("Session is no longer active, please login again")
+	}
+	uow.Commit()
+	return nil
+}
+
+var errInvalidRefreshToken = errors.New("Invalid refresh token")
+
+var errInvalidCredentials = errors.New("Invalid User Credentials")
+
+// failLogin records the failed attempt and commits it although the login fails. It answers
+// with failure, the same whatever was wrong, unless this attempt locked the user.
+func (service *AuthService) failLogin(uow *repository.UOW, username string, ipAddress string, tempUser *user.User, failure error) error {
+	locked, err := service.recordLoginFailure(uow, username, ipAddress, tempUser)
+	if err != nil {
+		service.log.Error(err)
+		return failure
+	}
+	uow.Commit()
+	if locked {
+		service.notifyLockout(tempUser, ipAddress)
+		return ErrAccountLocked
 	}
-	if tempUser.RoleID == uint(encrypt.AdminUserRoleID) {
+	return failure
+}
+
+// loadPermissions resolves the bank or client the user acts for, from the scope of the
+// user's role and the user's active memberships, and the permissions the role grants. A
+// BankId or ClientId already set in permissions picks one of several memberships.
+func (service *AuthService) loadPermissions(uow *repository.UOW, tempUser *user.User, permissions *user.UserPermissionDTO) error {
+	role := user.Role{}
+	err := service.repository.GetByID(uow, &role, tempUser.RoleID)
+	if err != nil {
+		service.log.Error(err)
+		return errors.New("User has no valid Role")
+	}
+	if role.Scope == user.RoleScopeAdmin {
 		permissions.IsSuperAdmin = true
-	} else if tempUser.RoleID == uint(encrypt.BankUserRoleID) {
-		tempBankUser := bank.BankUser{}
-		err = service.repository.GetFirstWhere(uow, &tempBankUser, "user_id=?", tempUser.ID)
-		if err != nil || tempBankUser.UserID == 0 {
-			service.log.Error(err)
-			return errors.New("Bank User donot have access to Any Bank")
+		permissions.BankId, permissions.ClientId = 0, 0
+	} else if role.Scope == user.RoleScopeBank {
+		var memberships []bank.BankUser
+		err = service.repository.GetAll(uow, &memberships, service.repository.Filter("user_id = ? AND is_active = ?", tempUser.ID, true))
+		if err != nil {
+			return err
+		}
+		var bankIds []uint
+		for _, membership := range memberships {
+			bankIds = append(bankIds, membership.BankID)
+		}
+		permissions.BankId, err = pickMembership(bankIds, permissions.BankId, "Bank")
+		if err != nil {
+			return err
+		}
+		permissions.ClientId = 0
+	} else if role.Scope == user.RoleScopeClient {
+		var memberships []client.ClientUser
+		err = service.repository.GetAll(uow, &memberships, service.repository.Filter("user_id = ? AND is_active = ?", tempUser.ID, true))
+		if err != nil {
+			return err
 		}
-		permissions.BankId = tempBankUser.BankID
-	} else if tempUser.RoleID == uint(encrypt.ClientUserRoleID) {
-		tempClientUser := client.ClientUser{}
-		err = service.repository.GetFirstWhere(uow, &tempClientUser, "user_id=?", tempUser.ID)
-		if err != nil || tempClientUser.UserID == 0 {
-			service.log.Error(err)
-			return errors.New("Client User donot have access to Any Client")
+		var clientIds []uint
+		for _, membership := range memberships {
+			clientIds = append(clientIds, membership.ClientID)
 		}
-		permissions.ClientId = tempClientUser.ClientID
+		permissions.ClientId, err = pickMembership(clientIds, permissions.ClientId, "Client")
+		if err != nil {
+			return err
+		}
+		permissions.BankId = 0
+	}
+	err = service.repository.GetAll(uow, &role.Permissions, service.repository.Filter("role_id = ?", role.ID))
+	if err != nil {
+		return err
 	}
+	permissions.Permissions = role.PermissionNames()
+	return nil
+}
 
+// pickMembership returns the requested bank or client id when the user is an active member
+// of it, or the only one the user belongs to.
+func pickMembership(memberOf []uint, requested uint, kind string) (uint, error) {
+	if len(memberOf) == 0 {
+		return 0, fmt.Errorf("%s User donot have access to Any %s", kind, kind)
+	}
+	if requested != 0 {
+		if !slices.Contains(memberOf, requested) {
+			return 0, fmt.Errorf("User donot have access to %s %d", kind, requested)
+		}
+		return requested, nil
+	}
+	if len(memberOf) > 1 {
+		return 0, fmt.Errorf("User belongs to several %ss, choose one with %s_id: %v", strings.ToLower(kind), strings.ToLower(kind), memberOf)
+	}
+	return memberOf[0], nil
+}
+
+// openSession records the login session the access tokens will name, with its first
+// refresh token.
+func (service *AuthService) openSession(uow *repository.UOW, tempUser *user.User, permissions *user.UserPermissionDTO, loginSessionId *uint, refreshToken *string) error {
 	tempSession := user.UserLoginInfo{
 		UserId:    tempUser.ID,
 		UserName:  tempUser.Username,
 		IsActive:  true,
 		RoleID:    tempUser.RoleID,
+		BankId:    permissions.BankId,
+		ClientId:  permissions.ClientId,
 		LoginTime: time.Now(),
 	}
-	err = service.repository.Add(uow, &tempSession)
+	err := service.repository.Add(uow, &tempSession)
 	if err != nil {
 		return err
 	}
-	service.log.Info(tempSession.ID)
 	*loginSessionId = tempSession.ID
+	*refreshToken, err = service.issueRefreshToken(uow, tempSession.ID)
+	return err
+}
 
-	service.log.Info(*loginSessionId)
-	uow.Commit()
-	return nil
+func (service *AuthService) issueRefreshToken(uow *repository.UOW, loginSessionId uint) (string, error) {
+	token, hash, err := encrypt.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	err = service.repository.Add(uow, &user.RefreshToken{
+		LoginSessionId: loginSessionId,
+		TokenHash:      hash,
+		ExpiresAt:      time.Now().Add(constants.RefreshTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (service *AuthService) closeSession(uow *repository.UOW, session *user.UserLoginInfo) error {
+	now := time.Now()
+	session.IsActive = false
+	session.LogoutTime = &now
+	return service.repository.Update(uow, session)
 }
 
 func (service *AuthService) CreateNewAdmin(admin *user.User) error {
 	uow := repository.NewUnitOfWork(service.DB)
 	defer uow.RollBack()
+	adminRole := user.Role{}
+	if err := service.repository.GetFirstWhere(uow, &adminRole, "role_name = ?", user.RoleSuperAdmin); err != nil {
+		return errors.New("SUPER_ADMIN role not found")
+	}
 	var tempUser = &user.User{
 		Name:     admin.Name,
 		Username: admin.Username,
 		Password: encrypt.HashPassword(admin.Password),
 		Email:    admin.Email,
 		IsActive: true,
-		RoleID:   uint(encrypt.AdminUserRoleID),
+		RoleID:   adminRole.ID,
 	}	
 	err := service.repository.Add(uow, &tempUser)
 	if err != nil {
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"bankManagement/constants"
+	"bankManagement/models/notification"
+	"bankManagement/models/user"
+	"bankManagement/repository"
+	"bankManagement/utils/log"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// LoginBlockedError is returned while a username or an IP address waits out its backoff.
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+}
+
+func (err *LoginBlockedError) Error() string {
+	return fmt.Sprintf("Too many failed login attempts, retry in %d seconds", int(math.Ceil(err.RetryAfter.Seconds())))
+}
+
+var ErrAccountLocked = errors.New("Account is locked after too many failed login attempts, contact your administrator")
+
+// checkLoginThrottle refuses the attempt while the username or the IP address is in backoff.
+func (service *AuthService) checkLoginThrottle(uow *repository.UOW, username string, ipAddress string) error {
+	var throttles []user.LoginThrottle
+	err := service.repository.GetAll(uow, &throttles, service.repository.Filter("throttle_key IN (?)", []string{user.UsernameThrottleKey(username), user.IPThrottleKey(ipAddress)}))
+	if err != nil {
+		return err
+	}
+	var retryAfter time.Duration
+	for _, throttle := range throttles {
+		if throttle.BlockedUntil != nil {
+			retryAfter = max(retryAfter, time.Until(*throttle.BlockedUntil))
+		}
+	}
+	if retryAfter > 0 {
+		return &LoginBlockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed attempt against the username and the IP address and
+// locks the user, when it exists, once it reaches constants.LoginLockoutThreshold, closing
+// its open sessions. It reports whether this attempt locked the user; the caller commits
+// even though the login failed, and then sends the lockout email.
+func (service *AuthService) recordLoginFailure(uow *repository.UOW, username string, ipAddress string, tempUser *user.User) (bool, error) {
+	userFailures, err := service.countFailure(uow, user.UsernameThrottleKey(username), constants.LoginFreeAttempts)
+	if err != nil {
+		return false, err
+	}
+	if ipAddress != "" {
+		if _, err := service.countFailure(uow, user.IPThrottleKey(ipAddress), constants.LoginIPFreeAttempts); err != nil {
+			return false, err
+		}
+	}
+	if tempUser == nil || tempUser.ID == 0 || tempUser.LockedAt != nil || userFailures < constants.LoginLockoutThreshold {
+		return false, nil
+	}
+	now := time.Now()
+	tempUser.LockedAt = &now
+	err = service.repository.Update(uow, tempUser)
+	if err != nil {
+		return false, err
+	}
+	err = service.closeUserSessions(uow, tempUser.ID, 0)
+	if err != nil {
+		return false, err
+	}
+	err = service.repository.Add(uow, &user.UserLockoutEvent{
+		UserId:         tempUser.ID,
+		UserName:       tempUser.Username,
+		IPAddress:      ipAddress,
+		FailedAttempts: userFailures,
+		LockedAt:       now,
+	})
+	if err != nil {
+		return false, err
+	}
+	service.log.WithFields(log.Fields{"user_id": tempUser.ID, "failed_logins": userFailures, "ip": ipAddress}).Error("user locked after failed logins")
+	return true, nil
+}
+
+// countFailure bumps the key's failure count, restarting it after a quiet
+// constants.LoginFailureWindow, and sets the backoff once the free attempts are spent.
+func (service *AuthService) countFailure(uow *repository.UOW, key string, freeAttempts int) (int, error) {
+	var throttles []user.LoginThrottle
+	err := service.repository.GetAll(uow, &throttles, service.repository.Filter("throttle_key = ?", key), service.repository.ForUpdate())
+	if err != nil {
+		return 0, err
+	}
+	throttle := user.LoginThrottle{ThrottleKey: key}
+	if len(throttles) > 0 {
+		throttle = throttles[0]
+	}
+	now := time.Now()
+	if now.Sub(throttle.LastFailedAt) > constants.LoginFailureWindow {
+		throttle.FailedCount = 0
+	}
+	throttle.FailedCount++
+	throttle.LastFailedAt = now
+	throttle.BlockedUntil = nil
+	if throttle.FailedCount > freeAttempts {
+		backoff := constants.LoginBackoffMax
+		if doublings := throttle.FailedCount - freeAttempts - 1; doublings < 30 {
+			backoff = min(constants.LoginBackoffBase<<doublings, constants.LoginBackoffMax)
+		}
+		blockedUntil := now.Add(backoff)
+		throttle.BlockedUntil = &blockedUntil
+	}
+	if throttle.ID == 0 {
+		err = service.repository.Add(uow, &throttle)
+	} else {
+		err = service.repository.Update(uow, &throttle)
+	}
+	return throttle.FailedCount, err
+}
+
+// clearLoginFailures forgets the username's failures after a successful login. The IP
+// address keeps its count, logging into one's own account must not reset it.
+func (service *AuthService) clearLoginFailures(uow *repository.UOW, username string) error {
+	var throttles []user.LoginThrottle
+	err := service.repository.GetAll(uow, &throttles, service.repository.Filter("throttle_key = ?", user.UsernameThrottleKey(username)))
+	if err != nil || len(throttles) == 0 || throttles[0].FailedCount == 0 {
+		return err
+	}
+	throttles[0].FailedCount = 0
+	throttles[0].BlockedUntil = nil
+	return service.repository.Update(uow, &throttles[0])
+}
+
+func (service *AuthService) notifyLockout(lockedUser *user.User, ipAddress string) {
+	go service.notifications.Notify(notification.EventAccountLocked, notification.Recipient{UserID: lockedUser.ID}, map[string]interface{}{
+		"Name":           lockedUser.Name,
+		"Username":       lockedUser.Username,
+		"FailedAttempts": constants.LoginLockoutThreshold,
+		"IPAddress":      ipAddress,
+	})
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"bankManagement/constants"
+	"bankManagement/models/notification"
+	"bankManagement/models/user"
+	"bankManagement/repository"
+	"bankManagement/utils/encrypt"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errInvalidResetToken = errors.New("Invalid or expired password reset token")
+
+// ChangePassword replaces the caller's password. The user's other login sessions are
+// closed, the one making the change stays open.
+func (service *AuthService) ChangePassword(userId uint, loginSessionId uint, change *user.ChangePasswordDTO) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	tempUser := user.User{}
+	err := service.repository.GetByIDForUpdate(uow, &tempUser, userId)
+	if err != nil {
+		return errors.New("User not found")
+	}
+	if !encrypt.CheckHashWithPassword(change.CurrentPassword, tempUser.Password) {
+		return errors.New("Current password is wrong")
+	}
+	err = service.setPassword(uow, &tempUser, change.NewPassword)
+	if err != nil {
+		return err
+	}
+	err = service.closeUserSessions(uow, userId, loginSessionId)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// RequestPasswordReset emails a one-time reset token to the user with that email. It
+// answers the same whether or not the email is known, not to tell which users exist.
+func (service *AuthService) RequestPasswordReset(emailAddress string) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	var users []user.User
+	err := service.repository.GetAll(uow, &users, service.repository.Filter("email = ?", emailAddress))
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 || !users[0].IsActive {
+		service.log.Info("password reset requested for unknown or inactive email")
+		return nil
+	}
+	tempUser := users[0]
+	// only the latest emailed token works
+	err = service.spendResetTokens(uow, tempUser.ID)
+	if err != nil {
+		return err
+	}
+	token, hash, err := encrypt.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	err = service.repository.Add(uow, &user.PasswordResetToken{
+		UserID:    tempUser.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(constants.PasswordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	go service.notifications.Notify(notification.EventPasswordReset, notification.Recipient{UserID: tempUser.ID}, map[string]interface{}{
+		"Username": tempUser.Username,
+		"Token":    token,
+		"ValidFor": constants.PasswordResetTTL,
+	})
+	return nil
+}
+
+// ResetPassword sets a new password with an emailed reset token and closes all the user's
+// login sessions.
+func (service *AuthService) ResetPassword(reset *user.ResetPasswordDTO) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	var resetTokens []user.PasswordResetToken
+	err := service.repository.GetAll(uow, &resetTokens,
+		service.repository.Filter("token_hash = ?", encrypt.HashToken(reset.Token)),
+		service.repository.ForUpdate(),
+	)
+	if err != nil {
+		return err
+	}
+	if len(resetTokens) == 0 {
+		return errInvalidResetToken
+	}
+	resetToken := resetTokens[0]
+	now := time.Now()
+	if resetToken.UsedAt != nil || now.After(resetToken.ExpiresAt) {
+		return errInvalidResetToken
+	}
+	tempUser := user.User{}
+	err = service.repository.GetByIDForUpdate(uow, &tempUser, resetToken.UserID)
+	if err != nil || !tempUser.IsActive {
+		return errInvalidResetToken
+	}
+	err = service.setPassword(uow, &tempUser, reset.NewPassword)
+	if err != nil {
+		return err
+	}
+	resetToken.UsedAt = &now
+	err = service.repository.Update(uow, &resetToken)
+	if err != nil {
+		return err
+	}
+	err = service.closeUserSessions(uow, tempUser.ID, 0)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// setPassword applies the password policy, refuses the current password and the ones in
+// the history, and moves the current hash into the history.
+func (service *AuthService) setPassword(uow *repository.UOW, tempUser *user.User, newPassword string) error {
+	err := encrypt.ValidatePassword(newPassword, tempUser.Username)
+	if err != nil {
+		return err
+	}
+	historySize := encrypt.GetPasswordPolicy().HistorySize
+	var history []user.PasswordHistory
+	if historySize > 1 {
+		err = service.repository.GetAll(uow, &history,
+			service.repository.Filter("user_id = ?", tempUser.ID),
+			service.repository.Order("id desc"),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	previousHashes := []string{tempUser.Password}
+	for i := 0; i < len(history) && i < historySize-1; i++ {
+		previousHashes = append(previousHashes, history[i].PasswordHash)
+	}
+	if historySize > 0 {
+		for _, previousHash := range previousHashes {
+			if encrypt.CheckHashWithPassword(newPassword, previousHash) {
+				return fmt.Errorf("Password was used recently, choose one different from your last %d", historySize)
+			}
+		}
+	}
+	if historySize > 1 {
+		err = service.repository.Add(uow, &user.PasswordHistory{UserID: tempUser.ID, PasswordHash: tempUser.Password})
+		if err != nil {
+			return err
+		}
+		// the one just added and the current password make the history size
+		for i := historySize - 2; i < len(history); i++ {
+			err = service.repository.DeleteById(uow, &user.PasswordHistory{}, history[i].ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	tempUser.Password = encrypt.HashPassword(newPassword)
+	return service.repository.Update(uow, tempUser)
+}
+
+func (service *AuthService) spendResetTokens(uow *repository.UOW, userId uint) error {
+	var resetTokens []user.PasswordResetToken
+	err := service.repository.GetAll(uow, &resetTokens, service.repository.Filter("user_id = ? AND used_at IS NULL", userId))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range resetTokens {
+		resetTokens[i].UsedAt = &now
+		if err := service.repository.Update(uow, &resetTokens[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeUserSessions closes every active login session of the user but keepSessionId.
+func (service *AuthService) closeUserSessions(uow *repository.UOW, userId uint, keepSessionId uint) error {
+	var sessions []user.UserLoginInfo
+	err := service.repository.GetAll(uow, &sessions,
+		service.repository.Filter("user_id = ? AND is_active = ? AND id <> ?", userId, true, keepSessionId),
+		service.repository.ForUpdate(),
+	)
+	if err != nil {
+		return err
+	}
+	for i := range sessions {
+		if err := service.closeSession(uow, &sessions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
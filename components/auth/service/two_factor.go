@@ -0,0 +1,370 @@
+package service
+
+import (
+	"bankManagement/constants"
+	"bankManagement/models/bank"
+	"bankManagement/models/user"
+	"bankManagement/repository"
+	"bankManagement/utils/encrypt"
+	"bankManagement/utils/totp"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+)
+
+var errInvalidTwoFactorCode = errors.New("Invalid two-factor code")
+
+// StartTwoFactorEnrollment generates a new TOTP secret for the user, pending until
+// ConfirmTwoFactor (or the login it was enrolled from) accepts a first code.
+func (service *AuthService) StartTwoFactorEnrollment(userId uint) (*user.TwoFactorEnrollmentDTO, error) {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	tempUser := user.User{}
+	err := service.repository.GetByID(uow, &tempUser, userId)
+	if err != nil || !tempUser.IsActive {
+		return nil, errors.New("User not found")
+	}
+	twoFactor, err := service.getTwoFactor(uow, userId)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor.IsEnabled() {
+		return nil, errors.New("Two-factor authentication is already enabled, disable it before enrolling again")
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	twoFactor.UserID = userId
+	twoFactor.Secret = secret
+	twoFactor.ConfirmedAt = nil
+	twoFactor.LastCounter = 0
+	if twoFactor.ID == 0 {
+		err = service.repository.Add(uow, twoFactor)
+	} else {
+		err = service.repository.Update(uow, twoFactor)
+	}
+	if err != nil {
+		return nil, err
+	}
+	uow.Commit()
+	return &user.TwoFactorEnrollmentDTO{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(constants.TOTPIssuer, tempUser.Username, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables the pending enrolment with a first code from the authenticator
+// and returns the recovery codes, which are not shown again.
+func (service *AuthService) ConfirmTwoFactor(userId uint, code string) ([]string, error) {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	twoFactor, err := service.getTwoFactor(uow, userId)
+	if err != nil {
+		return nil, err
+	}
+	recoveryCodes, err := service.confirmTwoFactor(uow, twoFactor, code)
+	if err != nil {
+		return nil, err
+	}
+	uow.Commit()
+	return recoveryCodes, nil
+}
+
+// VerifyTwoFactorLogin completes a login held by a pre-auth token: the code is checked and
+// the login session opened for the bank or client chosen at the password step. A login
+// that had to enrol confirms the enrolment with this code and gets the recovery codes.
+func (service *AuthService) VerifyTwoFactorLogin(userId uint, bankId uint, clientId uint, code string, ipAddress string, tempUser *user.User, permissions *user.UserPermissionDTO, loginSessionId *uint, refreshToken *string, recoveryCodes *[]string) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	err := service.repository.GetByID(uow, tempUser, userId)
+	if err != nil || !tempUser.IsActive {
+		return errors.New("User is not active")
+	}
+	if tempUser.LockedAt != nil {
+		return ErrAccountLocked
+	}
+	// codes are guessed like passwords, they share the backoff and the lockout
+	err = service.checkLoginThrottle(uow, tempUser.Username, ipAddress)
+	if err != nil {
+		return err
+	}
+	permissions.BankId = bankId
+	permissions.ClientId = clientId
+	err = service.loadPermissions(uow, tempUser, permissions)
+	if err != nil {
+		return err
+	}
+	twoFactor, err := service.getTwoFactor(uow, userId)
+	if err != nil {
+		return err
+	}
+	if twoFactor.IsEnabled() {
+		err = service.checkCode(uow, twoFactor, code)
+	} else {
+		*recoveryCodes, err = service.confirmTwoFactor(uow, twoFactor, code)
+	}
+	if err == errInvalidTwoFactorCode {
+		return service.failLogin(uow, tempUser.Username, ipAddress, tempUser, errInvalidTwoFactorCode)
+	}
+	if err != nil {
+		return err
+	}
+	err = service.clearLoginFailures(uow, tempUser.Username)
+	if err != nil {
+		return err
+	}
+	err = service.openSession(uow, tempUser, permissions, loginSessionId, refreshToken)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// VerifyStepUp checks the code a sensitive operation asks for again, the user must have
+// two-factor authentication enabled.
+func (service *AuthService) VerifyStepUp(userId uint, code string, ipAddress string) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	twoFactor, err := service.getTwoFactor(uow, userId)
+	if err != nil {
+		return err
+	}
+	if !twoFactor.IsEnabled() {
+		return errors.New("Enable two-factor authentication to perform this operation")
+	}
+	err = service.checkUserCode(uow, userId, twoFactor, code, ipAddress)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// checkUserCode checks a code a signed-in user gives to confirm a sensitive operation. It is
+// refused while the user is locked or in backoff, and a wrong code counts as a failed login,
+// so codes cannot be guessed with a stolen access token. A wrong code commits uow.
+func (service *AuthService) checkUserCode(uow *repository.UOW, userId uint, twoFactor *user.TwoFactor, code string, ipAddress string) error {
+	tempUser := user.User{}
+	err := service.repository.GetByID(uow, &tempUser, userId)
+	if err != nil || !tempUser.IsActive {
+		return errors.New("User is not active")
+	}
+	if tempUser.LockedAt != nil {
+		return ErrAccountLocked
+	}
+	err = service.checkLoginThrottle(uow, tempUser.Username, ipAddress)
+	if err != nil {
+		return err
+	}
+	err = service.checkCode(uow, twoFactor, code)
+	if err == errInvalidTwoFactorCode {
+		return service.failLogin(uow, tempUser.Username, ipAddress, &tempUser, errInvalidTwoFactorCode)
+	}
+	if err != nil {
+		return err
+	}
+	return service.clearLoginFailures(uow, tempUser.Username)
+}
+
+// RegenerateRecoveryCodes replaces all the user's recovery codes, used or not.
+func (service *AuthService) RegenerateRecoveryCodes(userId uint, code string, ipAddress string) ([]string, error) {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	twoFactor, err := service.getTwoFactor(uow, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !twoFactor.IsEnabled() {
+		return nil, errors.New("Two-factor authentication is not enabled")
+	}
+	err = service.checkUserCode(uow, userId, twoFactor, code, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+	recoveryCodes, err := service.issueRecoveryCodes(uow, userId)
+	if err != nil {
+		return nil, err
+	}
+	uow.Commit()
+	return recoveryCodes, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off, unless a bank the user works for
+// requires it.
+func (service *AuthService) DisableTwoFactor(userId uint, code string, ipAddress string) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	twoFactor, err := service.getTwoFactor(uow, userId)
+	if err != nil {
+		return err
+	}
+	if !twoFactor.IsEnabled() {
+		return errors.New("Two-factor authentication is not enabled")
+	}
+	var requiringBanks []bank.Bank
+	err = service.repository.GetAll(uow, &requiringBanks, service.repository.Filter(
+		"require_two_factor = ? AND id IN (SELECT bank_id FROM bank_users WHERE user_id = ? AND is_active = ?)", true, userId, true))
+	if err != nil {
+		return err
+	}
+	if len(requiringBanks) > 0 {
+		return errors.New("Bank " + requiringBanks[0].BankName + " requires two-factor authentication")
+	}
+	err = service.checkUserCode(uow, userId, twoFactor, code, ipAddress)
+	if err != nil {
+		return err
+	}
+	twoFactor.Secret = ""
+	twoFactor.ConfirmedAt = nil
+	twoFactor.LastCounter = 0
+	err = service.repository.Update(uow, twoFactor)
+	if err != nil {
+		return err
+	}
+	err = service.deleteRecoveryCodes(uow, userId)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// twoFactorStatus tells whether the user has two-factor authentication enabled, and whether
+// the bank the login is for requires it.
+func (service *AuthService) twoFactorStatus(uow *repository.UOW, userId uint, bankId uint) (bool, bool, error) {
+	twoFactor, err := service.getTwoFactor(uow, userId)
+	if err != nil {
+		return false, false, err
+	}
+	required := false
+	if bankId != 0 {
+		bankEntity := bank.Bank{}
+		err = service.repository.GetByID(uow, &bankEntity, bankId)
+		if err != nil {
+			return false, false, err
+		}
+		required = bankEntity.RequireTwoFactor
+	}
+	return twoFactor.IsEnabled(), required, nil
+}
+
+// getTwoFactor locks the user's authenticator row, so a code cannot be spent twice by
+// concurrent requests. An empty TwoFactor is returned when the user never enrolled.
+func (service *AuthService) getTwoFactor(uow *repository.UOW, userId uint) (*user.TwoFactor, error) {
+	var twoFactors []user.TwoFactor
+	err := service.repository.GetAll(uow, &twoFactors,
+		service.repository.Filter("user_id = ?", userId),
+		service.repository.ForUpdate(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(twoFactors) == 0 {
+		return &user.TwoFactor{}, nil
+	}
+	return &twoFactors[0], nil
+}
+
+func (service *AuthService) confirmTwoFactor(uow *repository.UOW, twoFactor *user.TwoFactor, code string) ([]string, error) {
+	if twoFactor.ID == 0 || twoFactor.Secret == "" {
+		return nil, errors.New("Enroll an authenticator first")
+	}
+	if twoFactor.ConfirmedAt != nil {
+		return nil, errors.New("Two-factor authentication is already enabled")
+	}
+	counter, ok := totp.Validate(twoFactor.Secret, code, time.Now())
+	if !ok {
+		return nil, errInvalidTwoFactorCode
+	}
+	now := time.Now()
+	twoFactor.ConfirmedAt = &now
+	twoFactor.LastCounter = counter
+	err := service.repository.Update(uow, twoFactor)
+	if err != nil {
+		return nil, err
+	}
+	return service.issueRecoveryCodes(uow, twoFactor.UserID)
+}
+
+// checkCode accepts a TOTP code newer than the last one accepted, or an unused recovery code.
+func (service *AuthService) checkCode(uow *repository.UOW, twoFactor *user.TwoFactor, code string) error {
+	counter, ok := totp.Validate(twoFactor.Secret, code, time.Now())
+	if ok {
+		if counter <= twoFactor.LastCounter {
+			return errors.New("Two-factor code was already used, wait for the next one")
+		}
+		twoFactor.LastCounter = counter
+		return service.repository.Update(uow, twoFactor)
+	}
+	var recoveryCodes []user.RecoveryCode
+	err := service.repository.GetAll(uow, &recoveryCodes,
+		service.repository.Filter("user_id = ? AND code_hash = ? AND used_at IS NULL", twoFactor.UserID, encrypt.HashToken(normalizeRecoveryCode(code))),
+		service.repository.ForUpdate(),
+	)
+	if err != nil {
+		return err
+	}
+	if len(recoveryCodes) == 0 {
+		return errInvalidTwoFactorCode
+	}
+	now := time.Now()
+	recoveryCodes[0].UsedAt = &now
+	return service.repository.Update(uow, &recoveryCodes[0])
+}
+
+func (service *AuthService) issueRecoveryCodes(uow *repository.UOW, userId uint) ([]string, error) {
+	err := service.deleteRecoveryCodes(uow, userId)
+	if err != nil {
+		return nil, err
+	}
+	recoveryCodes := make([]string, 0, constants.RecoveryCodeCount)
+	for i := 0; i < constants.RecoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		err = service.repository.Add(uow, &user.RecoveryCode{
+			UserID:   userId,
+			CodeHash: encrypt.HashToken(normalizeRecoveryCode(code)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		recoveryCodes = append(recoveryCodes, code)
+	}
+	return recoveryCodes, nil
+}
+
+func (service *AuthService) deleteRecoveryCodes(uow *repository.UOW, userId uint) error {
+	var recoveryCodes []user.RecoveryCode
+	err := service.repository.GetAll(uow, &recoveryCodes, service.repository.Filter("user_id = ?", userId))
+	if err != nil {
+		return err
+	}
+	for _, recoveryCode := range recoveryCodes {
+		err = service.repository.DeleteById(uow, &user.RecoveryCode{}, recoveryCode.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newRecoveryCode returns 80 random bits as four dash separated groups, e.g. k7qd-3mzp-...
+func newRecoveryCode() (string, error) {
+	random := make([]byte, 10)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	encoded := strings.ToLower(base32.StdEncoding.EncodeToString(random))
+	return encoded[0:4] + "-" + encoded[4:8] + "-" + encoded[8:12] + "-" + encoded[12:16], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
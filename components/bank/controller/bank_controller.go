@@ -3,7 +3,10 @@ package controller
 import (
 	"bankManagement/components/bank/service"
 	"bankManagement/middlewares/auth"
+	"bankManagement/models/user"
+	"bankManagement/utils/encrypt"
 	"bankManagement/utils/log"
+	"bankManagement/utils/web"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -32,12 +35,12 @@ func NewBankController(UserServcice *service.BankService, log log.WebLogger) *Ba
 
 func (controller *BankController) RegisterRoutes(router *mux.Router) {
 	bankRouter := router.PathPrefix("/banks").Subrouter()
-	bankRouter.Use(auth.AuthenticationMiddleware, auth.ValidateAdminPermissionsMiddleware) // SuperAdmin middleware
-	bankRouter.HandleFunc("/", controller.CreateBank).Methods("POST")
-	bankRouter.HandleFunc("/", controller.GetAllBanks).Methods("GET")
-	bankRouter.HandleFunc("/{id}", controller.GetBankByID).Methods("GET")
-	bankRouter.HandleFunc("/{id}", controller.DeleteBank).Methods("DELETE")
-	bankRouter.HandleFunc("/{id}", controller.UpdateBank).Methods("PUT")
+	bankRouter.Use(auth.AuthenticationMiddleware)
+	bankRouter.Handle("/", auth.Require(controller.CreateBank, user.PermissionBankWrite)).Methods("POST")
+	bankRouter.Handle("/", auth.Require(controller.GetAllBanks, user.PermissionBankRead)).Methods("GET")
+	bankRouter.Handle("/{id}", auth.Require(controller.GetBankByID, user.PermissionBankRead)).Methods("GET")
+	bankRouter.Handle("/{id}", auth.Require(controller.DeleteBank, user.PermissionBankWrite)).Methods("DELETE")
+	bankRouter.Handle("/{id}", auth.Require(controller.UpdateBank, user.PermissionBankWrite)).Methods("PUT")
 
 	// POST - /api/v1/bankManagement/banks
 }
@@ -47,7 +50,7 @@ func (controller *BankController) RegisterRoutes(router *mux.Router) {
 // / CREATE BANK
 func (controller *BankController) CreateBank(w http.ResponseWriter, r *http.Request) {
 
-	fmt.Println("CreateBank called")
+	controller.log.WithContext(r.Context()).Debug("CreateBank called")
 
 	bankEntityDTO := bank.BankAndUserDTO{}
 
@@ -65,7 +68,7 @@ func (controller *BankController) CreateBank(w http.ResponseWriter, r *http.Requ
 	bankEntityDTO.Email = email //// Set generated email in DTO for service layer use
 
 	// service call --  create bank and bank user
-	if err := controller.BankService.CreateBank(bankEntityDTO); err != nil {
+	if err := controller.BankService.WithActor(auth.ActorFromRequest(r)).CreateBank(bankEntityDTO); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -100,8 +103,8 @@ func validateBankDTO(dto bank.BankAndUserDTO) (string, error) {
 	if dto.Password == "" {
 		return "", errors.New("password is required")
 	}
-	if len(dto.Password) < 5 {
-		return "", errors.New("password must be at least 8 characters long")
+	if err := encrypt.ValidatePassword(dto.Password, dto.Username); err != nil {
+		return "", err
 	}
 
 	email := strings.ReplaceAll(strings.ToLower(dto.BankName), " ", "") + "@gmail.com"
@@ -119,7 +122,7 @@ func (controller *BankController) GetBankByID(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	bankEntity, err := controller.BankService.GetBankByID(uint(id))
+	bankEntity, err := controller.BankService.WithActor(auth.ActorFromRequest(r)).GetBankByID(uint(id))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -129,22 +132,33 @@ func (controller *BankController) GetBankByID(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(bankEntity)
 }
 
+var bankListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "bank_name": "bank_name", "created_at": "created_at"},
+	FilterFields: map[string]string{"is_active": "is_active", "bank_abbreviation": "bank_abbreviation"},
+}
+
 // GET ALL
 func (controller *BankController) GetAllBanks(w http.ResponseWriter, r *http.Request) {
+	query, err := web.ParseListQuery(r, bankListOptions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	banks, err := controller.BankService.GetAllBanks()
+	banks, err := controller.BankService.WithActor(auth.ActorFromRequest(r)).GetAllBanks(query)
 	if err != nil {
 		http.Error(w, "Failed to retrieve banks", http.StatusInternalServerError)
 		return
 	}
 
+	web.SetListHeaders(w, r, query)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(banks)
 }
 
 // DELETE Bank
 func (controller *BankController) DeleteBank(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("DeleteBank controller called...")
+	controller.log.WithContext(r.Context()).Debug("DeleteBank controller called...")
 
 	bankIDStr := mux.Vars(r)["id"]
 	bankID, err := strconv.Atoi(bankIDStr)
@@ -153,7 +167,7 @@ func (controller *BankController) DeleteBank(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := controller.BankService.DeleteBank(uint(bankID)); err != nil {
+	if err := controller.BankService.WithActor(auth.ActorFromRequest(r)).DeleteBank(uint(bankID)); err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
@@ -164,7 +178,7 @@ func (controller *BankController) DeleteBank(w http.ResponseWriter, r *http.Requ
 
 // // UPDATE BANK
 func (controller *BankController) UpdateBank(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("UpdateBank called")
+	controller.log.WithContext(r.Context()).Debug("UpdateBank called")
 
 	//bank ID-- URL
 	bankIDStr := mux.Vars(r)["id"]
@@ -180,7 +194,7 @@ func (controller *BankController) UpdateBank(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	// service call -- update(Bank & BankUser)
-	if err := controller.BankService.UpdateBank(uint(bankID), bankDTO); err != nil {
+	if err := controller.BankService.WithActor(auth.ActorFromRequest(r)).UpdateBank(uint(bankID), bankDTO); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			http.Error(w, fmt.Sprintf("Bank with ID %d not found", bankID), http.StatusNotFound)
 			return
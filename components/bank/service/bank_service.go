@@ -1,13 +1,15 @@
 package service
 
 import (
+	"bankManagement/constants"
+	"bankManagement/models/audit"
 	"bankManagement/models/bank"
 	"bankManagement/models/client"
 	"bankManagement/models/user"
 	"bankManagement/repository"
 	"bankManagement/utils/encrypt"
 	"bankManagement/utils/log"
-	"strings"
+	"bankManagement/utils/web"
 
 	"fmt"
 
@@ -32,10 +34,19 @@ func NewBankService(
 	}
 }
 
+// WithActor returns a copy of the service whose changes the audit log attributes to actor
+// and whose log lines carry the actor's request ID.
+func (s *BankService) WithActor(actor *audit.Actor) *BankService {
+	scoped := *s
+	scoped.DB = repository.BindActor(s.DB, actor)
+	scoped.log = s.log.WithFields(log.Fields{constants.RequestIDKey: actor.RequestId})
+	return &scoped
+}
+
 // / create bank
 func (s *BankService) CreateBank(bankAndUserEntityDTO bank.BankAndUserDTO) error {
 
-	fmt.Println("Bank Service called ...")
+	s.log.Debug("Bank Service called ...")
 
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
@@ -55,6 +66,11 @@ func (s *BankService) CreateBank(bankAndUserEntityDTO bank.BankAndUserDTO) error
 		// return err
 	}
 
+	bankUserRole := user.Role{}
+	if err := s.repository.GetFirstWhere(uow, &bankUserRole, "role_name = ?", user.RoleBankUser); err != nil {
+		return fmt.Errorf("BANK_USER role not found: %w", err)
+	}
+
 	hashedPassword := encrypt.HashPassword(bankAndUserEntityDTO.Password)
 
 	//Create User for BankUser
@@ -64,7 +80,7 @@ func (s *BankService) CreateBank(bankAndUserEntityDTO bank.BankAndUserDTO) error
 		Name:     bankAndUserEntityDTO.BankName, //  BankUSer Name == Bank Name
 		Email:    bankAndUserEntityDTO.Email,    //  BankUserEmail == Bank Email
 		IsActive: true,
-		RoleID:   uint(encrypt.BankUserRoleID),
+		RoleID:   bankUserRole.ID,
 	}
 	if err := s.repository.Add(uow, userEntity); err != nil {
 		return fmt.Errorf("failed to create BankUser: %w", err)
@@ -130,12 +146,12 @@ func (s *BankService) GetBankByID(id uint) (*bank.Bank, error) {
 }
 
 // GET ALL BANKS
-func (s *BankService) GetAllBanks() ([]bank.Bank, error) {
+func (s *BankService) GetAllBanks(query *web.ListQuery) ([]bank.Bank, error) {
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
 
 	var banks []bank.Bank
-	err := s.repository.GetAll(uow, &banks)
+	err := s.repository.GetAll(uow, &banks, query.QueryProcessors(s.repository)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch banks: %w", err)
 	}
@@ -166,24 +182,31 @@ func (s *BankService) DeleteBank(bankID uint) error {
 	if len(clients) > 0 {
 		return fmt.Errorf("cannot delete bank with ID %d: associated clients exist", bankID)
 	} else {
-		fmt.Println("No associated found.")
+		s.log.Debug("No associated found.")
 	}
 
-	// 2: Delete associated BankUser (from Joining `bankmanagement.bank_users` Table) and its corresponding User (in `users` Table)
-	var bankUser bank.BankUser
-	if err := s.repository.GetFirstWhere(uow, &bankUser, "bank_id = ?", bankID); err == nil {
-		// Delete the entry in `bank_users` table first
+	// 2: Delete every BankUser membership (from Joining `bankmanagement.bank_users` Table), and the User (in `users` Table) when it belongs to no other bank
+	var bankUsers []bank.BankUser
+	if err := s.repository.GetAll(uow, &bankUsers, s.repository.Filter("bank_id = ?", bankID)); err != nil {
+		return fmt.Errorf("error checking for BankUser in bank_users table: %w", err)
+	}
+	for _, bankUser := range bankUsers {
 		if err := s.repository.DeleteById(uow, &bankUser, bankUser.UserID); err != nil {
 			return fmt.Errorf("failed to delete associated BankUser: %w", err)
 		}
-		// now delete the associated User from `users` table
+		otherMembership := bank.BankUser{}
+		err := s.repository.GetFirstWhere(uow, &otherMembership, "user_id = ?", bankUser.UserID)
+		if err == nil {
+			continue
+		}
+		if !gorm.IsRecordNotFoundError(err) {
+			return fmt.Errorf("error checking other banks of User: %w", err)
+		}
 		userEntity := user.User{}
 		userEntity.ID = bankUser.UserID
 		if err := s.repository.DeleteById(uow, &userEntity, userEntity.ID); err != nil {
 			return fmt.Errorf("failed to delete associated User: %w", err)
 		}
-	} else if !gorm.IsRecordNotFoundError(err) {
-		return fmt.Errorf("error checking for BankUser in bank_users table: %w", err)
 	}
 
 	// 3. Delete the Bank itself from the `banks` table
@@ -195,7 +218,7 @@ func (s *BankService) DeleteBank(bankID uint) error {
 	return nil
 }
 
-// // UPDATE BANK (and BankUser - related details also)
+// // UPDATE BANK
 func (s *BankService) UpdateBank(bankID uint, bankDTO bank.BankAndUserDTO) error {
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
@@ -227,49 +250,7 @@ func (s *BankService) UpdateBank(bankID uint, bankDTO bank.BankAndUserDTO) error
 		return fmt.Errorf("failed to update bank: %w", err)
 	}
 
-	// ----------------  ASSOCIATED bANKUser deletion -----------------
-
-	//finding and updating associated BankUser
-
-	associatedUser := user.User{} // here i don't have UserID of the Bank User to be Updated  -- need to find that from Joining Table - 'bank_users'  [this user with  UserId  is automatically created with Bank]
-	bankUser := bank.BankUser{}   // Joining Table Struct
-
-	// 1 : Fetch the associated BankUser by bankId :  ----- and getting the UserID from 'bank_users' Table - basically by Populating(automatic) BankUser Struct
-	if err := s.repository.GetFirstWhere(uow, &bankUser, "bank_id = ?", bankID); err != nil {
-		if gorm.IsRecordNotFoundError(err) {
-			return fmt.Errorf("no associated bank user found for bank ID %d", bankID)
-		}
-		return fmt.Errorf("error fetching associated BankUser: %w", err)
-	}
-
-	//2 : Retrieve the associated 'User'  by 'UserID'(BankUser.UserID)
-	associatedUser.ID = bankUser.UserID
-	if err := s.repository.GetByID(uow, &associatedUser, associatedUser.ID); err != nil {
-		if gorm.IsRecordNotFoundError(err) {
-			return fmt.Errorf("user associated with bank UserID  %d does not exist", bankUser.UserID)
-		}
-		return fmt.Errorf("error fetching associated User: %w", err)
-	}
-
-	// 3 : Update User fields related to Bank info (Name and Email)
-	if bankDTO.BankName != "" {
-		associatedUser.Name = bankDTO.BankName
-		associatedUser.Email = generateBankUserEmail(bankDTO.BankName)
-
-		// email := strings.ReplaceAll(strings.ToLower(dto.BankName), " ", "") + "@gmail.com"
-	}
-
-	// 4 : save to DB
-	if err := s.repository.Update(uow, associatedUser); err != nil {
-		return fmt.Errorf("failed to update associated BankUser: %w", err)
-	}
-
-	// -----------------------------------------------------------
-
+	// the bank's users are managed on their own (/banks/{bank_id}/users), a bank has many
 	uow.Commit()
 	return nil
 }
-
-func generateBankUserEmail(updatedBankName string) string {
-	return strings.ReplaceAll(strings.ToLower(updatedBankName), " ", "") + "@gmail.com"
-}
@@ -5,14 +5,21 @@ import (
 	"bankManagement/constants"
 	"bankManagement/middlewares/auth"
 	"bankManagement/models/client"
-	"bankManagement/models/document"
+	"bankManagement/models/payments"
+	"bankManagement/models/salaryDisbursement"
+	"bankManagement/models/user"
 	"bankManagement/utils/encrypt"
 	errorsUtils "bankManagement/utils/errors"
 	"bankManagement/utils/log"
+	"bankManagement/utils/money"
+	"bankManagement/utils/web"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -32,37 +39,83 @@ func NewBankUserController(bankUserService *service.BankUserService, log log.Web
 	}
 }
 
+// what the list endpoints can be sorted (?sort=) and filtered on
+var clientListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "client_name": "client_name", "balance": "balance", "created_at": "created_at"},
+	FilterFields: map[string]string{"is_active": "is_active", "verification_status": "verification_status", "currency": "currency"},
+}
+
+var documentListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "file_name": "file_name", "created_at": "created_at"},
+	FilterFields: map[string]string{"client_id": "client_id", "file_type": "file_type"},
+	DefaultSort:  "-created_at",
+}
+
+var salaryBatchListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "total_amount": "total_amount", "created_at": "created_at"},
+	FilterFields: map[string]string{"status": "status", "client_id": "client_id", "strategy": "strategy"},
+	DefaultSort:  "-created_at",
+}
+
+var transactionListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "transaction_amount": "transaction_amount", "created_at": "created_at"},
+	FilterFields: map[string]string{"payment_type": "payment_type", "transaction_type": "transaction_type", "transaction_status": "transaction_status"},
+	DefaultSort:  "-created_at",
+}
+
 func (controller *BankUserController) RegisterRoutes(router *mux.Router) {
 	clientRouter := router.PathPrefix("/banks/{bank_id}/clients").Subrouter()
 	clientRouter.Use(auth.AuthenticationMiddleware, auth.ValidateBankPermissionsMiddleware) // BankUSer middleware  (BANK_USER can only CRUD on Client and ClientUser)
-	clientRouter.HandleFunc("/", controller.CreateClient).Methods("POST")
-	clientRouter.HandleFunc("/{id}", controller.GetClientByID).Methods("GET")
-	clientRouter.HandleFunc("/", controller.GetAllClients).Methods("GET")
-	clientRouter.HandleFunc("/{id}", controller.UpdateClientByID).Methods("PUT")
-	clientRouter.HandleFunc("/{id}", controller.DeleteClientByID).Methods("DELETE")
+	clientRouter.Handle("/", auth.Require(controller.CreateClient, user.PermissionClientCreate)).Methods("POST")
+	clientRouter.Handle("/{id}", auth.Require(controller.GetClientByID, user.PermissionClientRead)).Methods("GET")
+	clientRouter.Handle("/", auth.Require(controller.GetAllClients, user.PermissionClientRead)).Methods("GET")
+	clientRouter.Handle("/{id}", auth.Require(controller.UpdateClientByID, user.PermissionClientUpdate)).Methods("PUT")
+	clientRouter.Handle("/{id}", auth.Require(controller.DeleteClientByID, user.PermissionClientDelete)).Methods("DELETE")
+	clientRouter.Handle("/{id}/kyc", auth.Require(controller.GetKycStatus, user.PermissionClientRead)).Methods("GET")
+	clientRouter.Handle("/{id}/verify", auth.Require(controller.VerifyClient, user.PermissionKycReview)).Methods("POST")
+	clientRouter.Handle("/{id}/reject", auth.Require(controller.RejectClient, user.PermissionKycReview)).Methods("POST")
+
+	kycRouter := router.PathPrefix("/banks/{bank_id}/kyc/document_types").Subrouter()
+	kycRouter.Use(auth.AuthenticationMiddleware, auth.ValidateBankPermissionsMiddleware)
+	kycRouter.Handle("/", auth.Require(controller.GetRequiredDocumentTypes, user.PermissionDocumentRead)).Methods("GET")
+	kycRouter.Handle("/", auth.Require(controller.AddRequiredDocumentType, user.PermissionKycConfigure)).Methods("POST")
+	kycRouter.Handle("/{id}", auth.Require(controller.DeleteRequiredDocumentType, user.PermissionKycConfigure)).Methods("DELETE")
 
 	documentRouter := router.PathPrefix("/banks/{bank_id}/documents").Subrouter()
 	documentRouter.Use(auth.AuthenticationMiddleware, auth.ValidateBankPermissionsMiddleware) // BankUSer middleware  (BANK_USER can only UPLOAD DOCUMENTS)
-	documentRouter.HandleFunc("/", controller.UploadDocument).Methods("POST")
-	documentRouter.HandleFunc("/{id}", controller.GetDocumentByID).Methods("GET")
-	documentRouter.HandleFunc("/", controller.GetAllDocuments).Methods("GET")
-	documentRouter.HandleFunc("/{id}", controller.DeleteDocumentByID).Methods("DELETE")
+	documentRouter.Handle("/", auth.Require(controller.UploadDocument, user.PermissionDocumentWrite)).Methods("POST")
+	documentRouter.Handle("/{id}", auth.Require(controller.GetDocumentByID, user.PermissionDocumentRead)).Methods("GET")
+	documentRouter.Handle("/{id}/download", auth.Require(controller.DownloadDocument, user.PermissionDocumentRead)).Methods("GET")
+	documentRouter.Handle("/", auth.Require(controller.GetAllDocuments, user.PermissionDocumentRead)).Methods("GET")
+	documentRouter.Handle("/{id}", auth.Require(controller.DeleteDocumentByID, user.PermissionDocumentWrite)).Methods("DELETE")
 
 	paymentRouter := router.PathPrefix("/banks/{bank_id}/payment_requests").Subrouter()
 	paymentRouter.Use(auth.AuthenticationMiddleware, auth.ValidateBankPermissionsMiddleware)
-	paymentRouter.HandleFunc("/{payment_request_id}/approve", controller.ApprovePaymentRequest).Methods(http.MethodPost)
-	paymentRouter.HandleFunc("/{payment_request_id}/reject", controller.RejectPaymentRequest).Methods(http.MethodPost)
+	paymentRouter.Handle("/{payment_request_id}/approve", auth.Require(auth.RequireStepUp(controller.ApprovePaymentRequest), user.PermissionPaymentApprove)).Methods(http.MethodPost)
+	paymentRouter.Handle("/{payment_request_id}/reject", auth.Require(controller.RejectPaymentRequest, user.PermissionPaymentApprove)).Methods(http.MethodPost)
+
+	paymentRouter.Handle("/{id}", auth.Require(controller.GetPaymentRequest, user.PermissionPaymentRequestRead)).Methods(http.MethodGet)
 
-	paymentRouter.HandleFunc("/{id}", controller.GetPaymentRequest).Methods(http.MethodGet)
+	securityRouter := router.PathPrefix("/banks/{bank_id}/security").Subrouter()
+	securityRouter.Use(auth.AuthenticationMiddleware, auth.ValidateBankPermissionsMiddleware)
+	securityRouter.Handle("/", auth.Require(controller.UpdateBankSecurity, user.PermissionBankSecurityWrite)).Methods(http.MethodPut)
+
+	salaryBatchRouter := router.PathPrefix("/banks/{bank_id}/salary_batches").Subrouter()
+	salaryBatchRouter.Use(auth.AuthenticationMiddleware, auth.ValidateBankPermissionsMiddleware)
+	salaryBatchRouter.Handle("/", auth.Require(controller.GetAllSalaryBatches, user.PermissionSalaryBatchRead)).Methods(http.MethodGet)
+	salaryBatchRouter.Handle("/{batch_id}", auth.Require(controller.GetSalaryBatch, user.PermissionSalaryBatchRead)).Methods(http.MethodGet)
+	salaryBatchRouter.Handle("/{batch_id}/approve", auth.Require(controller.ApproveSalaryBatch, user.PermissionSalaryBatchApprove)).Methods(http.MethodPost)
+	salaryBatchRouter.Handle("/{batch_id}/reject", auth.Require(controller.RejectSalaryBatch, user.PermissionSalaryBatchApprove)).Methods(http.MethodPost)
 
 	transactionRouter := router.PathPrefix("/transactions").Subrouter()
-	transactionRouter.HandleFunc("/{client_id}/reports", controller.GenerateTransactionReport).Methods(http.MethodGet)
+	transactionRouter.Use(auth.AuthenticationMiddleware)
+	transactionRouter.Handle("/{client_id}/reports", auth.Require(controller.GenerateTransactionReport, user.PermissionTransactionRead)).Methods(http.MethodGet)
 
 }
 
 // // CREATE CLIENT
 func (controller *BankUserController) CreateClient(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("CreateClient called")
+	controller.log.WithContext(r.Context()).Debug("CreateClient called")
 	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
 
 	clientDTO := client.ClientDTO{}
@@ -78,11 +131,11 @@ func (controller *BankUserController) CreateClient(w http.ResponseWriter, r *htt
 	}
 	clientDTO.BankID = claims.BankId
 	//service call
-	if err := controller.BankUserService.CreateClient(clientDTO); err != nil {
+	if err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).CreateClient(clientDTO, claims.UserId); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	fmt.Println("Client and ClientUser created successfully.")
+	controller.log.WithContext(r.Context()).Debug("Client and ClientUser created successfully.")
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -97,21 +150,27 @@ func validateClientDTO(dto client.ClientDTO) error {
 	if !strings.Contains(dto.ClientEmail, "@") {
 		return errors.New("invalid email format")
 	}
-	if dto.Balance < 0 {
+	if dto.Balance.IsNegative() {
 		return errors.New("balance cannot be negative")
 	}
+	if dto.Currency != "" && len(dto.Currency) != 3 {
+		return errors.New("currency must be a 3 letter ISO code")
+	}
 	if dto.Username == "" {
 		return errors.New("username is required for client user")
 	}
 	if dto.Password == "" {
 		return errors.New("password is required for client user")
 	}
+	if err := encrypt.ValidatePassword(dto.Password, dto.Username); err != nil {
+		return err
+	}
 	return nil
 }
 
 // / GET CLIENT BY ID
 func (controller *BankUserController) GetClientByID(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("GetClientByID called..")
+	controller.log.WithContext(r.Context()).Debug("GetClientByID called..")
 	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
 
 	clientID, err := strconv.Atoi(mux.Vars(r)["id"])
@@ -121,7 +180,7 @@ func (controller *BankUserController) GetClientByID(w http.ResponseWriter, r *ht
 	}
 
 	// service layer call .. (bankId also added) -- onlyy clients specified BankId are fetched
-	clientEntity, err := controller.BankUserService.GetClientByID(uint(clientID), claims.BankId)
+	clientEntity, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).GetClientByID(uint(clientID), claims.BankId)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			http.Error(w, fmt.Sprintf("Client with ID %d not found", clientID), http.StatusNotFound)
@@ -134,10 +193,10 @@ func (controller *BankUserController) GetClientByID(w http.ResponseWriter, r *ht
 	//// do in this also
 
 	// Fetch ClientUser Username  (validating  ClientUser belongsto specified ClientId and BankId)
-	clientUser, err := controller.BankUserService.GetClientUserByClientID(clientEntity.ID, claims.BankId)
+	clientUser, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).GetClientUserByClientID(clientEntity.ID, claims.BankId)
 	var clientUserUsername string
 	if err != nil {
-		fmt.Println("Error fetching client user:", err)
+		controller.log.WithContext(r.Context()).Warning("Error fetching client user:", err)
 	} else if clientUser != nil {
 		clientUserUsername = clientUser.Username
 	}
@@ -148,24 +207,30 @@ func (controller *BankUserController) GetClientByID(w http.ResponseWriter, r *ht
 		ClientName:         clientEntity.ClientName,
 		ClientEmail:        clientEntity.ClientEmail,
 		Balance:            clientEntity.Balance,
+		Currency:           clientEntity.Currency,
 		IsActive:           clientEntity.IsActive,
 		VerificationStatus: clientEntity.VerificationStatus,
 		BankID:             clientEntity.BankID,
 		Username:           clientUserUsername,
 	}
 
-	fmt.Println("GetClientByID Finished..")
+	controller.log.WithContext(r.Context()).Debug("GetClientByID Finished..")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response) //return client data
 }
 
 // // GET ALL CLIENTS
 func (controller *BankUserController) GetAllClients(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("GetAllClients controller called ...")
+	controller.log.WithContext(r.Context()).Debug("GetAllClients controller called ...")
 
 	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	query, err := web.ParseListQuery(r, clientListOptions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	clients, err := controller.BankUserService.GetAllClients(claims.BankId)
+	clients, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).GetAllClients(claims.BankId, query)
 	if err != nil {
 		http.Error(w, "Failed to retrieve clients", http.StatusInternalServerError) //err.Error()
 		return
@@ -177,10 +242,10 @@ func (controller *BankUserController) GetAllClients(w http.ResponseWriter, r *ht
 	for _, clientEntity := range clients {
 
 		// Username - Fetch associated ClientUser Username
-		clientUser, err := controller.BankUserService.GetClientUserByClientID(clientEntity.ID, claims.BankId)
+		clientUser, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).GetClientUserByClientID(clientEntity.ID, claims.BankId)
 		var clientUserUsername string
 		if err != nil {
-			fmt.Println("Error fetching client user:", err)
+			controller.log.WithContext(r.Context()).Warning("Error fetching client user:", err)
 		} else if clientUser != nil {
 			clientUserUsername = clientUser.Username
 		}
@@ -191,6 +256,7 @@ func (controller *BankUserController) GetAllClients(w http.ResponseWriter, r *ht
 			ClientName:         clientEntity.ClientName,
 			ClientEmail:        clientEntity.ClientEmail,
 			Balance:            clientEntity.Balance,
+			Currency:           clientEntity.Currency,
 			IsActive:           clientEntity.IsActive,
 			VerificationStatus: clientEntity.VerificationStatus,
 			BankID:             clientEntity.BankID,
@@ -199,15 +265,16 @@ func (controller *BankUserController) GetAllClients(w http.ResponseWriter, r *ht
 		clientResponses = append(clientResponses, clientResponse)
 	}
 
+	web.SetListHeaders(w, r, query)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(clientResponses)
-	fmt.Println("GetAllClients response sent successfully.")
+	controller.log.WithContext(r.Context()).Debug("GetAllClients response sent successfully.")
 
 }
 
 // UPDATE CLIENT ()
 func (controller *BankUserController) UpdateClientByID(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("UpdateClient controller called ...")
+	controller.log.WithContext(r.Context()).Debug("UpdateClient controller called ...")
 
 	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
 
@@ -233,40 +300,28 @@ func (controller *BankUserController) UpdateClientByID(w http.ResponseWriter, r
 		return
 	}
 
-	if err := controller.BankUserService.UpdateClientByID(clientID, claims.BankId, updatedData); err != nil {
+	if err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).UpdateClientByID(clientID, claims.BankId, updatedData, claims.UserId); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	fmt.Println("Update ClientByID Controller Finished Successfullyy..")
+	controller.log.WithContext(r.Context()).Debug("Update ClientByID Controller Finished Successfullyy..")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Client updated successfully"))
 }
 
 func validateClientUpdateInput(updatedData client.Client) error {
 	// check if ClientName, ClientEmai - > not same as some Client. Update BAnkID check
-	allowedStatuses := map[string]bool{
-		"Pending":  true,
-		"Verified": true,
-		"Rejected": true,
-	}
-
-	if updatedData.Balance != 0 && updatedData.Balance < 1000 {
+	if !updatedData.Balance.IsZero() && updatedData.Balance.LessThan(money.MustParse("1000", updatedData.Balance.Currency)) {
 		return errors.New("balance must be at least 1000")
 	}
 
-	if updatedData.VerificationStatus != "" {
-		if _, ok := allowedStatuses[updatedData.VerificationStatus]; !ok {
-			return fmt.Errorf("invalid verification status; allowed values are: Pending, Verified, Rejected")
-		}
-	}
-
 	return nil
 }
 
 // / DELETE CLIENT BY ID
 
 func (controller *BankUserController) DeleteClientByID(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("DeleteClient controller called..")
+	controller.log.WithContext(r.Context()).Debug("DeleteClient controller called..")
 	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
 
 	vars := mux.Vars(r)
@@ -278,7 +333,7 @@ func (controller *BankUserController) DeleteClientByID(w http.ResponseWriter, r
 	}
 	clientID := uint(id)
 
-	err = controller.BankUserService.DeleteClientByID(clientID, claims.BankId)
+	err = controller.BankUserService.WithActor(auth.ActorFromRequest(r)).DeleteClientByID(clientID, claims.BankId)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			http.Error(w, fmt.Sprintf("Client with ID %d not found or has already been deleted", clientID), http.StatusNotFound)
@@ -288,13 +343,13 @@ func (controller *BankUserController) DeleteClientByID(w http.ResponseWriter, r
 		return
 	}
 
-	fmt.Println("DeleteClient controller finished..")
+	controller.log.WithContext(r.Context()).Debug("DeleteClient controller finished..")
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // Verify client
 func (controller *BankUserController) VerifyClient(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("Verified client controlle called ...")
+	controller.log.WithContext(r.Context()).Debug("Verified client controlle called ...")
 
 	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
 
@@ -306,12 +361,12 @@ func (controller *BankUserController) VerifyClient(w http.ResponseWriter, r *htt
 	}
 	clientID := uint(id)
 
-	err = controller.BankUserService.VerifyClient(clientID, claims.BankId)
+	err = controller.BankUserService.WithActor(auth.ActorFromRequest(r)).VerifyClient(clientID, claims.BankId, claims.UserId)
 	if err != nil {
-		if strings.Contains(err.Error(), "Client not found. Check ClientId") {
+		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, err.Error(), http.StatusNotFound)
 		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 		}
 		return
 	}
@@ -332,7 +387,7 @@ func (controller *BankUserController) ApprovePaymentRequest(w http.ResponseWrite
 		http.Error(w, "Invalid payment request ID", http.StatusBadRequest)
 		return
 	}
-	if err := controller.BankUserService.ApprovePaymentRequest(uint(id), claims.UserId); err != nil {
+	if err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).ApprovePaymentRequest(uint(id), claims.UserId); err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -341,14 +396,33 @@ func (controller *BankUserController) ApprovePaymentRequest(w http.ResponseWrite
 }
 
 func (controller *BankUserController) RejectPaymentRequest(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
 	idStr := mux.Vars(r)["payment_request_id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		http.Error(w, "Invalid payment request ID", http.StatusBadRequest)
 		return
 	}
-	if err := controller.BankUserService.RejectPaymentRequest(uint(id)); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	rejection := payments.PaymentRejectionDTO{}
+	if err := json.NewDecoder(r.Body).Decode(&rejection); err != nil {
+		http.Error(w, "Invalid input format; please check the JSON structure", http.StatusBadRequest)
+		return
+	}
+	rejection.Reason = strings.TrimSpace(rejection.Reason)
+	if err := web.GetValidator().Struct(rejection); err != nil {
+		http.Error(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	err = controller.BankUserService.WithActor(auth.ActorFromRequest(r)).RejectPaymentRequest(uint(id), claims.UserId, rejection.Reason)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "unauthorized"):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -362,7 +436,7 @@ func (controller *BankUserController) GetPaymentRequest(w http.ResponseWriter, r
 		http.Error(w, "Invalid payment request ID", http.StatusBadRequest)
 		return
 	}
-	paymentRequest, err := controller.BankUserService.GetPaymentRequest(uint(id))
+	paymentRequest, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).GetPaymentRequest(uint(id))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -371,6 +445,86 @@ func (controller *BankUserController) GetPaymentRequest(w http.ResponseWriter, r
 	json.NewEncoder(w).Encode(paymentRequest)
 }
 
+///////////// Salary Batch Approval Functions  ///////// Controller /////
+
+func (controller *BankUserController) GetAllSalaryBatches(w http.ResponseWriter, r *http.Request) {
+	bankID, err := strconv.ParseUint(mux.Vars(r)["bank_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid bank ID", http.StatusBadRequest)
+		return
+	}
+	query, err := web.ParseListQuery(r, salaryBatchListOptions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var batches []salaryDisbursement.SalaryBatch
+	if err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).GetAllSalaryBatches(uint(bankID), query, &batches); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	web.SetListHeaders(w, r, query)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batches)
+}
+
+func (controller *BankUserController) GetSalaryBatch(w http.ResponseWriter, r *http.Request) {
+	bankID, err := strconv.ParseUint(mux.Vars(r)["bank_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid bank ID", http.StatusBadRequest)
+		return
+	}
+	batchID, err := strconv.ParseUint(mux.Vars(r)["batch_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid salary batch ID", http.StatusBadRequest)
+		return
+	}
+	batch, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).GetSalaryBatch(uint(bankID), uint(batchID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
+
+func (controller *BankUserController) ApproveSalaryBatch(w http.ResponseWriter, r *http.Request) {
+	controller.reviewSalaryBatch(w, r, salaryDisbursement.ReviewActionApprove)
+}
+
+func (controller *BankUserController) RejectSalaryBatch(w http.ResponseWriter, r *http.Request) {
+	controller.reviewSalaryBatch(w, r, salaryDisbursement.ReviewActionReject)
+}
+
+// reviewSalaryBatch applies action to the whole batch, or to the lines listed in the optional body.
+func (controller *BankUserController) reviewSalaryBatch(w http.ResponseWriter, r *http.Request, action string) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	bankID, err := strconv.ParseUint(mux.Vars(r)["bank_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid bank ID", http.StatusBadRequest)
+		return
+	}
+	batchID, err := strconv.ParseUint(mux.Vars(r)["batch_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid salary batch ID", http.StatusBadRequest)
+		return
+	}
+	review := salaryDisbursement.SalaryBatchReviewDTO{}
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil && err != io.EOF {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	review.Action = action
+
+	report, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).ReviewSalaryBatch(uint(bankID), uint(batchID), claims.UserId, review)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
 ///////////// Transaction Report Functions  ///////// Controller /////
 
 func (controller *BankUserController) GenerateTransactionReport(w http.ResponseWriter, r *http.Request) {
@@ -381,118 +535,120 @@ func (controller *BankUserController) GenerateTransactionReport(w http.ResponseW
 		return
 	}
 
-	transactions, err := controller.BankUserService.GenerateTransactionReport(uint(clientID))
+	query, err := web.ParseListQuery(r, transactionListOptions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	transactions, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).GenerateTransactionReport(uint(clientID), query)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	web.SetListHeaders(w, r, query)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(transactions)
 }
 
 /////////////// --------------------- Upload Document ------------------------------------------ //////////////////////
 
-const DocumentUploadDir = `\C:\Users\sushant.chauhan\Desktop\Banking-App-Golang\documents`
-
-// / UPLOAD DOCUMENT
+// / UPLOAD DOCUMENT - multipart form with a "file", the "client_id" it belongs to and its KYC "document_type"
 func (controller *BankUserController) UploadDocument(w http.ResponseWriter, r *http.Request) {
-
-	fmt.Println(" \n----------  UploadDocument called ----------  ")
-
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
 	bankId, err := strconv.ParseUint(mux.Vars(r)["bank_id"], 10, 32)
 	if err != nil {
 		http.Error(w, "Invalid bank ID", http.StatusBadRequest)
 		return
 	}
 
-	// if err := r.ParseMultipartForm(10 << 20); err != nil {
-	// 	http.Error(w, "Parsing form error", http.StatusInternalServerError)
-	// 	return
-	// }
-
-	fmt.Println(" -----bankId ----------- = ", bankId)
-
-	fmt.Println(" -------------------- 1 -------------------- ")
-
-	// file, fileHeader, err := r.FormFile("file")
-	// if err != nil {
-	// 	http.Error(w, "Error retrieving the file", http.StatusInternalServerError)
-	// 	return
-	// }
-	// defer file.Close()
-
-	fmt.Println(" -------------------- 2-------------------- ")
-
-	// os.MkdirAll(DocumentUploadDir, os.ModePerm)
-
-	// // Define file path
-	// fileName := filepath.Base(fileHeader.Filename)
-	// fileType := fileHeader.Header.Get("Content-Type")
-	// filePath := filepath.Join(DocumentUploadDir, fileName)
-
-	// // Save the file
-	// targetFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE, 0666)
-	// if err != nil {
-	// 	http.Error(w, "Failed to open file for writing: "+err.Error(), http.StatusInternalServerError)
-	// 	return
-	// }
-	// defer targetFile.Close()
-
-	fmt.Println(" -------------------- 3 -------------------- ")
-
-	// if _, err := io.Copy(targetFile, file); err != nil {
-	// 	http.Error(w, "Failed to copy the file: "+err.Error(), http.StatusInternalServerError)
-	// 	return
-	// }
-
-	// uploadedByUserId, _ := strconv.ParseUint(r.FormValue("uploaded_by_user_id"), 10, 32)
-	// clientId, _ := strconv.ParseUint(r.FormValue("client_id"), 10, 32)
+	// room for the form fields on top of the file
+	r.Body = http.MaxBytesReader(w, r.Body, constants.DocumentMaxBytes+1<<20)
+	if err := r.ParseMultipartForm(constants.DocumentMaxBytes); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid multipart form, documents are limited to %d bytes", constants.DocumentMaxBytes), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	clientId, err := strconv.ParseUint(r.FormValue("client_id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid client ID", http.StatusBadRequest)
+		return
+	}
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Error retrieving the file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Error reading the file", http.StatusBadRequest)
+		return
+	}
 
-	// Create Document record
-	documentRecord := document.Document{
-		FileName:         "fileName",
-		FileType:         "fileType",
-		FileURL:          "filePath",
-		UploadedByUserId: 7, //uint(uploadedByUserId),
-		ClientId:         2, //uint(clientId),
-		BankId:           uint(bankId),
+	documentRecord, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).UploadDocument(uint(bankId), uint(clientId), claims.UserId, r.FormValue("document_type"), filepath.Base(fileHeader.Filename), content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	fmt.Println("------- 4--------------------")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(documentRecord)
+}
 
-	// Service call to save document details
-	if err := controller.BankUserService.CreateDocument(documentRecord); err != nil {
-		http.Error(w, "Failed to create document record: "+err.Error(), http.StatusInternalServerError)
+// / DOWNLOAD DOCUMENT - streams the stored file
+func (controller *BankUserController) DownloadDocument(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	docID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid document ID format", http.StatusBadRequest)
 		return
 	}
 
-	fmt.Println("----- 5--------------------")
+	documentEntity, content, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).OpenDocument(uint(docID), claims.BankId)
+	if err != nil {
+		http.Error(w, "Failed to fetch document: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	defer content.Close()
 
-	fmt.Println("File uploaded and saved successfully")
-	w.WriteHeader(http.StatusCreated)
-	w.Write([]byte("Document uploaded successfully"))
+	w.Header().Set("Content-Type", documentEntity.FileType)
+	w.Header().Set("Content-Length", strconv.FormatInt(documentEntity.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": documentEntity.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, content); err != nil {
+		controller.log.Error(err)
+	}
 }
 
 // GET DOCUMENTS - from bank
 func (controller *BankUserController) GetAllDocuments(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("------- GetAllDocuments called ------------")
+	controller.log.WithContext(r.Context()).Debug("------- GetAllDocuments called ------------")
 	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
 	bankID := claims.BankId // Assuming BankId is extracted from JWT claims
 
-	documents, err := controller.BankUserService.GetAllDocuments(bankID)
+	query, err := web.ParseListQuery(r, documentListOptions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	documents, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).GetAllDocuments(bankID, query)
 	if err != nil {
 		http.Error(w, "Failed to fetch documents: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	web.SetListHeaders(w, r, query)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(documents)
 }
 
 // / GET DOCUMENT BY ID
 func (controller *BankUserController) GetDocumentByID(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("GetDocumentByID called")
+	controller.log.WithContext(r.Context()).Debug("GetDocumentByID called")
 	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
 	bankID := claims.BankId // BankId is extracted from JWT claims
 
@@ -503,7 +659,7 @@ func (controller *BankUserController) GetDocumentByID(w http.ResponseWriter, r *
 		return
 	}
 
-	document, err := controller.BankUserService.GetDocumentByID(uint(docID), bankID)
+	document, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).GetDocumentByID(uint(docID), bankID)
 	if err != nil {
 		http.Error(w, "Failed to fetch document: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -515,7 +671,7 @@ func (controller *BankUserController) GetDocumentByID(w http.ResponseWriter, r *
 
 // // DELETE DOCUMENT - controller
 func (controller *BankUserController) DeleteDocumentByID(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("DeleteDocumentByID called")
+	controller.log.WithContext(r.Context()).Debug("DeleteDocumentByID called")
 	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
 	bankID := claims.BankId // Assuming BankId is extracted from JWT claims
 
@@ -527,7 +683,7 @@ func (controller *BankUserController) DeleteDocumentByID(w http.ResponseWriter,
 	}
 
 	// Call the service layer to delete the document
-	if err := controller.BankUserService.DeleteDocumentByID(uint(docID), bankID); err != nil {
+	if err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).DeleteDocumentByID(uint(docID), bankID); err != nil {
 		http.Error(w, "Failed to delete document: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
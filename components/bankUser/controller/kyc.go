@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"bankManagement/constants"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/client"
+	"bankManagement/models/document"
+	"bankManagement/utils/encrypt"
+	"bankManagement/utils/web"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+/////////////  KYC Functions  //////// Controller /////
+
+func (controller *BankUserController) GetKycStatus(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	clientID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid client ID", http.StatusBadRequest)
+		return
+	}
+	status, err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).GetKycStatus(uint(clientID), claims.BankId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// Reject client KYC - body {"reason": "..."}
+func (controller *BankUserController) RejectClient(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	clientID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid client ID", http.StatusBadRequest)
+		return
+	}
+	review := client.KycReviewDTO{}
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, "Invalid input format; please check the JSON structure", http.StatusBadRequest)
+		return
+	}
+	if err := web.GetValidator().Struct(review); err != nil {
+		http.Error(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+
+	err = controller.BankUserService.WithActor(auth.ActorFromRequest(r)).RejectClient(uint(clientID), claims.BankId, claims.UserId, review.Reason)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Client KYC rejected"))
+}
+
+func (controller *BankUserController) GetRequiredDocumentTypes(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	var documentTypes []document.RequiredDocumentType
+	if err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).GetRequiredDocumentTypes(claims.BankId, &documentTypes); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(documentTypes)
+}
+
+func (controller *BankUserController) AddRequiredDocumentType(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	documentType := document.RequiredDocumentType{}
+	if err := json.NewDecoder(r.Body).Decode(&documentType); err != nil {
+		http.Error(w, "Invalid input format; please check the JSON structure", http.StatusBadRequest)
+		return
+	}
+	if err := web.GetValidator().Struct(documentType); err != nil {
+		http.Error(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	if err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).AddRequiredDocumentType(claims.BankId, &documentType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(documentType)
+}
+
+func (controller *BankUserController) DeleteRequiredDocumentType(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid document type ID", http.StatusBadRequest)
+		return
+	}
+	if err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).DeleteRequiredDocumentType(claims.BankId, uint(id)); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
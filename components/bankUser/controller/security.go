@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"bankManagement/constants"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/bank"
+	"bankManagement/utils/encrypt"
+	"bankManagement/utils/web"
+	"encoding/json"
+	"net/http"
+)
+
+func (controller *BankUserController) UpdateBankSecurity(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	security := bank.BankSecurityDTO{}
+	if err := json.NewDecoder(r.Body).Decode(&security); err != nil {
+		http.Error(w, "Invalid input format; please check the JSON structure", http.StatusBadRequest)
+		return
+	}
+	if err := web.GetValidator().Struct(security); err != nil {
+		http.Error(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	bankEntity := bank.Bank{}
+	if err := controller.BankUserService.WithActor(auth.ActorFromRequest(r)).UpdateBankSecurity(claims.BankId, &security, &bankEntity); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bankEntity)
+}
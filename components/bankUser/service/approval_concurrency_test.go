@@ -0,0 +1,271 @@
+package service_test
+
+import (
+	"bankManagement/app"
+	"bankManagement/components/bankUser/service"
+	fxService "bankManagement/components/fx/service"
+	ledgerService "bankManagement/components/ledger/service"
+	outboxService "bankManagement/components/outbox/service"
+	"bankManagement/models/bank"
+	"bankManagement/models/client"
+	"bankManagement/models/employee"
+	"bankManagement/models/ledger"
+	"bankManagement/models/payments"
+	"bankManagement/models/salaryDisbursement"
+	"bankManagement/models/user"
+	"bankManagement/repository"
+	"bankManagement/utils/money"
+	"bankManagement/utils/testdb"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+)
+
+// approvalFixture is a bank, one of its users and a verified sender client holding
+// openingBalance, with a receiver client to pay.
+type approvalFixture struct {
+	app        *app.App
+	service    *service.BankUserService
+	ledger     *ledgerService.LedgerService
+	bankId     uint
+	bankUserId uint
+	senderId   uint
+	receiverId uint
+}
+
+func newApprovalFixture(t *testing.T, openingBalance money.Money) *approvalFixture {
+	appObj := testdb.Open(t)
+	db, repo := appObj.DB, appObj.Repository
+	fixture := &approvalFixture{app: appObj}
+	fixture.ledger = ledgerService.NewLedgerService(db, repo, appObj.Log)
+	events := outboxService.NewOutboxService(db, repo, appObj.Log)
+	rates := fxService.NewDBRateProvider(db, repo, appObj.Log)
+	fixture.service = service.NewBankUserService(db, repo, appObj.Log, fixture.ledger, rates, nil, events, nil)
+
+	bankEntity := bank.Bank{BankName: "Test Bank", BankAbbreviation: "TB"}
+	mustCreate(t, db, &bankEntity)
+	fixture.bankId = bankEntity.ID
+	bankUserRole := user.Role{}
+	if err := db.Where("role_name = ?", user.RoleBankUser).First(&bankUserRole).Error; err != nil {
+		t.Fatal(err)
+	}
+	bankUser := user.User{Username: "approver", Password: "-", Name: "Approver", Email: "approver@example.com", IsActive: true, RoleID: bankUserRole.ID}
+	mustCreate(t, db, &bankUser)
+	fixture.bankUserId = bankUser.ID
+	mustCreate(t, db, &bank.BankUser{UserID: bankUser.ID, BankID: bankEntity.ID, IsActive: true})
+
+	fixture.senderId = fixture.createClient(t, "Sender", openingBalance)
+	fixture.receiverId = fixture.createClient(t, "Receiver", money.New(1, openingBalance.Currency))
+	return fixture
+}
+
+func (fixture *approvalFixture) createClient(t *testing.T, name string, openingBalance money.Money) uint {
+	clientEntity := client.Client{
+		ClientName:         name,
+		ClientEmail:        strings.ToLower(name) + "@example.com",
+		Balance:            openingBalance,
+		Currency:           openingBalance.Currency,
+		IsActive:           true,
+		VerificationStatus: client.VerificationStatusVerified,
+		BankID:             fixture.bankId,
+	}
+	uow := repository.NewUnitOfWork(fixture.app.DB)
+	defer uow.RollBack()
+	if err := fixture.app.Repository.Add(uow, &clientEntity); err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.ledger.PostOpeningBalance(uow, &clientEntity, 0); err != nil {
+		t.Fatal(err)
+	}
+	uow.Commit()
+	return clientEntity.ID
+}
+
+func (fixture *approvalFixture) createPaymentRequests(t *testing.T, count int, amount money.Money) []uint {
+	var ids []uint
+	for i := 0; i < count; i++ {
+		request := payments.PaymentRequest{
+			SenderClientID:   fixture.senderId,
+			ReceiverClientID: fixture.receiverId,
+			AuthorizerBankId: fixture.bankId,
+			PaymentAmount:    amount,
+			Currency:         amount.Currency,
+			ReceivedAmount:   money.Zero(amount.Currency),
+			ReceivedCurrency: amount.Currency,
+			Status:           "Pending",
+			CreatedByUserId:  fixture.bankUserId,
+		}
+		mustCreate(t, fixture.app.DB, &request)
+		ids = append(ids, request.ID)
+	}
+	return ids
+}
+
+// createSalaryBatch submits an AllOrNothing batch paying each salary to a new employee.
+func (fixture *approvalFixture) createSalaryBatch(t *testing.T, salaries ...money.Money) uint {
+	total := money.Zero(salaries[0].Currency)
+	for _, salary := range salaries {
+		total = total.Add(salary)
+	}
+	batch := salaryDisbursement.SalaryBatch{
+		ClientID:        fixture.senderId,
+		BankID:          fixture.bankId,
+		Status:          salaryDisbursement.DisbursementStatusPending,
+		Strategy:        salaryDisbursement.StrategyAllOrNothing,
+		TotalAmount:     total,
+		CreatedByUserId: fixture.bankUserId,
+	}
+	mustCreate(t, fixture.app.DB, &batch)
+	for i, salary := range salaries {
+		emp := employee.Employee{ClientID: fixture.senderId, EmployeeName: fmt.Sprintf("Employee %d", i), SalaryAmount: salary, AccountNo: fmt.Sprintf("ACC%d", i), TotalSalaryReceived: money.Zero(salary.Currency)}
+		mustCreate(t, fixture.app.DB, &emp)
+		mustCreate(t, fixture.app.DB, &salaryDisbursement.SalaryDisbursement{
+			ClientID:        fixture.senderId,
+			EmpID:           emp.ID,
+			SalaryAmount:    salary,
+			Status:          salaryDisbursement.DisbursementStatusPending,
+			CreatedByUserId: fixture.bankUserId,
+			BatchID:         batch.ID,
+		})
+	}
+	return batch.ID
+}
+
+// checkLedger fails the test if the sender's balance went negative at any point of its
+// posting history, and returns the final balance.
+func (fixture *approvalFixture) checkLedger(t *testing.T) money.Money {
+	t.Helper()
+	account := ledger.Account{}
+	err := fixture.app.DB.Where("client_id = ? AND account_type = ?", fixture.senderId, ledger.AccountTypeClient).First(&account).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	var postings []ledger.Posting
+	if err := fixture.app.DB.Where("account_id = ?", account.ID).Order("id asc").Find(&postings).Error; err != nil {
+		t.Fatal(err)
+	}
+	balance := money.Zero(account.Currency)
+	for _, posting := range postings {
+		balance = balance.Add(money.New(posting.Amount.Minor, account.Currency))
+		if balance.IsNegative() {
+			t.Fatalf("sender balance went negative: %s after posting %d", balance, posting.ID)
+		}
+	}
+	return balance
+}
+
+// outcome collects the results of concurrent approvals.
+type outcome struct {
+	sync.Mutex
+	paid     money.Money
+	refused  []money.Money
+	failures []error
+}
+
+func (o *outcome) record(amount money.Money, err error) {
+	o.Lock()
+	defer o.Unlock()
+	switch {
+	case err == nil:
+		o.paid = o.paid.Add(amount)
+	case strings.Contains(strings.ToLower(err.Error()), "insufficient"):
+		o.refused = append(o.refused, amount)
+	default:
+		o.failures = append(o.failures, err)
+	}
+}
+
+// check asserts that what was paid is what left the account, and that every refusal was
+// for want of money: the balance left cannot cover any refused amount.
+func (o *outcome) check(t *testing.T, openingBalance money.Money, finalBalance money.Money) {
+	t.Helper()
+	for _, err := range o.failures {
+		t.Errorf("approval failed for another reason than the balance: %v", err)
+	}
+	if spent := openingBalance.Sub(finalBalance); spent.Cmp(o.paid) != 0 {
+		t.Errorf("approvals paid %s but the balance fell by %s", o.paid, spent)
+	}
+	for _, amount := range o.refused {
+		if !finalBalance.LessThan(amount) {
+			t.Errorf("an approval of %s was refused although %s is left", amount, finalBalance)
+		}
+	}
+}
+
+func TestParallelPaymentApprovalsCannotOverdraw(t *testing.T) {
+	openingBalance := money.New(100000, "INR")
+	amount := money.New(10000, "INR")
+	fixture := newApprovalFixture(t, openingBalance)
+	requestIds := fixture.createPaymentRequests(t, 20, amount)
+
+	result := &outcome{paid: money.Zero("INR")}
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, requestId := range requestIds {
+		wg.Add(1)
+		go func(requestId uint) {
+			defer wg.Done()
+			<-start
+			result.record(amount, fixture.service.ApprovePaymentRequest(requestId, fixture.bankUserId))
+		}(requestId)
+	}
+	close(start)
+	wg.Wait()
+
+	finalBalance := fixture.checkLedger(t)
+	result.check(t, openingBalance, finalBalance)
+	if approved := len(requestIds) - len(result.refused) - len(result.failures); approved != 10 {
+		t.Errorf("%d approvals succeeded, the balance affords exactly 10", approved)
+	}
+	if !finalBalance.IsZero() {
+		t.Errorf("final balance is %s, expected 0", finalBalance)
+	}
+}
+
+func TestPaymentApprovalsRacingSalaryBatchCannotOverdraw(t *testing.T) {
+	openingBalance := money.New(100000, "INR")
+	amount := money.New(10000, "INR")
+	fixture := newApprovalFixture(t, openingBalance)
+	requestIds := fixture.createPaymentRequests(t, 8, amount)
+	batchId := fixture.createSalaryBatch(t, money.New(20000, "INR"), money.New(20000, "INR"))
+
+	result := &outcome{paid: money.Zero("INR")}
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, requestId := range requestIds {
+		wg.Add(1)
+		go func(requestId uint) {
+			defer wg.Done()
+			<-start
+			result.record(amount, fixture.service.ApprovePaymentRequest(requestId, fixture.bankUserId))
+		}(requestId)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-start
+		_, err := fixture.service.ReviewSalaryBatch(fixture.bankId, batchId, fixture.bankUserId,
+			salaryDisbursement.SalaryBatchReviewDTO{Action: salaryDisbursement.ReviewActionApprove})
+		result.record(money.New(40000, "INR"), err)
+	}()
+	close(start)
+	wg.Wait()
+
+	// 1,200.00 is asked of 1,000.00: either the batch and six payments go through, or the
+	// batch comes after the seventh payment and is refused, leaving room for all eight
+	finalBalance := fixture.checkLedger(t)
+	result.check(t, openingBalance, finalBalance)
+	if len(result.refused) == 0 {
+		t.Error("every approval succeeded, the balance cannot afford them all")
+	}
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value interface{}) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatal(err)
+	}
+}
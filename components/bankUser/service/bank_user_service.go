@@ -1,39 +1,75 @@
 package service
 
 import (
+	fxService "bankManagement/components/fx/service"
+	ledgerService "bankManagement/components/ledger/service"
+	notificationService "bankManagement/components/notification/service"
+	outboxService "bankManagement/components/outbox/service"
+	"bankManagement/constants"
+	"bankManagement/models/audit"
 	"bankManagement/models/bank"
 	"bankManagement/models/client"
 	"bankManagement/models/document"
+	"bankManagement/models/employee"
+	"bankManagement/models/outbox"
 	"bankManagement/models/payments"
+	"bankManagement/models/salaryDisbursement"
 	"bankManagement/models/transaction"
 	"bankManagement/models/user"
 	"bankManagement/repository"
-	"bankManagement/utils/email"
 	"bankManagement/utils/encrypt"
 	"bankManagement/utils/log"
+	"bankManagement/utils/money"
+	"bankManagement/utils/storage"
+	"bankManagement/utils/web"
 	"errors"
 	"fmt"
-	"strconv"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type BankUserService struct {
-	DB         *gorm.DB
-	repository repository.Repository
-	log        log.WebLogger
+	DB            *gorm.DB
+	repository    repository.Repository
+	log           log.WebLogger
+	ledger        *ledgerService.LedgerService
+	rates         fxService.RateProvider
+	store         storage.DocumentStore
+	events        *outboxService.OutboxService
+	notifications *notificationService.NotificationService
 }
 
-func NewBankUserService(db *gorm.DB, repo repository.Repository, log log.WebLogger) *BankUserService {
+func NewBankUserService(db *gorm.DB, repo repository.Repository, log log.WebLogger, ledger *ledgerService.LedgerService, rates fxService.RateProvider, store storage.DocumentStore, events *outboxService.OutboxService, notifications *notificationService.NotificationService) *BankUserService {
 	return &BankUserService{
-		DB:         db,
-		repository: repo,
-		log:        log,
+		DB:            db,
+		repository:    repo,
+		log:           log,
+		ledger:        ledger,
+		rates:         rates,
+		store:         store,
+		events:        events,
+		notifications: notifications,
 	}
 }
 
-func (s *BankUserService) CreateClient(clientDTO client.ClientDTO) error {
+// WithActor returns a copy of the service whose changes the audit log attributes to actor
+// and whose log lines carry the actor's request ID.
+func (s *BankUserService) WithActor(actor *audit.Actor) *BankUserService {
+	scoped := *s
+	scoped.DB = repository.BindActor(s.DB, actor)
+	scoped.log = s.log.WithFields(log.Fields{constants.RequestIDKey: actor.RequestId})
+	return &scoped
+}
+
+func (s *BankUserService) CreateClient(clientDTO client.ClientDTO, createdByUserId uint) error {
 
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
@@ -48,21 +84,40 @@ func (s *BankUserService) CreateClient(clientDTO client.ClientDTO) error {
 		return err
 	}
 
+	// currency is fixed for the life of the account, INR unless the bank user picks another
+	currency := strings.ToUpper(clientDTO.Currency)
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	openingBalance, err := clientDTO.Balance.InCurrency(currency)
+	if err != nil {
+		return err
+	}
+
 	clientEntity := &client.Client{
 		ClientName:         clientDTO.ClientName,
 		ClientEmail:        clientDTO.ClientEmail,
-		Balance:            clientDTO.Balance,
+		Balance:            openingBalance,
+		Currency:           currency,
 		IsActive:           clientDTO.IsActive,
-		VerificationStatus: clientDTO.VerificationStatus,
+		VerificationStatus: client.VerificationStatusPending, // KYC starts once documents are uploaded
 		BankID:             clientDTO.BankID,
 	}
+	if clientDTO.Balance.IsZero() {
+		clientEntity.Balance = money.Money{} // blank so the column default opening balance applies
+	}
 
 	if err := s.repository.Add(uow, clientEntity); err != nil {
 		return err
 	}
 
+	// Opening balance is booked in the ledger, Client.Balance only mirrors it
+	if err := s.ledger.PostOpeningBalance(uow, clientEntity, createdByUserId); err != nil {
+		return fmt.Errorf("failed to post opening balance: %w", err)
+	}
+
 	var clientUserRole user.Role
-	if err := s.repository.GetFirstWhere(uow, &clientUserRole, "role_name = ?", "CLIENT_USER"); err != nil {
+	if err := s.repository.GetFirstWhere(uow, &clientUserRole, "role_name = ?", user.RoleClientUser); err != nil {
 		if gorm.IsRecordNotFoundError(err) {
 			return fmt.Errorf("CLIENT_USER role not found: %w", err)
 		}
@@ -93,6 +148,9 @@ func (s *BankUserService) CreateClient(clientDTO client.ClientDTO) error {
 		return fmt.Errorf("failed to create cientUSer table: %w", err)
 	}
 
+	if err := s.publishClientEvent(uow, outbox.EventClientCreated, clientEntity, createdByUserId, ""); err != nil {
+		return err
+	}
 	uow.Commit()
 	return nil
 }
@@ -154,7 +212,7 @@ func (s *BankUserService) ValidateBankID(uow *repository.UOW, bankID uint) error
 
 // GET CLIENT BY ID
 func (s *BankUserService) GetClientByID(id uint, bankId uint) (*client.Client, error) {
-	fmt.Println("Getting ClientID in Service...")
+	s.log.Debug("Getting ClientID in Service...")
 
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
@@ -171,28 +229,27 @@ func (s *BankUserService) GetClientByID(id uint, bankId uint) (*client.Client, e
 		return nil, err // other errs directly
 	}
 
-	fmt.Println("Client found and mapped to DTO")
+	s.log.Debug("Client found and mapped to DTO")
 	uow.Commit()
 	return &clientEntity, nil
 }
 
 // / GET ALL CLIENTS
-func (s *BankUserService) GetAllClients(bankId uint) ([]client.Client, error) {
-	fmt.Println("GetAllClients service called")
+func (s *BankUserService) GetAllClients(bankId uint, query *web.ListQuery) ([]client.Client, error) {
+	s.log.Debug("GetAllClients service called")
 
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
 
 	var clients []client.Client
-	err := s.repository.GetAll(uow, &clients,
-		s.repository.Filter("bank_id=?", bankId),
-	)
+	queryProcessors := []repository.QueryProcessor{s.repository.Filter("bank_id=?", bankId)}
+	err := s.repository.GetAll(uow, &clients, append(queryProcessors, query.QueryProcessors(s.repository)...)...)
 	if err != nil {
-		fmt.Println("Error in retrieving clients: ", err)
+		s.log.Error("Error in retrieving clients: ", err)
 		return nil, err
 	}
 
-	fmt.Println("Successfully retrieved all clients")
+	s.log.Debug("Successfully retrieved all clients")
 	uow.Commit()
 	return clients, nil
 }
@@ -204,10 +261,11 @@ func (s *BankUserService) GetClientUserByClientID(clientID uint, bankID uint) (*
 
 	var clientUser client.ClientUser
 	// err := s.DB.Where("client_id = ?", clientID).First(&clientUser).Error
+	// a client has several users, the first one is the login created with the client
 	if err := s.repository.GetFirstWhere(
 		uow,
 		&clientUser,
-		s.repository.Filter("client_id=? AND bank_id=?", clientID, bankID),
+		"client_id = ? AND client_id IN (SELECT id FROM clients WHERE bank_id = ?)", clientID, bankID,
 	); err != nil {
 		if gorm.IsRecordNotFoundError(err) {
 			return nil, fmt.Errorf("no client user found for client ID %d and bank ID %d", clientID, bankID)
@@ -216,7 +274,7 @@ func (s *BankUserService) GetClientUserByClientID(clientID uint, bankID uint) (*
 	}
 
 	var userEntity user.User
-	if err := s.repository.GetFirstWhere(uow, &userEntity, s.repository.Filter("id=?", clientUser.UserID)); err != nil {
+	if err := s.repository.GetFirstWhere(uow, &userEntity, "id = ?", clientUser.UserID); err != nil {
 		return nil, fmt.Errorf("user associated with client ID %d not found", clientID)
 	}
 
@@ -225,21 +283,25 @@ func (s *BankUserService) GetClientUserByClientID(clientID uint, bankID uint) (*
 }
 
 // / UPDATE CLIENT
-func (s *BankUserService) UpdateClientByID(id uint, bankId uint, updatedData client.Client) error {
-	fmt.Println("UpdateClient service called ...")
+func (s *BankUserService) UpdateClientByID(id uint, bankId uint, updatedData client.Client, updatedByUserId uint) error {
+	s.log.Debug("UpdateClient service called ...")
 
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
 
-	// Check if it exists & fetch it
+	// Check if it exists & fetch it, locked as the row is saved back below and the balance may be adjusted
 	var existingClient client.Client
-	if err := s.repository.GetFirstWhere(uow, &existingClient, "id = ? AND bank_id = ?", id, bankId); err != nil {
-		if gorm.IsRecordNotFoundError(err) {
+	if err := s.repository.GetByIDForUpdate(uow, &existingClient, id); err != nil || existingClient.BankID != bankId {
+		if err == nil || gorm.IsRecordNotFoundError(err) {
 			return fmt.Errorf("client with ID %d not found or does not belong to the specified bank", id)
 		}
 		return err
 	}
 
+	if updatedData.Currency != "" && !strings.EqualFold(updatedData.Currency, existingClient.Currency) {
+		return errors.New("client currency cannot be changed once the account is opened")
+	}
+
 	// Updating fileds if BankUser wants that, otherwise i am keeping Old Values
 	if updatedData.ClientName != "" {
 		existingClient.ClientName = updatedData.ClientName
@@ -247,9 +309,6 @@ func (s *BankUserService) UpdateClientByID(id uint, bankId uint, updatedData cli
 	if updatedData.ClientEmail != "" {
 		existingClient.ClientEmail = updatedData.ClientEmail
 	}
-	if updatedData.Balance > 0 { // balance cannot be negative, will not update
-		existingClient.Balance = updatedData.Balance
-	}
 
 	// 0 or 1 - isActive
 	switch updatedData.IsActive {
@@ -259,17 +318,9 @@ func (s *BankUserService) UpdateClientByID(id uint, bankId uint, updatedData cli
 		return errors.New("invalid value for isActive; only true or false are allowed")
 	}
 
-	/// 3 values are allowed for verif. status -- checking that
-	allowedStatuses := map[string]bool{
-		"Pending":  true,
-		"Verified": true,
-		"Rejected": true,
-	}
-	if updatedData.VerificationStatus != "" {
-		if _, ok := allowedStatuses[updatedData.VerificationStatus]; !ok {
-			return fmt.Errorf("invalid verification status; allowed values are: Pending, Approved, Rejected")
-		}
-		existingClient.VerificationStatus = updatedData.VerificationStatus
+	// verification status only moves through the KYC workflow
+	if updatedData.VerificationStatus != "" && updatedData.VerificationStatus != existingClient.VerificationStatus {
+		return errors.New("verification status is changed through the KYC verify and reject endpoints")
 	}
 
 	///updated client record saved
@@ -277,14 +328,21 @@ func (s *BankUserService) UpdateClientByID(id uint, bankId uint, updatedData cli
 		return err
 	}
 
-	fmt.Println("Update ClientByID Controller Finished Successfullyy..")
+	// balance cannot be negative, will not update. Change is booked as a ledger adjustment
+	if updatedData.Balance.IsPositive() {
+		if err := s.ledger.PostAdjustment(uow, existingClient.ID, updatedData.Balance, updatedByUserId); err != nil {
+			return fmt.Errorf("failed to post balance adjustment: %w", err)
+		}
+	}
+
+	s.log.Debug("Update ClientByID Controller Finished Successfullyy..")
 	uow.Commit()
 	return nil
 }
 
 // // DELETE CLIENT
 func (s *BankUserService) DeleteClientByID(id uint, bankId uint) error {
-	fmt.Println("Deelte Client service called ...")
+	s.log.Debug("Deelte Client service called ...")
 
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
@@ -315,21 +373,11 @@ func (s *BankUserService) DeleteClientByID(id uint, bankId uint) error {
 		return fmt.Errorf("failed to delete client with ID %d: %w", id, err)
 	}
 
-	fmt.Println("Delete Client service finished ...")
+	s.log.Debug("Delete Client service finished ...")
 	uow.Commit()
 	return nil
 }
 
-func (s *BankUserService) VerifyClient(id uint, bankId uint) error {
-	clientEntity, err := s.GetClientByID(id, bankId)
-	if err != nil {
-		return fmt.Errorf("client not found: %w", err)
-	}
-	clientEntity.VerificationStatus = "Verified"
-
-	return s.UpdateClientByID(id, bankId, *clientEntity)
-}
-
 //--------------------------------------------------------------------------------------------------------------------------
 
 /////////////  Payment Approval Functions  //////////// Service /////
@@ -338,20 +386,24 @@ func (s *BankUserService) VerifyClient(id uint, bankId uint) error {
 func (s *BankUserService) ApprovePaymentRequest(paymentId uint, approvedByUserId uint) error {
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
-	//Getting Payment Request
+	//Getting Payment Request, locked so that it can only be approved once
 	paymentRequest := payments.PaymentRequest{}
-	err := s.repository.GetByID(uow, &paymentRequest, paymentId)
+	err := s.repository.GetByIDForUpdate(uow, &paymentRequest, paymentId)
 	if err != nil {
 		return err
 	}
+	if paymentRequest.Status != transaction.TransactionStatusPending {
+		return fmt.Errorf("payment request is already %s", paymentRequest.Status)
+	}
 	//Validating if user has access
-	bankUser := bank.BankUser{}
-	err = s.repository.GetFirstWhere(uow, &bankUser, "bank_id=? AND user_id=?", paymentRequest.AuthorizerBankId, approvedByUserId)
+	err = s.checkPaymentAuthorizer(uow, &paymentRequest, approvedByUserId)
 	if err != nil {
 		return err
 	}
-	if bankUser.UserID != approvedByUserId || bankUser.UserID == 0 {
-		return errors.New("unauthorized access to approve request")
+	//Locking both clients before the balance check, it must still hold when the ledger is posted
+	err = s.ledger.LockClients(uow, paymentRequest.SenderClientID, paymentRequest.ReceiverClientID)
+	if err != nil {
+		return err
 	}
 	//Validating if User has valid balance
 	senderClient := client.Client{}
@@ -359,9 +411,34 @@ func (s *BankUserService) ApprovePaymentRequest(paymentId uint, approvedByUserId
 	if err != nil {
 		return err
 	}
-	if senderClient.Balance < paymentRequest.PaymentAmount {
+	//KYC may have been revoked since the request was made
+	if err := senderClient.CheckVerified(); err != nil {
+		return err
+	}
+	senderBalance, err := s.ledger.GetClientBalance(uow, paymentRequest.SenderClientID)
+	if err != nil {
+		return err
+	}
+	if senderBalance.LessThan(paymentRequest.PaymentAmount) {
 		return errors.New("cannot approve the payment as balance insufficient. reject the payment or contact client to update balance")
 	}
+	receiverClient := client.Client{}
+	err = s.repository.GetByID(uow, &receiverClient, paymentRequest.ReceiverClientID)
+	if err != nil {
+		return err
+	}
+	// Converting at the rate quoted now, the receiver is credited in their own currency
+	rate, err := s.rates.GetRate(senderClient.Currency, receiverClient.Currency)
+	if err != nil {
+		return err
+	}
+	receivedAmount, err := paymentRequest.PaymentAmount.Convert(rate, receiverClient.Currency)
+	if err != nil {
+		return err
+	}
+	if !receivedAmount.IsPositive() {
+		return errors.New("payment amount is too small to convert to the receiver's currency")
+	}
 
 	//Creating New Payment Entry
 	paymentEntry := payments.Payment{
@@ -371,6 +448,8 @@ func (s *BankUserService) ApprovePaymentRequest(paymentId uint, approvedByUserId
 		// CreditTransactionID :,
 		// DebitTransactionID :,
 		PaymentAmount:    paymentRequest.PaymentAmount,
+		ReceivedAmount:   receivedAmount,
+		ExchangeRate:     rate,
 		Status:           transaction.TransactionStatusApproved,
 		CreatedByUserId:  paymentRequest.CreatedByUserId,
 		ApprovedByUserId: approvedByUserId,
@@ -387,34 +466,19 @@ func (s *BankUserService) ApprovePaymentRequest(paymentId uint, approvedByUserId
 	if err != nil {
 		return err
 	}
-	senderClient.Balance -= paymentEntry.PaymentAmount
-	err = s.repository.Update(uow, &senderClient)
-	if err != nil {
-		return err
-	}
 
 	//credit Transaction
-	receiverClient := client.Client{}
-	err = s.repository.GetByID(uow, &receiverClient, paymentRequest.SenderClientID)
-	if err != nil {
-		return err
-	}
 	creditTransaction := transaction.Transaction{
 		ClientID:          paymentRequest.ReceiverClientID,
 		TransactionType:   transaction.TransactionCredit,
 		PaymentType:       "Transfer",
-		TransactionAmount: paymentEntry.PaymentAmount,
+		TransactionAmount: paymentEntry.ReceivedAmount,
 		TransactionStatus: transaction.TransactionStatusApproved,
 	}
 	err = s.repository.Add(uow, &creditTransaction)
 	if err != nil {
 		return err
 	}
-	receiverClient.Balance += paymentEntry.PaymentAmount
-	err = s.repository.Update(uow, &receiverClient)
-	if err != nil {
-		return err
-	}
 
 	paymentEntry.CreditTransactionID = creditTransaction.ID
 	paymentEntry.DebitTransactionID = debitTransaction.ID
@@ -423,8 +487,17 @@ func (s *BankUserService) ApprovePaymentRequest(paymentId uint, approvedByUserId
 	if err != nil {
 		return err
 	}
+	//Moving the money: one balanced journal entry debiting sender and crediting receiver
+	err = s.ledger.PostPayment(uow, paymentEntry.SenderClientID, paymentEntry.ReceiverClientID, paymentEntry.PaymentAmount, paymentEntry.ReceivedAmount, paymentEntry.ID, paymentEntry.AuthorizedBankID, approvedByUserId)
+	if err != nil {
+		return err
+	}
+	resolvedAt := time.Now()
 	paymentRequest.Resolved = true
 	paymentRequest.Status = transaction.TransactionStatusApproved
+	paymentRequest.ReceivedAmount = receivedAmount
+	paymentRequest.ResolvedByUserId = approvedByUserId
+	paymentRequest.ResolvedAt = &resolvedAt
 
 	err = s.repository.
 		Update(uow, &paymentRequest)
@@ -432,21 +505,92 @@ func (s *BankUserService) ApprovePaymentRequest(paymentId uint, approvedByUserId
 		return err
 	}
 
-	go email.GetSMTPService().SendEmail("Payment Approved", "Your payment with id:"+strconv.Itoa(int(paymentId))+" has been approved", senderClient.ClientEmail)
+	err = s.events.Publish(uow, outbox.Event{
+		Type:          outbox.EventPaymentApproved,
+		AggregateType: "payment_requests",
+		AggregateId:   paymentRequest.ID,
+		BankId:        paymentRequest.AuthorizerBankId,
+		Payload: outbox.PaymentEvent{
+			PaymentRequestId: paymentRequest.ID,
+			PaymentId:        paymentEntry.ID,
+			SenderClientId:   paymentRequest.SenderClientID,
+			ReceiverClientId: paymentRequest.ReceiverClientID,
+			BankId:           paymentRequest.AuthorizerBankId,
+			Amount:           paymentRequest.PaymentAmount,
+			ReceivedAmount:   &receivedAmount,
+			ActorUserId:      approvedByUserId,
+		},
+	})
+	if err != nil {
+		return err
+	}
 	// paymentRequest.Status = "Approved"
 	uow.Commit()
 	return nil
 }
 
-// Reject payment Request
-func (s *BankUserService) RejectPaymentRequest(id uint) error {
-	paymentRequest, err := s.GetPaymentRequest(id)
+// Reject payment Request, the reason is kept on the request and sent to the client
+func (s *BankUserService) RejectPaymentRequest(id uint, rejectedByUserId uint, reason string) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	//Locked so that it cannot be approved meanwhile
+	paymentRequest := payments.PaymentRequest{}
+	err := s.repository.GetByIDForUpdate(uow, &paymentRequest, id)
+	if err != nil {
+		return errors.New("payment request not found")
+	}
+	if paymentRequest.Status != transaction.TransactionStatusPending {
+		return fmt.Errorf("payment request is already %s", paymentRequest.Status)
+	}
+	err = s.checkPaymentAuthorizer(uow, &paymentRequest, rejectedByUserId)
 	if err != nil {
 		return err
 	}
 
-	paymentRequest.Status = "Rejected"
-	return s.UpdatePaymentRequest(*paymentRequest)
+	resolvedAt := time.Now()
+	paymentRequest.Status = transaction.TransactionStatusRejected
+	paymentRequest.Resolved = true
+	paymentRequest.PostApprovalNote = reason
+	paymentRequest.ResolvedByUserId = rejectedByUserId
+	paymentRequest.ResolvedAt = &resolvedAt
+	err = s.repository.Update(uow, &paymentRequest)
+	if err != nil {
+		return err
+	}
+	err = s.events.Publish(uow, outbox.Event{
+		Type:          outbox.EventPaymentRejected,
+		AggregateType: "payment_requests",
+		AggregateId:   paymentRequest.ID,
+		BankId:        paymentRequest.AuthorizerBankId,
+		Payload: outbox.PaymentEvent{
+			PaymentRequestId: paymentRequest.ID,
+			SenderClientId:   paymentRequest.SenderClientID,
+			ReceiverClientId: paymentRequest.ReceiverClientID,
+			BankId:           paymentRequest.AuthorizerBankId,
+			Amount:           paymentRequest.PaymentAmount,
+			ActorUserId:      rejectedByUserId,
+			Reason:           reason,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// checkPaymentAuthorizer fails unless the user is a user of the bank the payment request
+// is to be authorized by.
+func (s *BankUserService) checkPaymentAuthorizer(uow *repository.UOW, paymentRequest *payments.PaymentRequest, userId uint) error {
+	bankUser := bank.BankUser{}
+	err := s.repository.GetFirstWhere(uow, &bankUser, "bank_id=? AND user_id=?", paymentRequest.AuthorizerBankId, userId)
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+	if bankUser.UserID != userId || bankUser.UserID == 0 {
+		return errors.New("unauthorized access to the payment request")
+	}
+	return nil
 }
 
 // Get Payment Request - Helper
@@ -478,9 +622,283 @@ func (s *BankUserService) UpdatePaymentRequest(paymentRequest payments.PaymentRe
 	return nil
 }
 
+/////////////  Salary Batch Approval Functions  //////////// Service /////
+
+func (s *BankUserService) GetAllSalaryBatches(bankId uint, query *web.ListQuery, batches *[]salaryDisbursement.SalaryBatch) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	queryProcessors := []repository.QueryProcessor{s.repository.Filter("bank_id = ?", bankId)}
+	if err := s.repository.GetAll(uow, batches, append(queryProcessors, query.QueryProcessors(s.repository)...)...); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+func (s *BankUserService) GetSalaryBatch(bankId uint, batchId uint) (*salaryDisbursement.SalaryBatch, error) {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	var batches []salaryDisbursement.SalaryBatch
+	err := s.repository.GetAll(uow, &batches,
+		s.repository.Filter("id = ? AND bank_id = ?", batchId, bankId),
+		s.repository.Preload("Lines"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) == 0 {
+		return nil, fmt.Errorf("salary batch with ID %d not found in bank ID %d", batchId, bankId)
+	}
+	uow.Commit()
+	return &batches[0], nil
+}
+
+// ReviewSalaryBatch approves or rejects the pending lines of a batch, all of them or only
+// review.LineIDs. Approved lines are paid right away from the client's account following the
+// batch strategy; the batch is closed once no line is left pending.
+func (s *BankUserService) ReviewSalaryBatch(bankId uint, batchId uint, reviewedByUserId uint, review salaryDisbursement.SalaryBatchReviewDTO) (*salaryDisbursement.SalaryBatchReport, error) {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+
+	//Locking the batch so two reviewers cannot resolve the same lines
+	batch := salaryDisbursement.SalaryBatch{}
+	err := s.repository.GetByIDForUpdate(uow, &batch, batchId)
+	if err != nil || batch.BankID != bankId {
+		return nil, fmt.Errorf("salary batch with ID %d not found in bank ID %d", batchId, bankId)
+	}
+	if batch.Status != salaryDisbursement.DisbursementStatusPending {
+		return nil, fmt.Errorf("salary batch is already %s", batch.Status)
+	}
+	//Validating if user has access
+	bankUser := bank.BankUser{}
+	err = s.repository.GetFirstWhere(uow, &bankUser, "bank_id=? AND user_id=?", bankId, reviewedByUserId)
+	if err != nil || bankUser.UserID == 0 {
+		return nil, errors.New("unauthorized access to review salary batch")
+	}
+
+	var pendingLines []salaryDisbursement.SalaryDisbursement
+	err = s.repository.GetAll(uow, &pendingLines,
+		s.repository.Filter("batch_id = ? AND status = ?", batch.ID, salaryDisbursement.DisbursementStatusPending),
+	)
+	if err != nil {
+		return nil, err
+	}
+	selectedLines, err := selectBatchLines(pendingLines, review.LineIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if review.Action == salaryDisbursement.ReviewActionApprove {
+		//Locking the client before the balance check, it must still hold when the ledger is posted
+		err = s.ledger.LockClients(uow, batch.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		//KYC may have been revoked since the batch was submitted
+		batchClient := client.Client{}
+		if err := s.repository.GetByID(uow, &batchClient, batch.ClientID); err != nil {
+			return nil, err
+		}
+		if err := batchClient.CheckVerified(); err != nil {
+			return nil, err
+		}
+		balance, err := s.ledger.GetClientBalance(uow, batch.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if batch.Strategy == salaryDisbursement.StrategyPriority {
+			err = s.paySalaryLinesByPriority(uow, selectedLines, balance, reviewedByUserId)
+		} else {
+			err = s.paySalaryLinesAllOrNothing(uow, selectedLines, balance, reviewedByUserId)
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for i := range selectedLines {
+			selectedLines[i].Status = salaryDisbursement.DisbursementStatusRejected
+			selectedLines[i].StatusReason = "rejected by bank"
+			if review.Note != "" {
+				selectedLines[i].StatusReason = "rejected by bank: " + review.Note
+			}
+			if err := s.repository.Update(uow, &selectedLines[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	//Closing the batch once every line is resolved
+	var lines []salaryDisbursement.SalaryDisbursement
+	err = s.repository.GetAll(uow, &lines, s.repository.Filter("batch_id = ?", batch.ID))
+	if err != nil {
+		return nil, err
+	}
+	batch.Status = salaryDisbursement.DisbursementStatusRejected
+	for _, line := range lines {
+		if line.Status == salaryDisbursement.DisbursementStatusPending {
+			batch.Status = salaryDisbursement.DisbursementStatusPending
+			break
+		}
+		if line.Status == salaryDisbursement.DisbursementStatusApproved {
+			batch.Status = salaryDisbursement.DisbursementStatusApproved
+		}
+	}
+	batch.ReviewedByUserId = reviewedByUserId
+	if review.Note != "" {
+		batch.ReviewNote = review.Note
+	}
+	err = s.repository.Update(uow, &batch)
+	if err != nil {
+		return nil, err
+	}
+	batch.Lines = lines
+	report, err := s.salaryBatchReport(uow, &batch)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.publishSalaryBatchEvents(uow, &batch, selectedLines, reviewedByUserId)
+	if err != nil {
+		return nil, err
+	}
+	uow.Commit()
+	return report, nil
+}
+
+func (s *BankUserService) paySalaryLinesAllOrNothing(uow *repository.UOW, lines []salaryDisbursement.SalaryDisbursement, balance money.Money, approvedByUserId uint) error {
+	total := money.Zero(balance.Currency)
+	for _, line := range lines {
+		total = total.Add(line.SalaryAmount)
+	}
+	if balance.LessThan(total) {
+		return errors.New("cannot approve the salary lines as balance insufficient. reject them or contact client to update balance")
+	}
+	for i := range lines {
+		if err := s.paySalaryLine(uow, &lines[i], approvedByUserId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// paySalaryLinesByPriority pays lines by ascending employee priority (then employee ID) while the
+// balance covers them. Once a line does not fit, it and every line after it are skipped.
+func (s *BankUserService) paySalaryLinesByPriority(uow *repository.UOW, lines []salaryDisbursement.SalaryDisbursement, balance money.Money, approvedByUserId uint) error {
+	priorities := make(map[uint]int)
+	for _, line := range lines {
+		emp := employee.Employee{}
+		if err := s.repository.GetByID(uow, &emp, line.EmpID); err != nil {
+			return err
+		}
+		priorities[line.EmpID] = emp.Priority
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		if priorities[lines[i].EmpID] != priorities[lines[j].EmpID] {
+			return priorities[lines[i].EmpID] < priorities[lines[j].EmpID]
+		}
+		return lines[i].EmpID < lines[j].EmpID
+	})
+
+	remaining := balance
+	fundsRunOut := false
+	for i := range lines {
+		if !fundsRunOut && remaining.LessThan(lines[i].SalaryAmount) {
+			fundsRunOut = true
+			lines[i].StatusReason = fmt.Sprintf("insufficient balance: %s left, %s needed", remaining, lines[i].SalaryAmount)
+		} else if fundsRunOut {
+			lines[i].StatusReason = "funds ran out on a higher priority employee"
+		}
+		if fundsRunOut {
+			lines[i].Status = salaryDisbursement.DisbursementStatusRejected
+			if err := s.repository.Update(uow, &lines[i]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.paySalaryLine(uow, &lines[i], approvedByUserId); err != nil {
+			return err
+		}
+		remaining = remaining.Sub(lines[i].SalaryAmount)
+	}
+	return nil
+}
+
+// salaryBatchReport lists the outcome of every line of the batch.
+func (s *BankUserService) salaryBatchReport(uow *repository.UOW, batch *salaryDisbursement.SalaryBatch) (*salaryDisbursement.SalaryBatchReport, error) {
+	report := &salaryDisbursement.SalaryBatchReport{Batch: batch}
+	for _, line := range batch.Lines {
+		emp := employee.Employee{}
+		if err := s.repository.GetByID(uow, &emp, line.EmpID); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return nil, err
+		}
+		report.Results = append(report.Results, salaryDisbursement.DisbursementResultDTO{
+			EmployeeID:   line.EmpID,
+			EmployeeName: emp.EmployeeName,
+			Amount:       line.SalaryAmount,
+			Result:       salaryDisbursement.ResultOf(line.Status),
+			Reason:       line.StatusReason,
+		})
+	}
+	return report, nil
+}
+
+// selectBatchLines picks the lines a review applies to: all pending lines, or exactly lineIds.
+func selectBatchLines(pendingLines []salaryDisbursement.SalaryDisbursement, lineIds []uint) ([]salaryDisbursement.SalaryDisbursement, error) {
+	if len(lineIds) == 0 {
+		return pendingLines, nil
+	}
+	pendingById := make(map[uint]salaryDisbursement.SalaryDisbursement)
+	for _, line := range pendingLines {
+		pendingById[line.ID] = line
+	}
+	var selected []salaryDisbursement.SalaryDisbursement
+	for _, lineId := range lineIds {
+		line, ok := pendingById[lineId]
+		if !ok {
+			return nil, fmt.Errorf("line ID %d is not a pending line of this batch", lineId)
+		}
+		selected = append(selected, line)
+		delete(pendingById, lineId)
+	}
+	return selected, nil
+}
+
+// paySalaryLine moves the money of one approved line: debit transaction, employee total, ledger posting.
+func (s *BankUserService) paySalaryLine(uow *repository.UOW, line *salaryDisbursement.SalaryDisbursement, approvedByUserId uint) error {
+	//Can use api to disburse salary to account number. In that case status will be pending. and can be updated using webhooks
+	tempTransaction := &transaction.Transaction{
+		ClientID:          line.ClientID,
+		PaymentType:       "Transfer",
+		TransactionType:   transaction.TransactionDebit,
+		TransactionAmount: line.SalaryAmount,
+		TransactionStatus: transaction.TransactionStatusApproved,
+	}
+	err := s.repository.Add(uow, tempTransaction)
+	if err != nil {
+		return err
+	}
+	emp := employee.Employee{}
+	err = s.repository.GetByID(uow, &emp, line.EmpID)
+	if err != nil {
+		return err
+	}
+	emp.TotalSalaryReceived = emp.TotalSalaryReceived.Add(line.SalaryAmount)
+	err = s.repository.Update(uow, &emp)
+	if err != nil {
+		return err
+	}
+	line.TransactionID = tempTransaction.ID
+	line.Status = salaryDisbursement.DisbursementStatusApproved
+	err = s.repository.Update(uow, line)
+	if err != nil {
+		return err
+	}
+	return s.ledger.PostSalary(uow, line.ClientID, line.EmpID, line.SalaryAmount, line.ID, approvedByUserId)
+}
+
 ///////////// Transaction Report Functions  ///////// Service ///////
 
-func (s *BankUserService) GenerateTransactionReport(clientID uint) ([]transaction.Transaction, error) {
+func (s *BankUserService) GenerateTransactionReport(clientID uint, query *web.ListQuery) ([]transaction.Transaction, error) {
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
 
@@ -488,7 +906,7 @@ func (s *BankUserService) GenerateTransactionReport(clientID uint) ([]transactio
 	queryProcessors := []repository.QueryProcessor{
 		s.repository.Filter("client_id = ?", clientID),
 	}
-	err := s.repository.GetAll(uow, &transactions, queryProcessors...)
+	err := s.repository.GetAll(uow, &transactions, append(queryProcessors, query.QueryProcessors(s.repository)...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -499,33 +917,87 @@ func (s *BankUserService) GenerateTransactionReport(clientID uint) ([]transactio
 
 //-------------------------------------------------------------------------------------------------------
 
-// upload document
-// Service layer method to create a document record
-func (s *BankUserService) CreateDocument(doc document.Document) error {
-
-	fmt.Println(" --------------------CreateDocument Service Function  START--------------------")
+// UploadDocument stores a KYC document of one of the bank's clients. The type is sniffed
+// from the content, the blob goes to the document store under its content hash and the
+// document row points at it. Uploading the last missing required document moves the
+// client to ReadyForReview.
+func (s *BankUserService) UploadDocument(bankID uint, clientID uint, uploadedByUserId uint, documentType string, fileName string, content []byte) (*document.Document, error) {
+	if len(content) == 0 {
+		return nil, errors.New("document is empty")
+	}
+	if int64(len(content)) > constants.DocumentMaxBytes {
+		return nil, fmt.Errorf("document is larger than %d bytes", constants.DocumentMaxBytes)
+	}
+	fileType, _, _ := mime.ParseMediaType(http.DetectContentType(content))
+	if !slices.Contains(constants.DocumentAllowedTypes, fileType) {
+		return nil, fmt.Errorf("document type %s is not allowed, use one of: %s", fileType, strings.Join(constants.DocumentAllowedTypes, ", "))
+	}
 
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
 
-	if err := s.repository.Add(uow, &doc); err != nil {
-		return fmt.Errorf("failed to add document: %w", err)
+	// locked as its KYC status may move below
+	clientEntity := client.Client{}
+	if err := s.repository.GetByIDForUpdate(uow, &clientEntity, clientID); err != nil || clientEntity.BankID != bankID {
+		if err == nil || gorm.IsRecordNotFoundError(err) {
+			return nil, fmt.Errorf("client with ID %d not found for bank ID %d", clientID, bankID)
+		}
+		return nil, err
+	}
+	documentType, err := s.validateDocumentType(uow, bankID, documentType)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, key := storage.ContentKey(content)
+	if err := s.store.Put(key, content, fileType); err != nil {
+		s.log.Error(err)
+		return nil, errors.New("document could not be stored")
+	}
+	documentRecord := document.Document{
+		FileName:         fileName,
+		FileType:         fileType,
+		DocumentType:     documentType,
+		FileURL:          key,
+		ContentHash:      hash,
+		Size:             int64(len(content)),
+		UploadedByUserId: uploadedByUserId,
+		ClientId:         clientID,
+		BankId:           bankID,
+	}
+	if err := s.repository.Add(uow, &documentRecord); err != nil {
+		return nil, fmt.Errorf("failed to add document: %w", err)
+	}
+	if err := s.refreshKycStatus(uow, &clientEntity, true); err != nil {
+		return nil, err
 	}
 
 	uow.Commit()
+	return &documentRecord, nil
+}
 
-	fmt.Println(" --------------------CreateDocument Service Function - END-------------------")
-
-	return nil
+// OpenDocument returns the document row and a reader over its file, the caller closes it.
+func (s *BankUserService) OpenDocument(docID uint, bankID uint) (*document.Document, io.ReadCloser, error) {
+	documentEntity, err := s.GetDocumentByID(docID, bankID)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.store.Get(documentEntity.FileURL)
+	if err != nil {
+		s.log.Error(err)
+		return nil, nil, errors.New("document file could not be read")
+	}
+	return documentEntity, content, nil
 }
 
 // all documents for a specific bank
-func (s *BankUserService) GetAllDocuments(bankID uint) ([]document.Document, error) {
+func (s *BankUserService) GetAllDocuments(bankID uint, query *web.ListQuery) ([]document.Document, error) {
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
 
 	var documents []document.Document
-	if err := s.repository.GetAll(uow, &documents, s.repository.Filter("bank_id = ?", bankID)); err != nil {
+	queryProcessors := []repository.QueryProcessor{s.repository.Filter("bank_id = ?", bankID)}
+	if err := s.repository.GetAll(uow, &documents, append(queryProcessors, query.QueryProcessors(s.repository)...)...); err != nil {
 		return nil, fmt.Errorf("error retrieving documents: %w", err)
 	}
 
@@ -552,7 +1024,7 @@ func (s *BankUserService) GetDocumentByID(docID uint, bankID uint) (*document.Do
 
 // / DELETE DOCUMENT - service
 func (s *BankUserService) DeleteDocumentByID(docID uint, bankID uint) error {
-	fmt.Println("DeleteDocumentByID service called")
+	s.log.Debug("DeleteDocumentByID service called")
 
 	uow := repository.NewUnitOfWork(s.DB)
 	defer uow.RollBack()
@@ -567,17 +1039,37 @@ func (s *BankUserService) DeleteDocumentByID(docID uint, bankID uint) error {
 		return fmt.Errorf("failed to fetch document: %w", err)
 	}
 
-	// // Remove file from the filesystem
-	// if err := os.Remove(documentEntity.FileURL); err != nil {
-	// 	return fmt.Errorf("failed to delete file from filesystem: %w", err)
-	// }
-
 	// Delete the document record from the database
 	if err := s.repository.DeleteById(uow, &documentEntity, docID); err != nil {
 		return fmt.Errorf("failed to delete document record: %w", err)
 	}
 
-	fmt.Println("DeleteDocumentByID service finished")
+	clientEntity := client.Client{}
+	if err := s.repository.GetByIDForUpdate(uow, &clientEntity, documentEntity.ClientId); err == nil {
+		if err := s.refreshKycStatus(uow, &clientEntity, false); err != nil {
+			return err
+		}
+	} else if !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+
+	// the blob is content addressed, other documents may share it
+	var sharingDocuments []document.Document
+	if err := s.repository.GetAll(uow, &sharingDocuments,
+		s.repository.Filter("file_url = ?", documentEntity.FileURL),
+		s.repository.Limit(1),
+	); err != nil {
+		return err
+	}
+
+	s.log.Debug("DeleteDocumentByID service finished")
 	uow.Commit()
+
+	// removed only once the row is gone for good, a leftover blob is harmless
+	if len(sharingDocuments) == 0 {
+		if err := s.store.Delete(documentEntity.FileURL); err != nil {
+			s.log.Error(err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,128 @@
+package service
+
+import (
+	outboxService "bankManagement/components/outbox/service"
+	"bankManagement/models/client"
+	"bankManagement/models/notification"
+	"bankManagement/models/outbox"
+	"bankManagement/models/salaryDisbursement"
+	"bankManagement/repository"
+	"bankManagement/utils/money"
+	"strconv"
+)
+
+func (s *BankUserService) publishClientEvent(uow *repository.UOW, eventType string, clientEntity *client.Client, actorUserId uint, reason string) error {
+	return s.events.Publish(uow, outbox.Event{
+		Type:          eventType,
+		AggregateType: "clients",
+		AggregateId:   clientEntity.ID,
+		BankId:        clientEntity.BankID,
+		Payload: outbox.ClientEvent{
+			ClientId:    clientEntity.ID,
+			BankId:      clientEntity.BankID,
+			ClientName:  clientEntity.ClientName,
+			ClientEmail: clientEntity.ClientEmail,
+			ActorUserId: actorUserId,
+			Reason:      reason,
+		},
+	})
+}
+
+// publishSalaryBatchEvents announces the lines the review paid, and the batch once the
+// review closed it.
+func (s *BankUserService) publishSalaryBatchEvents(uow *repository.UOW, batch *salaryDisbursement.SalaryBatch, reviewedLines []salaryDisbursement.SalaryDisbursement, reviewedByUserId uint) error {
+	batchEvent := outbox.SalaryBatchEvent{
+		BatchId:     batch.ID,
+		ClientId:    batch.ClientID,
+		BankId:      batch.BankID,
+		Status:      batch.Status,
+		PaidAmount:  money.Zero(batch.TotalAmount.CurrencyCode()),
+		ActorUserId: reviewedByUserId,
+	}
+	for _, line := range reviewedLines {
+		if line.Status == salaryDisbursement.DisbursementStatusApproved {
+			batchEvent.PaidLines++
+			batchEvent.PaidAmount = batchEvent.PaidAmount.Add(line.SalaryAmount)
+		}
+	}
+	event := outbox.Event{
+		AggregateType: "salary_batches",
+		AggregateId:   batch.ID,
+		BankId:        batch.BankID,
+		Payload:       batchEvent,
+	}
+	if batchEvent.PaidLines > 0 {
+		event.Type = outbox.EventSalaryDisbursed
+		if err := s.events.Publish(uow, event); err != nil {
+			return err
+		}
+	}
+	if batch.Status != salaryDisbursement.DisbursementStatusPending {
+		event.Type = outbox.EventSalaryBatchReviewed
+		if err := s.events.Publish(uow, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SubscribeNotifications notifies the clients of KYC reviews, approved and rejected payments
+// and reviewed salary batches once the change is committed. The subscriber keeps the name it had
+// when it only sent emails, the outbox records deliveries under it.
+func (s *BankUserService) SubscribeNotifications(events *outboxService.OutboxService) {
+	events.Subscribe("bank_user.emails", s.sendEventNotification,
+		outbox.EventClientVerified, outbox.EventClientRejected, outbox.EventPaymentApproved, outbox.EventPaymentRejected, outbox.EventSalaryBatchReviewed)
+}
+
+func (s *BankUserService) sendEventNotification(event *outbox.OutboxEvent) error {
+	key := "outbox:" + strconv.Itoa(int(event.ID))
+	switch event.EventType {
+	case outbox.EventClientVerified, outbox.EventClientRejected:
+		var payload outbox.ClientEvent
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		eventType := notification.EventClientVerified
+		if event.EventType == outbox.EventClientRejected {
+			eventType = notification.EventClientRejected
+		}
+		return s.notifications.NotifyOnce(key, eventType, notification.Recipient{Email: payload.ClientEmail}, payload)
+	case outbox.EventPaymentApproved, outbox.EventPaymentRejected:
+		var payload outbox.PaymentEvent
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		clientEmail, err := s.clientEmail(payload.SenderClientId)
+		if err != nil {
+			return err
+		}
+		eventType := notification.EventPaymentApproved
+		if event.EventType == outbox.EventPaymentRejected {
+			eventType = notification.EventPaymentRejected
+		}
+		return s.notifications.NotifyOnce(key, eventType, notification.Recipient{Email: clientEmail}, payload)
+	case outbox.EventSalaryBatchReviewed:
+		var payload outbox.SalaryBatchEvent
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		clientEmail, err := s.clientEmail(payload.ClientId)
+		if err != nil {
+			return err
+		}
+		return s.notifications.NotifyOnce(key, notification.EventSalaryBatchReviewed, notification.Recipient{Email: clientEmail}, payload)
+	}
+	return nil
+}
+
+func (s *BankUserService) clientEmail(clientId uint) (string, error) {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	clientEntity := client.Client{}
+	err := s.repository.GetByID(uow, &clientEntity, clientId)
+	if err != nil {
+		return "", err
+	}
+	uow.Commit()
+	return clientEntity.ClientEmail, nil
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"bankManagement/models/client"
+	"bankManagement/models/document"
+	"bankManagement/models/outbox"
+	"bankManagement/repository"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/////////////  KYC Functions  //////////// Service /////
+
+// AddRequiredDocumentType makes every client of the bank upload a document of this type
+// before KYC review. Clients already waiting for review are not moved back.
+func (s *BankUserService) AddRequiredDocumentType(bankId uint, documentType *document.RequiredDocumentType) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+
+	documentType.BankID = bankId
+	documentType.DocumentType = strings.TrimSpace(documentType.DocumentType)
+	existing := document.RequiredDocumentType{}
+	err := s.repository.GetFirstWhere(uow, &existing, "bank_id = ? AND document_type = ?", bankId, documentType.DocumentType)
+	if err == nil {
+		return fmt.Errorf("document type %s is already required", documentType.DocumentType)
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+	if err := s.repository.Add(uow, documentType); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+func (s *BankUserService) GetRequiredDocumentTypes(bankId uint, documentTypes *[]document.RequiredDocumentType) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	if err := s.repository.GetAll(uow, documentTypes, s.repository.Filter("bank_id = ?", bankId)); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+func (s *BankUserService) DeleteRequiredDocumentType(bankId uint, id uint) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	documentType := document.RequiredDocumentType{}
+	if err := s.repository.GetFirstWhere(uow, &documentType, "id = ? AND bank_id = ?", id, bankId); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return fmt.Errorf("required document type with ID %d not found for bank ID %d", id, bankId)
+		}
+		return err
+	}
+	if err := s.repository.DeleteById(uow, &documentType, id); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// GetKycStatus lists the client's status with the required document types still missing.
+func (s *BankUserService) GetKycStatus(clientId uint, bankId uint) (*document.KycStatusDTO, error) {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	clientEntity := client.Client{}
+	if err := s.repository.GetFirstWhere(uow, &clientEntity, "id = ? AND bank_id = ?", clientId, bankId); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, fmt.Errorf("client with ID %d not found for bank ID %d", clientId, bankId)
+		}
+		return nil, err
+	}
+	status, err := s.kycStatus(uow, &clientEntity)
+	if err != nil {
+		return nil, err
+	}
+	uow.Commit()
+	return status, nil
+}
+
+// VerifyClient passes KYC for a client whose required documents are all uploaded.
+func (s *BankUserService) VerifyClient(id uint, bankId uint, verifiedByUserId uint) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	clientEntity, err := s.getClientForKycReview(uow, id, bankId)
+	if err != nil {
+		return err
+	}
+	if clientEntity.VerificationStatus != client.VerificationStatusReadyForReview {
+		return fmt.Errorf("client is %s, only clients ready for review can be verified", clientEntity.VerificationStatus)
+	}
+	status, err := s.kycStatus(uow, clientEntity)
+	if err != nil {
+		return err
+	}
+	if len(status.MissingDocumentTypes) > 0 {
+		return fmt.Errorf("client is missing required documents: %s", strings.Join(status.MissingDocumentTypes, ", "))
+	}
+
+	now := time.Now()
+	clientEntity.VerificationStatus = client.VerificationStatusVerified
+	clientEntity.VerificationNote = ""
+	clientEntity.VerifiedByUserId = verifiedByUserId
+	clientEntity.VerifiedAt = &now
+	if err := s.repository.Update(uow, clientEntity); err != nil {
+		return err
+	}
+	if err := s.publishClientEvent(uow, outbox.EventClientVerified, clientEntity, verifiedByUserId, ""); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// RejectClient fails KYC with a reason, also revoking an earlier verification. The client
+// goes back to ReadyForReview on the next document upload.
+func (s *BankUserService) RejectClient(id uint, bankId uint, rejectedByUserId uint, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return errors.New("a reason is required to reject a client")
+	}
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	clientEntity, err := s.getClientForKycReview(uow, id, bankId)
+	if err != nil {
+		return err
+	}
+	if clientEntity.VerificationStatus != client.VerificationStatusReadyForReview && clientEntity.VerificationStatus != client.VerificationStatusVerified {
+		return fmt.Errorf("client is %s, only clients ready for review or verified can be rejected", clientEntity.VerificationStatus)
+	}
+
+	clientEntity.VerificationStatus = client.VerificationStatusRejected
+	clientEntity.VerificationNote = reason
+	clientEntity.VerifiedByUserId = rejectedByUserId
+	clientEntity.VerifiedAt = nil
+	if err := s.repository.Update(uow, clientEntity); err != nil {
+		return err
+	}
+	if err := s.publishClientEvent(uow, outbox.EventClientRejected, clientEntity, rejectedByUserId, reason); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// getClientForKycReview locks the client so concurrent uploads and reviews serialize.
+func (s *BankUserService) getClientForKycReview(uow *repository.UOW, id uint, bankId uint) (*client.Client, error) {
+	clientEntity := client.Client{}
+	if err := s.repository.GetByIDForUpdate(uow, &clientEntity, id); err != nil || clientEntity.BankID != bankId {
+		if err == nil || gorm.IsRecordNotFoundError(err) {
+			return nil, fmt.Errorf("client with ID %d not found for bank ID %d", id, bankId)
+		}
+		return nil, err
+	}
+	return &clientEntity, nil
+}
+
+// refreshKycStatus moves a locked client after its documents changed: an upload completing
+// the required set makes a Pending or Rejected client ReadyForReview, a deletion breaking it
+// sends a client waiting for review back to Pending. Verified clients are left alone.
+func (s *BankUserService) refreshKycStatus(uow *repository.UOW, clientEntity *client.Client, uploaded bool) error {
+	status, err := s.kycStatus(uow, clientEntity)
+	if err != nil {
+		return err
+	}
+	complete := len(status.MissingDocumentTypes) == 0
+	switch {
+	case uploaded && complete &&
+		(clientEntity.VerificationStatus == client.VerificationStatusPending || clientEntity.VerificationStatus == client.VerificationStatusRejected):
+		clientEntity.VerificationStatus = client.VerificationStatusReadyForReview
+	case !uploaded && !complete && clientEntity.VerificationStatus == client.VerificationStatusReadyForReview:
+		clientEntity.VerificationStatus = client.VerificationStatusPending
+	default:
+		return nil
+	}
+	return s.repository.Update(uow, clientEntity)
+}
+
+func (s *BankUserService) kycStatus(uow *repository.UOW, clientEntity *client.Client) (*document.KycStatusDTO, error) {
+	var requiredTypes []document.RequiredDocumentType
+	if err := s.repository.GetAll(uow, &requiredTypes, s.repository.Filter("bank_id = ?", clientEntity.BankID)); err != nil {
+		return nil, err
+	}
+	var documents []document.Document
+	if err := s.repository.GetAll(uow, &documents, s.repository.Filter("client_id = ?", clientEntity.ID)); err != nil {
+		return nil, err
+	}
+
+	status := &document.KycStatusDTO{
+		ClientID:              clientEntity.ID,
+		VerificationStatus:    clientEntity.VerificationStatus,
+		VerificationNote:      clientEntity.VerificationNote,
+		VerifiedAt:            clientEntity.VerifiedAt,
+		RequiredDocumentTypes: []string{},
+		UploadedDocumentTypes: []string{},
+		MissingDocumentTypes:  []string{},
+	}
+	for _, doc := range documents {
+		if doc.DocumentType != "" && !slices.Contains(status.UploadedDocumentTypes, doc.DocumentType) {
+			status.UploadedDocumentTypes = append(status.UploadedDocumentTypes, doc.DocumentType)
+		}
+	}
+	for _, requiredType := range requiredTypes {
+		status.RequiredDocumentTypes = append(status.RequiredDocumentTypes, requiredType.DocumentType)
+		if !slices.Contains(status.UploadedDocumentTypes, requiredType.DocumentType) {
+			status.MissingDocumentTypes = append(status.MissingDocumentTypes, requiredType.DocumentType)
+		}
+	}
+	return status, nil
+}
+
+// validateDocumentType requires one of the bank's document types when it configured any.
+func (s *BankUserService) validateDocumentType(uow *repository.UOW, bankId uint, documentType string) (string, error) {
+	documentType = strings.TrimSpace(documentType)
+	if documentType == "" {
+		return "", errors.New("document_type is required")
+	}
+	var requiredTypes []document.RequiredDocumentType
+	if err := s.repository.GetAll(uow, &requiredTypes, s.repository.Filter("bank_id = ?", bankId)); err != nil {
+		return "", err
+	}
+	if len(requiredTypes) == 0 {
+		return documentType, nil
+	}
+	allowed := make([]string, 0, len(requiredTypes))
+	for _, requiredType := range requiredTypes {
+		if strings.EqualFold(requiredType.DocumentType, documentType) {
+			return requiredType.DocumentType, nil
+		}
+		allowed = append(allowed, requiredType.DocumentType)
+	}
+	return "", fmt.Errorf("document_type %s is not required by the bank, use one of: %s", documentType, strings.Join(allowed, ", "))
+}
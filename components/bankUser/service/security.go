@@ -0,0 +1,22 @@
+package service
+
+import (
+	"bankManagement/models/bank"
+	"bankManagement/repository"
+)
+
+// UpdateBankSecurity sets whether the bank's users must log in with a two-factor code. Users
+// who have not enrolled yet are asked to at their next login.
+func (s *BankUserService) UpdateBankSecurity(bankId uint, security *bank.BankSecurityDTO, bankEntity *bank.Bank) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	if err := s.repository.GetByIDForUpdate(uow, bankEntity, bankId); err != nil {
+		return err
+	}
+	bankEntity.RequireTwoFactor = *security.RequireTwoFactor
+	if err := s.repository.Update(uow, bankEntity); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
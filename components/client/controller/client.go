@@ -2,47 +2,69 @@ package controller
 
 import (
 	"bankManagement/components/client/service"
+	idempotencyService "bankManagement/components/idempotency/service"
 	"bankManagement/constants"
 	"bankManagement/middlewares/auth"
+	"bankManagement/middlewares/idempotency"
 	"bankManagement/models/employee"
 	"bankManagement/models/reports"
+	"bankManagement/models/salaryDisbursement"
+	"bankManagement/models/user"
 	"bankManagement/utils/encrypt"
 	errorsUtils "bankManagement/utils/errors"
 	"bankManagement/utils/log"
+	"bankManagement/utils/spreadsheet"
 	"bankManagement/utils/web"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
 
 type ClientController struct {
-	ClientService *service.ClientService
-	log           log.WebLogger
+	ClientService      *service.ClientService
+	idempotencyService *idempotencyService.IdempotencyService
+	log                log.WebLogger
 }
 
 func NewClientController(
 	ClientService *service.ClientService,
+	idempotencyService *idempotencyService.IdempotencyService,
 	log log.WebLogger,
 ) *ClientController {
 	return &ClientController{
-		ClientService: ClientService,
-		log:           log,
+		ClientService:      ClientService,
+		idempotencyService: idempotencyService,
+		log:                log,
 	}
 }
 
+var employeeListOptions = web.ListOptions{
+	SortFields: map[string]string{
+		"id": "id", "name": "employee_name", "salary_amount": "salary_amount", "priority": "priority", "created_at": "created_at",
+	},
+	FilterFields: map[string]string{"account_no": "account_no", "priority": "priority"},
+}
+
 func (ctrl *ClientController) RegisterRoutes(
 	router *mux.Router) {
 	subRouter := router.PathPrefix("/clients/{client_id}/").Subrouter()
 	subRouter.Use(auth.AuthenticationMiddleware, auth.ValidateClientPermissionsMiddleware)
-	subRouter.HandleFunc("/employees", ctrl.CreateEmployee).Methods(http.MethodPost)
-	subRouter.HandleFunc("/employees", ctrl.GetAllEmployees).Methods(http.MethodGet)
-	subRouter.HandleFunc("/employees/{employee_id}", ctrl.GetAllEmployees).Methods(http.MethodGet)
-	subRouter.HandleFunc("/employees/{employee_id}", ctrl.UpdateEmployee).Methods(http.MethodPut)
-	subRouter.HandleFunc("/employees/{employee_id}", ctrl.DeleteEmployeeById).Methods(http.MethodDelete)
-	subRouter.HandleFunc("/disburse_salary", ctrl.DisburseSalary).Methods(http.MethodPost)
-	subRouter.HandleFunc("/reports/salary_report", ctrl.GetSalaryReport).Methods(http.MethodPost)
-	subRouter.HandleFunc("/reports/payment_report", ctrl.GetPaymentReport).Methods(http.MethodPost)
+	subRouter.Handle("/employees", auth.Require(ctrl.CreateEmployee, user.PermissionEmployeeWrite)).Methods(http.MethodPost)
+	subRouter.Handle("/employees", auth.Require(ctrl.GetAllEmployees, user.PermissionEmployeeRead)).Methods(http.MethodGet)
+	subRouter.Handle("/employees/import", auth.Require(ctrl.ImportEmployees, user.PermissionEmployeeWrite)).Methods(http.MethodPost)
+	subRouter.Handle("/employees/export", auth.Require(ctrl.ExportEmployees, user.PermissionEmployeeRead)).Methods(http.MethodGet)
+	subRouter.Handle("/employees/{employee_id}", auth.Require(ctrl.GetAllEmployees, user.PermissionEmployeeRead)).Methods(http.MethodGet)
+	subRouter.Handle("/employees/{employee_id}", auth.Require(ctrl.UpdateEmployee, user.PermissionEmployeeWrite)).Methods(http.MethodPut)
+	subRouter.Handle("/employees/{employee_id}", auth.Require(ctrl.DeleteEmployeeById, user.PermissionEmployeeWrite)).Methods(http.MethodDelete)
+	subRouter.Handle("/disburse_salary", auth.Require(idempotency.Idempotent(ctrl.idempotencyService, ctrl.DisburseSalary), user.PermissionSalaryDisburse)).Methods(http.MethodPost)
+	subRouter.Handle("/reports/salary_report", auth.Require(ctrl.GetSalaryReport, user.PermissionReportRead)).Methods(http.MethodPost)
+	subRouter.Handle("/reports/payment_report", auth.Require(ctrl.GetPaymentReport, user.PermissionReportRead)).Methods(http.MethodPost)
 }
 
 func (ctrl *ClientController) Todo(w http.ResponseWriter, r *http.Request) {
@@ -76,7 +98,7 @@ func (ctrl *ClientController) CreateEmployee(w http.ResponseWriter, r *http.Requ
 	}
 
 	employeeDetails.ClientID = uint(client_id)
-	err = ctrl.ClientService.CreateEmployee(employeeDetails)
+	err = ctrl.ClientService.WithActor(auth.ActorFromRequest(r)).CreateEmployee(employeeDetails)
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
 		return
@@ -113,7 +135,7 @@ func (ctrl *ClientController) UpdateEmployee(w http.ResponseWriter, r *http.Requ
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
 	}
-	err = ctrl.ClientService.Update(employeeDetails)
+	err = ctrl.ClientService.WithActor(auth.ActorFromRequest(r)).Update(employeeDetails)
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
 		return
@@ -141,7 +163,7 @@ func (ctrl *ClientController) DeleteEmployeeById(w http.ResponseWriter, r *http.
 	}
 	empId := uint(finalId)
 
-	err = ctrl.ClientService.DeleteEmployeeById(empId)
+	err = ctrl.ClientService.WithActor(auth.ActorFromRequest(r)).DeleteEmployeeById(empId)
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
 		return
@@ -168,10 +190,18 @@ func (ctrl *ClientController) GetAllEmployees(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = ctrl.ClientService.GetAllEmployeesByClientId(&employeeDetails, uint(client_id))
+	query, err := web.ParseListQuery(r, employeeListOptions)
 	if err != nil {
-		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = ctrl.ClientService.WithActor(auth.ActorFromRequest(r)).GetAllEmployeesByClientId(&employeeDetails, uint(client_id), query)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	web.SetListHeaders(w, r, query)
 	web.SendResponse(w,
 		web.WebResponse{
 			StatusCode: http.StatusOK,
@@ -182,22 +212,156 @@ func (ctrl *ClientController) GetAllEmployees(w http.ResponseWriter, r *http.Req
 
 }
 
+// ImportEmployees takes the file as a multipart "file" field or as the raw request body.
+// The format comes from ?format=, the file name or the Content-Type; ?dry_run=true only
+// validates and reports.
+func (ctrl *ClientController) ImportEmployees(w http.ResponseWriter, r *http.Request) {
+	id, ok := mux.Vars(r)["client_id"]
+	if !ok {
+		errorsUtils.SendErrorWithCustomMessage(w, "Client ID Not Found", http.StatusBadRequest)
+		return
+	}
+	client_id, err := strconv.Atoi(id)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Client ID should be a int", http.StatusBadRequest)
+		return
+	}
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			errorsUtils.SendErrorWithCustomMessage(w, "dry_run should be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, constants.EmployeeImportMaxBytes)
+	format := r.URL.Query().Get("format")
+	var data []byte
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			errorsUtils.SendErrorWithCustomMessage(w, "Multipart upload should have a file field", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		if format == "" {
+			format = spreadsheet.FormatFromFilename(header.Filename)
+		}
+		data, err = io.ReadAll(file)
+	} else {
+		if format == "" {
+			format = map[string]string{
+				spreadsheet.ContentTypeCSV:  spreadsheet.FormatCSV,
+				spreadsheet.ContentTypeXLSX: spreadsheet.FormatXLSX,
+			}[mediaType]
+		}
+		data, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "File could not be read, uploads are limited to 10MB", http.StatusBadRequest)
+		return
+	}
+	if format == "" {
+		errorsUtils.SendErrorWithCustomMessage(w, "File format could not be detected, use format=csv or format=xlsx", http.StatusBadRequest)
+		return
+	}
+
+	report, err := ctrl.ClientService.WithActor(auth.ActorFromRequest(r)).ImportEmployees(uint(client_id), strings.ToLower(format), data, dryRun)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response := web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Employees Imported Successfully",
+		Data:       report,
+	}
+	switch {
+	case report.Failed > 0:
+		response.StatusCode = http.StatusUnprocessableEntity
+		response.Message = "Import Has Row Errors, No Employees Were Saved"
+	case dryRun:
+		response.Message = "Dry Run Completed, No Employees Were Saved"
+	}
+	web.SendResponse(w, response)
+}
+
+func (ctrl *ClientController) ExportEmployees(w http.ResponseWriter, r *http.Request) {
+	id, ok := mux.Vars(r)["client_id"]
+	if !ok {
+		errorsUtils.SendErrorWithCustomMessage(w, "Client ID Not Found", http.StatusBadRequest)
+		return
+	}
+	client_id, err := strconv.Atoi(id)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Client ID should be a int", http.StatusBadRequest)
+		return
+	}
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = spreadsheet.FormatCSV
+	}
+	contentType := map[string]string{
+		spreadsheet.FormatCSV:  spreadsheet.ContentTypeCSV,
+		spreadsheet.FormatXLSX: spreadsheet.ContentTypeXLSX,
+	}[format]
+	if contentType == "" {
+		errorsUtils.SendErrorWithCustomMessage(w, "format should be csv or xlsx", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := ctrl.ClientService.WithActor(auth.ActorFromRequest(r)).ExportEmployees(uint(client_id))
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var file bytes.Buffer
+	err = spreadsheet.Write(format, &file, rows)
+	if err != nil {
+		ctrl.log.Error(err)
+		errorsUtils.SendErrorWithCustomMessage(w, "Export could not be generated", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="client-%d-employees.%s"`, client_id, format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(file.Bytes())
+}
+
 func (ctrl *ClientController) DisburseSalary(w http.ResponseWriter, r *http.Request) {
 	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
 	user_id := claims.UserId
 	client_id := claims.ClientId
 	if client_id == 0 || user_id == 0 {
 		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
+		return
 	}
 
-	err := ctrl.ClientService.DisburseSalaryAllEmployees(uint(client_id), uint(user_id))
+	// the body is optional, without it every employee is submitted all-or-nothing
+	request := salaryDisbursement.DisburseSalaryDTO{}
+	err := web.UnMarshalJSON(r, &request)
+	if err != nil && err != io.EOF {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(request)
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+
+	report, err := ctrl.ClientService.WithActor(auth.ActorFromRequest(r)).DisburseSalaryAllEmployees(uint(client_id), uint(user_id), request)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 	web.SendResponse(w,
 		web.WebResponse{
 			StatusCode: http.StatusCreated,
-			Message:    "Salary Disbursed Successfully",
+			Message:    "Salary Batch Submitted For Bank Approval",
+			Data:       report,
 		})
 
 }
@@ -209,7 +373,7 @@ func (ctrl *ClientController) GetSalaryReport(w http.ResponseWriter, r *http.Req
 		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
 	}
 	var salaryReport reports.SalaryReport
-	err := ctrl.ClientService.GetSalaryReport(uint(client_id), &salaryReport)
+	err := ctrl.ClientService.WithActor(auth.ActorFromRequest(r)).GetSalaryReport(uint(client_id), &salaryReport)
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
 		return
@@ -230,7 +394,7 @@ func (ctrl *ClientController) GetPaymentReport(w http.ResponseWriter, r *http.Re
 		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
 	}
 	var paymentReport reports.PaymentReport
-	err := ctrl.ClientService.GetPaymentReport(uint(client_id), &paymentReport)
+	err := ctrl.ClientService.WithActor(auth.ActorFromRequest(r)).GetPaymentReport(uint(client_id), &paymentReport)
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
 		return
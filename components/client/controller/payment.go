@@ -2,10 +2,13 @@ package controller
 
 import (
 	"bankManagement/components/client/service"
+	idempotencyService "bankManagement/components/idempotency/service"
 	"bankManagement/constants"
 	"bankManagement/middlewares/auth"
+	"bankManagement/middlewares/idempotency"
 	"bankManagement/models/beneficiary"
 	"bankManagement/models/payments"
+	"bankManagement/models/user"
 	"bankManagement/utils/encrypt"
 	errorsUtils "bankManagement/utils/errors"
 	"bankManagement/utils/log"
@@ -17,32 +20,47 @@ import (
 )
 
 type PaymentController struct {
-	PaymentService *service.PaymentService
-	log            log.WebLogger
+	PaymentService     *service.PaymentService
+	idempotencyService *idempotencyService.IdempotencyService
+	log                log.WebLogger
 }
 
 func NewPaymentController(
 	PaymentService *service.PaymentService,
+	idempotencyService *idempotencyService.IdempotencyService,
 	log log.WebLogger,
 ) *PaymentController {
 	return &PaymentController{
-		PaymentService: PaymentService,
-		log:            log,
+		PaymentService:     PaymentService,
+		idempotencyService: idempotencyService,
+		log:                log,
 	}
 }
 
+var beneficiaryListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "beneficiary_name": "beneficiary_name", "created_at": "created_at"},
+	FilterFields: map[string]string{"status": "status", "is_active": "is_active"},
+}
+
+var paymentRequestListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "amount": "amount", "created_at": "created_at"},
+	FilterFields: map[string]string{"status": "status", "receiver_client_id": "receiver_client_id", "currency": "currency"},
+	DefaultSort:  "-created_at",
+}
+
 func (ctrl *PaymentController) RegisterRoutes(
 	router *mux.Router) {
 	subRouter := router.PathPrefix("/clients/{client_id}/").Subrouter()
 	subRouter.Use(auth.AuthenticationMiddleware, auth.ValidateClientPermissionsMiddleware)
-	subRouter.HandleFunc("/beneficiaries", ctrl.CreateBeneficiary).Methods(http.MethodPost)
-	subRouter.HandleFunc("/beneficiaries", ctrl.GetAllBeneficiaries).Methods(http.MethodGet)
-	subRouter.HandleFunc("/beneficiaries/{beneficiary_id}", ctrl.Todo).Methods(http.MethodGet)
-	subRouter.HandleFunc("/beneficiaries/{beneficiary_id}", ctrl.Todo).Methods(http.MethodPut)
-	subRouter.HandleFunc("/beneficiaries/{beneficiary_id}", ctrl.DeleteBeneficiaryById).Methods(http.MethodDelete)
-	subRouter.HandleFunc("/make_payment", ctrl.CreatePaymentRequest).Methods(http.MethodPost)
-	subRouter.HandleFunc("/payments_requests", ctrl.GetAllPaymentRequestForClient).Methods(http.MethodGet)
-	subRouter.HandleFunc("/payments", ctrl.GetAllPaymentRequestForClient).Methods(http.MethodGet)
+	subRouter.Handle("/beneficiaries", auth.Require(ctrl.CreateBeneficiary, user.PermissionBeneficiaryWrite)).Methods(http.MethodPost)
+	subRouter.Handle("/beneficiaries", auth.Require(ctrl.GetAllBeneficiaries, user.PermissionBeneficiaryRead)).Methods(http.MethodGet)
+	subRouter.Handle("/beneficiaries/{beneficiary_id}", auth.Require(ctrl.Todo, user.PermissionBeneficiaryRead)).Methods(http.MethodGet)
+	subRouter.Handle("/beneficiaries/{beneficiary_id}", auth.Require(ctrl.Todo, user.PermissionBeneficiaryWrite)).Methods(http.MethodPut)
+	subRouter.Handle("/beneficiaries/{beneficiary_id}", auth.Require(ctrl.DeleteBeneficiaryById, user.PermissionBeneficiaryWrite)).Methods(http.MethodDelete)
+	subRouter.Handle("/make_payment", auth.Require(idempotency.Idempotent(ctrl.idempotencyService, ctrl.CreatePaymentRequest), user.PermissionPaymentCreate)).Methods(http.MethodPost)
+	subRouter.Handle("/payments_requests", auth.Require(ctrl.GetAllPaymentRequestForClient, user.PermissionPaymentRead)).Methods(http.MethodGet)
+	subRouter.Handle("/payments_requests/{payment_request_id}/cancel", auth.Require(ctrl.CancelPaymentRequest, user.PermissionPaymentCancel)).Methods(http.MethodPost)
+	subRouter.Handle("/payments", auth.Require(ctrl.GetAllPaymentRequestForClient, user.PermissionPaymentRead)).Methods(http.MethodGet)
 
 }
 
@@ -74,7 +92,7 @@ func (ctrl *PaymentController) CreateBeneficiary(w http.ResponseWriter, r *http.
 	tempBeneficiary.CreatedByUserID = user_id
 	tempBeneficiary.ApprovedByUserID = user_id
 
-	err = ctrl.PaymentService.CreateBeneficiary(&tempBeneficiary)
+	err = ctrl.PaymentService.WithActor(auth.ActorFromRequest(r)).CreateBeneficiary(&tempBeneficiary)
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
 		return
@@ -94,12 +112,18 @@ func (ctrl *PaymentController) GetAllBeneficiaries(w http.ResponseWriter, r *htt
 		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
 		return
 	}
+	query, err := web.ParseListQuery(r, beneficiaryListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	var allBeneficiaries []beneficiary.Beneficiary
-	err := ctrl.PaymentService.GetAllBeneficiariesForClient(client_id, &allBeneficiaries)
+	err = ctrl.PaymentService.WithActor(auth.ActorFromRequest(r)).GetAllBeneficiariesForClient(client_id, query, &allBeneficiaries)
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	web.SetListHeaders(w, r, query)
 	web.SendResponse(w, web.WebResponse{
 		StatusCode: http.StatusOK,
 		Message:    "Beneficiary Successfully  Retrieved",
@@ -128,7 +152,7 @@ func (ctrl *PaymentController) DeleteBeneficiaryById(w http.ResponseWriter, r *h
 		return
 	}
 
-	err = ctrl.PaymentService.DeleteBeneficiaryById(client_id, uint(beneficiary_id))
+	err = ctrl.PaymentService.WithActor(auth.ActorFromRequest(r)).DeleteBeneficiaryById(client_id, uint(beneficiary_id))
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
 		return
@@ -161,7 +185,7 @@ func (ctrl *PaymentController) CreatePaymentRequest(w http.ResponseWriter, r *ht
 		return
 	}
 
-	err = ctrl.PaymentService.CreatePaymentRequest(client_id, user_id, &paymentRequestDTO)
+	err = ctrl.PaymentService.WithActor(auth.ActorFromRequest(r)).CreatePaymentRequest(client_id, user_id, &paymentRequestDTO)
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
 		return
@@ -181,12 +205,18 @@ func (ctrl *PaymentController) GetAllPaymentRequestForClient(w http.ResponseWrit
 		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
 		return
 	}
+	query, err := web.ParseListQuery(r, paymentRequestListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	var allPaymentRequest []payments.PaymentRequest
-	err := ctrl.PaymentService.GetAllPaymentRequest(client_id, &allPaymentRequest)
+	err = ctrl.PaymentService.WithActor(auth.ActorFromRequest(r)).GetAllPaymentRequest(client_id, query, &allPaymentRequest)
 	if err != nil {
 		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	web.SetListHeaders(w, r, query)
 
 	web.SendResponse(w, web.WebResponse{
 		StatusCode: http.StatusCreated,
@@ -194,3 +224,27 @@ func (ctrl *PaymentController) GetAllPaymentRequestForClient(w http.ResponseWrit
 		Data:       allPaymentRequest,
 	})
 }
+
+func (ctrl *PaymentController) CancelPaymentRequest(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	if claims.ClientId == 0 || claims.UserId == 0 {
+		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
+		return
+	}
+	paymentRequestId, err := strconv.Atoi(mux.Vars(r)["payment_request_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Payment Request ID should be a int", http.StatusBadRequest)
+		return
+	}
+	var paymentRequest payments.PaymentRequest
+	err = ctrl.PaymentService.WithActor(auth.ActorFromRequest(r)).CancelPaymentRequest(claims.ClientId, uint(paymentRequestId), claims.UserId, &paymentRequest)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Payment Request Cancelled",
+		Data:       paymentRequest,
+	})
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	ledgerService "bankManagement/components/ledger/service"
+	"bankManagement/constants"
+	"bankManagement/models/audit"
 	"bankManagement/models/client"
 	"bankManagement/models/employee"
 	"bankManagement/models/payments"
@@ -9,7 +12,10 @@ import (
 	"bankManagement/models/transaction"
 	"bankManagement/repository"
 	"bankManagement/utils/log"
+	"bankManagement/utils/money"
+	"bankManagement/utils/web"
 	"errors"
+	"fmt"
 
 	"github.com/jinzhu/gorm"
 )
@@ -18,20 +24,32 @@ type ClientService struct {
 	DB         *gorm.DB
 	repository repository.Repository
 	log        log.WebLogger
+	ledger     *ledgerService.LedgerService
 }
 
 func NewClientService(
 	DB *gorm.DB,
 	repository repository.Repository,
 	log log.WebLogger,
+	ledger *ledgerService.LedgerService,
 ) *ClientService {
 	return &ClientService{
 		DB,
 		repository,
 		log,
+		ledger,
 	}
 }
 
+// WithActor returns a copy of the service whose changes the audit log attributes to actor
+// and whose log lines carry the actor's request ID.
+func (service *ClientService) WithActor(actor *audit.Actor) *ClientService {
+	scoped := *service
+	scoped.DB = repository.BindActor(service.DB, actor)
+	scoped.log = service.log.WithFields(log.Fields{constants.RequestIDKey: actor.RequestId})
+	return &scoped
+}
+
 func (service *ClientService) CreateEmployee(emp *employee.Employee) error {
 	uow := repository.NewUnitOfWork(service.DB)
 	defer uow.RollBack()
@@ -40,6 +58,12 @@ func (service *ClientService) CreateEmployee(emp *employee.Employee) error {
 	if err != nil || tempClient.ID == 0 {
 		return errors.New("Client Not found")
 	}
+	// salaries are paid in the client's currency
+	emp.SalaryAmount, err = emp.SalaryAmount.InCurrency(tempClient.Currency)
+	if err != nil {
+		return err
+	}
+	emp.TotalSalaryReceived = money.Zero(tempClient.Currency)
 	err = service.repository.Add(uow, emp)
 	if err != nil {
 		return err
@@ -57,6 +81,14 @@ func (service *ClientService) Update(emp *employee.Employee) error {
 	if err != nil || tempEmployee.ID == 0 {
 		return errors.New("Employee Not found")
 	}
+	emp.SalaryAmount, err = emp.SalaryAmount.InCurrency(tempEmployee.Currency)
+	if err != nil {
+		return err
+	}
+	emp.TotalSalaryReceived, err = emp.TotalSalaryReceived.InCurrency(tempEmployee.Currency)
+	if err != nil {
+		return err
+	}
 	err = service.repository.Update(uow, emp)
 	if err != nil {
 		return err
@@ -66,12 +98,11 @@ func (service *ClientService) Update(emp *employee.Employee) error {
 
 }
 
-func (service *ClientService) GetAllEmployeesByClientId(emp *[]employee.Employee, clientId uint) error {
+func (service *ClientService) GetAllEmployeesByClientId(emp *[]employee.Employee, clientId uint, query *web.ListQuery) error {
 	uow := repository.NewUnitOfWork(service.DB)
 	defer uow.RollBack()
-	err := service.repository.GetAll(uow, emp,
-		service.repository.Filter("client_id=?", clientId),
-	)
+	queryProcessors := []repository.QueryProcessor{service.repository.Filter("client_id=?", clientId)}
+	err := service.repository.GetAll(uow, emp, append(queryProcessors, query.QueryProcessors(service.repository)...)...)
 	if err != nil {
 		return err
 	}
@@ -99,134 +130,116 @@ func (service *ClientService) DeleteEmployeeById(clientId uint) error {
 	return nil
 }
 
-func (service *ClientService) DisburseSalaryToOneEmployee(clientId uint, empId uint, approvedByUserId uint) error {
+// DisburseSalaryAllEmployees submits the salaries of the client's employees as a pending batch
+// paid with the requested strategy. No money moves until a bank user approves it under
+// /banks/{bank_id}/salary_batches.
+func (service *ClientService) DisburseSalaryAllEmployees(clientId uint, createdByUserId uint, request salaryDisbursement.DisburseSalaryDTO) (*salaryDisbursement.SalaryBatchReport, error) {
 	uow := repository.NewUnitOfWork(service.DB)
 	defer uow.RollBack()
-	tempDisbursement := &salaryDisbursement.SalaryDisbursement{
-		ClientID:        clientId,
-		EmpID:           empId,
-		CreatedByUserId: approvedByUserId,
-	}
-	tempClient := &client.Client{}
-	err := service.repository.GetByID(uow, tempClient, clientId)
-	if err != nil {
-		return err
-	}
-
-	tempEmployeeDetails := &employee.Employee{}
-	err = service.repository.GetByID(uow, tempEmployeeDetails, empId)
-	if err != nil {
-		return err
-	}
-	if tempClient.Balance < tempEmployeeDetails.SalaryAmount {
-		return errors.New("Insufficient Balance")
-	}
-	tempClient.Balance = tempClient.Balance - tempEmployeeDetails.SalaryAmount
-	service.repository.Update(uow, tempClient)
-
-	tempDisbursement.SalaryAmount = tempEmployeeDetails.SalaryAmount
-	//Can use api to disburse salary to account number. In that case status will be pending. and can be updated using webhooks
-	tempTransaction := &transaction.Transaction{
-		ClientID:          clientId,
-		PaymentType:       "Transfer",
-		TransactionType:   transaction.TransactionDebit,
-		TransactionAmount: tempEmployeeDetails.SalaryAmount,
-		TransactionStatus: transaction.TransactionStatusApproved,
-	}
-	err = service.repository.Add(uow, tempTransaction)
-
+	report, err := service.SubmitSalaryBatch(uow, clientId, createdByUserId, 0, request)
 	if err != nil {
-		return err
-	}
-	tempEmployeeDetails.TotalSalaryReceived += tempTransaction.TransactionAmount
-	err = service.repository.Update(uow, tempEmployeeDetails)
-
-	if err != nil {
-		return err
-	}
-	tempDisbursement.TransactionID = tempTransaction.ID
-	tempDisbursement.Status = salaryDisbursement.DisbursementStatusApproved
-
-	err = service.repository.Add(uow, tempDisbursement)
-
-	if err != nil {
-		return err
+		return nil, err
 	}
 	uow.Commit()
-	return nil
+	return report, nil
 }
 
-func (service *ClientService) DisburseSalaryAllEmployees(clientId uint, approvedByUserId uint) error {
-	uow := repository.NewUnitOfWork(service.DB)
-	defer uow.RollBack()
+// SubmitSalaryBatch creates, inside the caller's unit of work, a pending batch with one pending
+// line per employee to pay (the selected ones for the Subset strategy). payrollRunId links the
+// lines to a scheduled payroll run, 0 when submitted manually.
+func (service *ClientService) SubmitSalaryBatch(uow *repository.UOW, clientId uint, createdByUserId uint, payrollRunId uint, request salaryDisbursement.DisburseSalaryDTO) (*salaryDisbursement.SalaryBatchReport, error) {
+	if request.Strategy == "" {
+		request.Strategy = salaryDisbursement.StrategyAllOrNothing
+	}
 	var allEmployees []employee.Employee
 	err := service.repository.GetAll(uow, &allEmployees,
 		service.repository.Filter("client_id=?", clientId),
 	)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(allEmployees) == 0 {
+		return nil, errors.New("client has no employees to pay")
+	}
+
+	selected := make(map[uint]bool)
+	if request.Strategy == salaryDisbursement.StrategySubset {
+		if len(request.EmployeeIDs) == 0 {
+			return nil, errors.New("employee_ids are required for the Subset strategy")
+		}
+		known := make(map[uint]bool)
+		for _, emp := range allEmployees {
+			known[emp.ID] = true
+		}
+		for _, empId := range request.EmployeeIDs {
+			if !known[empId] {
+				return nil, fmt.Errorf("employee ID %d does not belong to this client", empId)
+			}
+			selected[empId] = true
+		}
 	}
 
 	tempClient := &client.Client{}
 	err = service.repository.GetByID(uow, tempClient, clientId)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := tempClient.CheckVerified(); err != nil {
+		return nil, err
 	}
-	var amountToBeDisbursed float64 = 0
+	batch := &salaryDisbursement.SalaryBatch{
+		ClientID:        clientId,
+		BankID:          tempClient.BankID,
+		Status:          salaryDisbursement.DisbursementStatusPending,
+		Strategy:        request.Strategy,
+		TotalAmount:     money.Zero(tempClient.Currency),
+		CreatedByUserId: createdByUserId,
+	}
+	report := &salaryDisbursement.SalaryBatchReport{Batch: batch}
+	var toPay []employee.Employee
 	for _, emp := range allEmployees {
-		amountToBeDisbursed += emp.SalaryAmount
+		if request.Strategy == salaryDisbursement.StrategySubset && !selected[emp.ID] {
+			report.Results = append(report.Results, salaryDisbursement.DisbursementResultDTO{
+				EmployeeID:   emp.ID,
+				EmployeeName: emp.EmployeeName,
+				Amount:       emp.SalaryAmount,
+				Result:       salaryDisbursement.ResultSkipped,
+				Reason:       "not selected",
+			})
+			continue
+		}
+		toPay = append(toPay, emp)
+		batch.TotalAmount = batch.TotalAmount.Add(emp.SalaryAmount)
 	}
-
-	// tempEmployeeDetails := &employee.Employee{}
-	// err = service.repository.GetByID(uow, tempEmployeeDetails, empId)
-	// if err != nil {
-	// 	return err
-	// }
-	if tempClient.Balance < amountToBeDisbursed {
-		return errors.New("Insufficient Balance")
+	err = service.repository.Add(uow, batch)
+	if err != nil {
+		return nil, err
 	}
-	tempClient.Balance = tempClient.Balance - amountToBeDisbursed
-	service.repository.Update(uow, tempClient)
 
-	for _, emp := range allEmployees {
-		tempDisbursement := &salaryDisbursement.SalaryDisbursement{
+	for _, emp := range toPay {
+		line := salaryDisbursement.SalaryDisbursement{
 			ClientID:        clientId,
 			EmpID:           emp.ID,
-			CreatedByUserId: approvedByUserId,
-		}
-		tempDisbursement.SalaryAmount = emp.SalaryAmount
-		// //Can use api to disburse salary to account number. In that case status will be pending. and can be updated using webhooks
-		tempTransaction := &transaction.Transaction{
-			ClientID:          clientId,
-			PaymentType:       "Transfer",
-			TransactionType:   transaction.TransactionDebit,
-			TransactionAmount: emp.SalaryAmount,
-			TransactionStatus: transaction.TransactionStatusApproved,
-		}
-		err = service.repository.Add(uow, tempTransaction)
-
-		if err != nil {
-			return err
+			SalaryAmount:    emp.SalaryAmount,
+			Status:          salaryDisbursement.DisbursementStatusPending,
+			CreatedByUserId: createdByUserId,
+			PayrollRunID:    payrollRunId,
+			BatchID:         batch.ID,
 		}
-		emp.TotalSalaryReceived += tempTransaction.TransactionAmount
-		err = service.repository.Update(uow, emp)
-
+		err = service.repository.Add(uow, &line)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		tempDisbursement.TransactionID = tempTransaction.ID
-		tempDisbursement.Status = salaryDisbursement.DisbursementStatusApproved
-
-		err = service.repository.Add(uow, tempDisbursement)
-
-		if err != nil {
-			return err
-		}
-
+		batch.Lines = append(batch.Lines, line)
+		report.Results = append(report.Results, salaryDisbursement.DisbursementResultDTO{
+			EmployeeID:   emp.ID,
+			EmployeeName: emp.EmployeeName,
+			Amount:       emp.SalaryAmount,
+			Result:       salaryDisbursement.ResultPending,
+			Reason:       "awaiting bank approval",
+		})
 	}
-
-	uow.Commit()
-	return nil
+	return report, nil
 }
 
 func (service *ClientService) GetSalaryReport(clientId uint, report *reports.SalaryReport) error {
@@ -247,15 +260,12 @@ func (service *ClientService) GetSalaryReport(clientId uint, report *reports.Sal
 	if err != nil {
 		return err
 	}
-	var amountToBeDisbursed float64 = 0
-	for _, emp := range allEmployees {
-		amountToBeDisbursed += emp.SalaryAmount
-	}
+	currency := tempClient.Balance.Currency
 
 	SalaryReport := &reports.SalaryReport{
-		ExpectedMonthlySalaryDisbursal: 0,
-		TotalSalaryDisbursed:           0,
-		AverageSalary:                  0,
+		ExpectedMonthlySalaryDisbursal: money.Zero(currency),
+		TotalSalaryDisbursed:           money.Zero(currency),
+		AverageSalary:                  money.Zero(currency),
 		TotalEmployees:                 employeeCount,
 		ClientID:                       clientId,
 		ClientName:                     tempClient.ClientName,
@@ -276,11 +286,11 @@ func (service *ClientService) GetSalaryReport(clientId uint, report *reports.Sal
 			SalaryDisbursed: emp.TotalSalaryReceived,
 			MonthlySalary:   emp.SalaryAmount,
 		})
-		SalaryReport.TotalSalaryDisbursed += emp.TotalSalaryReceived
-		SalaryReport.ExpectedMonthlySalaryDisbursal += emp.SalaryAmount
+		SalaryReport.TotalSalaryDisbursed = SalaryReport.TotalSalaryDisbursed.Add(emp.TotalSalaryReceived)
+		SalaryReport.ExpectedMonthlySalaryDisbursal = SalaryReport.ExpectedMonthlySalaryDisbursal.Add(emp.SalaryAmount)
 	}
 	if len(allEmployees) > 0 {
-		SalaryReport.AverageSalary = SalaryReport.ExpectedMonthlySalaryDisbursal / float64(SalaryReport.TotalEmployees)
+		SalaryReport.AverageSalary = SalaryReport.ExpectedMonthlySalaryDisbursal.Div(int64(len(allEmployees)))
 	}
 	SalaryReport.EmployeeDisbursementData = empDisbursement
 	*report = *SalaryReport
@@ -308,12 +318,14 @@ func (service *ClientService) GetPaymentReport(clientId uint, report *reports.Pa
 		return err
 	}
 
+	currency := tempClient.Balance.Currency
+
 	PaymentReport := &reports.PaymentReport{
-		AveragePaymentValue:       0,
+		AveragePaymentValue:       money.Zero(currency),
 		TotalPaymentsSent:         0,
 		TotalPaymentsReceived:     0,
-		TotalPaymentReceivedValue: 0,
-		TotalPaymentSentValue:     0,
+		TotalPaymentReceivedValue: money.Zero(currency),
+		TotalPaymentSentValue:     money.Zero(currency),
 		TotalPaymentRequests:      0,
 		ApprovedPaymentRequests:   0,
 		RejectedPaymentRequests:   0,
@@ -331,13 +343,13 @@ func (service *ClientService) GetPaymentReport(clientId uint, report *reports.Pa
 			if !ok {
 				paymentCreated[req.ReceiverClientID] = reports.ClientPaymentDTO{
 					ClientID:                         req.ReceiverClientID,
-					TotalPaymentSentByThisClient:     0,
-					TotalPaymentReceivedByThisClient: 0,
+					TotalPaymentSentByThisClient:     money.Zero(currency),
+					TotalPaymentReceivedByThisClient: money.Zero(currency),
 				}
 				receiver = paymentCreated[req.ReceiverClientID]
 			}
-			receiver.TotalPaymentReceivedByThisClient += req.PaymentAmount
-			PaymentReport.TotalPaymentSentValue += req.PaymentAmount
+			receiver.TotalPaymentReceivedByThisClient = receiver.TotalPaymentReceivedByThisClient.Add(req.PaymentAmount)
+			PaymentReport.TotalPaymentSentValue = PaymentReport.TotalPaymentSentValue.Add(req.PaymentAmount)
 			PaymentReport.TotalPaymentsSent += 1
 			paymentCreated[req.ReceiverClientID] = receiver
 
@@ -347,13 +359,14 @@ func (service *ClientService) GetPaymentReport(clientId uint, report *reports.Pa
 			if !ok {
 				paymentCreated[req.SenderClientID] = reports.ClientPaymentDTO{
 					ClientID:                         req.SenderClientID,
-					TotalPaymentSentByThisClient:     0,
-					TotalPaymentReceivedByThisClient: 0,
+					TotalPaymentSentByThisClient:     money.Zero(currency),
+					TotalPaymentReceivedByThisClient: money.Zero(currency),
 				}
 				sender = paymentCreated[req.SenderClientID]
 			}
-			sender.TotalPaymentSentByThisClient += req.PaymentAmount
-			PaymentReport.TotalPaymentReceivedValue += req.PaymentAmount
+			// counted as received, converted into this client's currency
+			sender.TotalPaymentSentByThisClient = sender.TotalPaymentSentByThisClient.Add(req.ReceivedAmount)
+			PaymentReport.TotalPaymentReceivedValue = PaymentReport.TotalPaymentReceivedValue.Add(req.ReceivedAmount)
 			PaymentReport.TotalPaymentsReceived += 1
 			paymentCreated[req.SenderClientID] = sender
 		}
@@ -370,7 +383,7 @@ func (service *ClientService) GetPaymentReport(clientId uint, report *reports.Pa
 	}
 	if PaymentReport.TotalPaymentsSent != 0 {
 
-		PaymentReport.AveragePaymentValue = PaymentReport.TotalPaymentSentValue / float64(PaymentReport.TotalPaymentsSent)
+		PaymentReport.AveragePaymentValue = PaymentReport.TotalPaymentSentValue.Div(int64(PaymentReport.TotalPaymentsSent))
 	}
 	PaymentReport.ClientPaymentData = paymentCreated
 	*report = *PaymentReport
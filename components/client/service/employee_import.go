@@ -0,0 +1,227 @@
+package service
+
+import (
+	"bankManagement/constants"
+	"bankManagement/models/client"
+	"bankManagement/models/employee"
+	"bankManagement/repository"
+	"bankManagement/utils/money"
+	"bankManagement/utils/spreadsheet"
+	"bankManagement/utils/web"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ImportEmployees upserts the client's employees from a CSV or XLSX file, matching on
+// AccountNo, in a single unit of work. Every row is validated and reported; nothing is
+// saved when a row fails or when dryRun is set.
+func (service *ClientService) ImportEmployees(clientId uint, format string, data []byte, dryRun bool) (*employee.ImportReport, error) {
+	rows, err := spreadsheet.Read(format, data)
+	if err != nil {
+		return nil, err
+	}
+	// trailing blank lines are common in spreadsheets
+	for len(rows) > 0 && isBlankRow(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("file is empty, expected a header row")
+	}
+	columns, err := importColumns(rows[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(rows)-1 > constants.EmployeeImportMaxRows {
+		return nil, fmt.Errorf("file has %d rows, at most %d can be imported at once", len(rows)-1, constants.EmployeeImportMaxRows)
+	}
+
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	tempClient := &client.Client{}
+	err = service.repository.GetByID(uow, tempClient, clientId)
+	if err != nil || tempClient.ID == 0 {
+		return nil, errors.New("Client Not found")
+	}
+
+	report := &employee.ImportReport{DryRun: dryRun, Rows: []employee.ImportRowResult{}}
+	seen := make(map[string]int)
+	for i, row := range rows[1:] {
+		if isBlankRow(row) {
+			continue
+		}
+		report.TotalRows++
+		result := employee.ImportRowResult{Row: i + 2}
+		emp, rowErrors := parseEmployeeRow(row, columns, tempClient.Currency)
+		result.AccountNo = emp.AccountNo
+		if firstRow, ok := seen[emp.AccountNo]; ok && emp.AccountNo != "" {
+			rowErrors = append(rowErrors, fmt.Sprintf("account_no is duplicated, first seen on row %d", firstRow))
+		} else {
+			seen[emp.AccountNo] = result.Row
+		}
+		if len(rowErrors) == 0 {
+			result.Action, err = service.upsertEmployee(uow, clientId, emp, columns)
+			if err != nil {
+				if !errors.Is(err, errEmployeeOfOtherClient) && !errors.Is(err, errDeletedEmployee) {
+					return nil, err
+				}
+				rowErrors = append(rowErrors, err.Error())
+			}
+		}
+
+		result.Errors = rowErrors
+		switch {
+		case len(rowErrors) > 0:
+			report.Failed++
+		case result.Action == employee.ImportActionCreate:
+			report.Created++
+		default:
+			report.Updated++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	if dryRun || report.Failed > 0 {
+		return report, nil
+	}
+	uow.Commit()
+	report.Applied = true
+	return report, nil
+}
+
+// ExportEmployees returns the client's roster as rows, the header first, in the import
+// column layout so the file can be edited and imported back. Text that would run as a
+// formula in a spreadsheet application is escaped.
+func (service *ClientService) ExportEmployees(clientId uint) ([][]string, error) {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	employees := []employee.Employee{}
+	err := service.repository.GetAll(uow, &employees,
+		service.repository.Filter("client_id = ?", clientId),
+	)
+	if err != nil {
+		return nil, err
+	}
+	uow.Commit()
+
+	rows := [][]string{employee.ExportColumns}
+	for _, emp := range employees {
+		rows = append(rows, []string{
+			spreadsheet.EscapeText(emp.EmployeeName),
+			emp.SalaryAmount.Decimal(),
+			spreadsheet.EscapeText(emp.AccountNo),
+			strconv.Itoa(emp.Priority),
+			spreadsheet.EscapeText(emp.Currency),
+			emp.TotalSalaryReceived.Decimal(),
+		})
+	}
+	return rows, nil
+}
+
+var errEmployeeOfOtherClient = errors.New("account_no belongs to an employee of another client")
+var errDeletedEmployee = errors.New("account_no belongs to a deleted employee")
+
+// upsertEmployee creates the employee or updates the one holding the same AccountNo, only
+// overwriting the optional fields whose column the file has. A deleted employee keeps its
+// AccountNo, the row is reported rather than imported.
+func (service *ClientService) upsertEmployee(uow *repository.UOW, clientId uint, emp *employee.Employee, columns map[string]int) (string, error) {
+	var matches []employee.Employee
+	err := service.repository.GetAll(uow, &matches,
+		service.repository.Filter("account_no = ?", emp.AccountNo),
+		service.repository.Unscoped(),
+	)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) > 0 {
+		existing := &matches[0]
+		if existing.ClientID != clientId {
+			return "", errEmployeeOfOtherClient
+		}
+		if existing.DeletedAt != nil {
+			return "", errDeletedEmployee
+		}
+		existing.EmployeeName = emp.EmployeeName
+		existing.SalaryAmount = emp.SalaryAmount
+		if _, ok := columns[employee.ColumnPriority]; ok {
+			existing.Priority = emp.Priority
+		}
+		if err := service.repository.Update(uow, existing); err != nil {
+			return "", err
+		}
+		return employee.ImportActionUpdate, nil
+	}
+	emp.ClientID = clientId
+	emp.TotalSalaryReceived = money.Zero(emp.SalaryAmount.CurrencyCode())
+	if err := service.repository.Add(uow, emp); err != nil {
+		return "", err
+	}
+	return employee.ImportActionCreate, nil
+}
+
+// importColumns maps each known header to its column index.
+func importColumns(header []string) (map[string]int, error) {
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; ok && name != "" {
+			return nil, fmt.Errorf("column %q appears more than once", name)
+		}
+		columns[name] = i
+	}
+	var missing []string
+	for _, name := range employee.ImportRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required columns: %s", strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+// parseEmployeeRow builds an employee from a data row and validates it with the model's
+// validator tags; the salary is bound to the client's currency.
+func parseEmployeeRow(row []string, columns map[string]int, currency string) (*employee.Employee, []string) {
+	cell := func(name string) string {
+		index, ok := columns[name]
+		if !ok || index >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[index])
+	}
+	var rowErrors []string
+	emp := &employee.Employee{
+		EmployeeName: spreadsheet.UnescapeText(cell(employee.ColumnName)),
+		AccountNo:    spreadsheet.UnescapeText(cell(employee.ColumnAccountNo)),
+	}
+	if value := cell(employee.ColumnSalaryAmount); value != "" {
+		salary, err := money.Parse(value, currency)
+		if err != nil {
+			rowErrors = append(rowErrors, "salary_amount: "+err.Error())
+		}
+		emp.SalaryAmount = salary
+	}
+	if value := cell(employee.ColumnPriority); value != "" {
+		priority, err := strconv.Atoi(value)
+		if err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("priority: %q is not a whole number", value))
+		}
+		emp.Priority = priority
+	}
+	if err := web.GetValidator().Struct(emp); err != nil {
+		rowErrors = append(rowErrors, web.GetValidationErrors(err)...)
+	}
+	return emp, rowErrors
+}
+
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
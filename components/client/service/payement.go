@@ -1,12 +1,19 @@
 package service
 
 import (
+	ledgerService "bankManagement/components/ledger/service"
+	"bankManagement/constants"
+	"bankManagement/models/audit"
 	"bankManagement/models/beneficiary"
 	"bankManagement/models/client"
 	"bankManagement/models/payments"
+	"bankManagement/models/transaction"
 	"bankManagement/repository"
 	"bankManagement/utils/log"
+	"bankManagement/utils/web"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
@@ -15,20 +22,32 @@ type PaymentService struct {
 	DB         *gorm.DB
 	repository repository.Repository
 	log        log.WebLogger
+	ledger     *ledgerService.LedgerService
 }
 
 func NewPaymentService(
 	DB *gorm.DB,
 	repository repository.Repository,
 	log log.WebLogger,
+	ledger *ledgerService.LedgerService,
 ) *PaymentService {
 	return &PaymentService{
 		DB,
 		repository,
 		log,
+		ledger,
 	}
 }
 
+// WithActor returns a copy of the service whose changes the audit log attributes to actor
+// and whose log lines carry the actor's request ID.
+func (srv *PaymentService) WithActor(actor *audit.Actor) *PaymentService {
+	scoped := *srv
+	scoped.DB = repository.BindActor(srv.DB, actor)
+	scoped.log = srv.log.WithFields(log.Fields{constants.RequestIDKey: actor.RequestId})
+	return &scoped
+}
+
 func (srv *PaymentService) CreateBeneficiary(bfciary *beneficiary.Beneficiary) error {
 	uow := repository.NewUnitOfWork(srv.DB)
 	defer uow.RollBack()
@@ -65,7 +84,7 @@ func (srv *PaymentService) CreateBeneficiary(bfciary *beneficiary.Beneficiary) e
 
 // }
 
-func (srv *PaymentService) GetAllBeneficiariesForClient(clientId uint, beneficiaries *[]beneficiary.Beneficiary) error {
+func (srv *PaymentService) GetAllBeneficiariesForClient(clientId uint, query *web.ListQuery, beneficiaries *[]beneficiary.Beneficiary) error {
 	uow := repository.NewUnitOfWork(srv.DB)
 	defer uow.RollBack()
 	tempSender := client.Client{}
@@ -73,9 +92,8 @@ func (srv *PaymentService) GetAllBeneficiariesForClient(clientId uint, beneficia
 	if err != nil {
 		return err
 	}
-	err = srv.repository.GetAll(uow, beneficiaries,
-		srv.repository.Filter("client_id=?", clientId),
-	)
+	queryProcessors := []repository.QueryProcessor{srv.repository.Filter("client_id=?", clientId)}
+	err = srv.repository.GetAll(uow, beneficiaries, append(queryProcessors, query.QueryProcessors(srv.repository)...)...)
 	if err != nil {
 		return err
 	}
@@ -125,8 +143,21 @@ func (srv *PaymentService) CreatePaymentRequest(clientId uint, createdByUserId u
 	if err != nil {
 		return err
 	}
+	if err := tempSender.CheckVerified(); err != nil {
+		return err
+	}
 
-	if tempSender.Balance < paymentRequest.PaymentAmount {
+	// the amount is always in the sender's currency, conversion happens at approval
+	tempPaymentRequest.PaymentAmount, err = paymentRequest.PaymentAmount.InCurrency(tempSender.Currency)
+	if err != nil {
+		return err
+	}
+
+	balance, err := srv.ledger.GetClientBalance(uow, clientId)
+	if err != nil {
+		return err
+	}
+	if balance.LessThan(tempPaymentRequest.PaymentAmount) {
 		return errors.New("Insufficient Balance to Transact")
 	}
 
@@ -153,7 +184,33 @@ func (srv *PaymentService) CreatePaymentRequest(clientId uint, createdByUserId u
 
 }
 
-func (srv *PaymentService) GetAllPaymentRequest(clientId uint, requests *[]payments.PaymentRequest) error {
+// CancelPaymentRequest withdraws a payment request of the client that no bank user has
+// reviewed yet.
+func (srv *PaymentService) CancelPaymentRequest(clientId uint, paymentRequestId uint, cancelledByUserId uint, paymentRequest *payments.PaymentRequest) error {
+	uow := repository.NewUnitOfWork(srv.DB)
+	defer uow.RollBack()
+	//Locked so that it cannot be approved meanwhile
+	err := srv.repository.GetByIDForUpdate(uow, paymentRequest, paymentRequestId)
+	if err != nil || paymentRequest.SenderClientID != clientId {
+		return errors.New("Payment Request Not found")
+	}
+	if paymentRequest.Status != transaction.TransactionStatusPending {
+		return fmt.Errorf("Payment Request is already %s", paymentRequest.Status)
+	}
+	cancelledAt := time.Now()
+	paymentRequest.Status = transaction.TransactionStatusCancelled
+	paymentRequest.Resolved = true
+	paymentRequest.ResolvedByUserId = cancelledByUserId
+	paymentRequest.ResolvedAt = &cancelledAt
+	err = srv.repository.Update(uow, paymentRequest)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+func (srv *PaymentService) GetAllPaymentRequest(clientId uint, query *web.ListQuery, requests *[]payments.PaymentRequest) error {
 	uow := repository.NewUnitOfWork(srv.DB)
 	defer uow.RollBack()
 
@@ -163,10 +220,11 @@ func (srv *PaymentService) GetAllPaymentRequest(clientId uint, requests *[]payme
 		return err
 	}
 
-	err = srv.repository.GetAll(uow, requests,
+	queryProcessors := []repository.QueryProcessor{
 		srv.repository.Preload("ReceiverClient"),
 		srv.repository.Filter("sender_client_id=?", clientId),
-	)
+	}
+	err = srv.repository.GetAll(uow, requests, append(queryProcessors, query.QueryProcessors(srv.repository)...)...)
 
 	if err != nil {
 		return err
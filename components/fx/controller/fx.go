@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"bankManagement/components/fx/service"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/fx"
+	"bankManagement/models/user"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type FxController struct {
+	FxService *service.FxService
+	log       log.WebLogger
+}
+
+func NewFxController(
+	FxService *service.FxService,
+	log log.WebLogger,
+) *FxController {
+	return &FxController{
+		FxService: FxService,
+		log:       log,
+	}
+}
+
+var rateListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "from_currency": "from_currency", "to_currency": "to_currency", "updated_at": "updated_at"},
+	FilterFields: map[string]string{"from_currency": "from_currency", "to_currency": "to_currency"},
+}
+
+func (ctrl *FxController) RegisterRoutes(
+	router *mux.Router) {
+	subRouter := router.PathPrefix("/fx_rates").Subrouter()
+	subRouter.Use(auth.AuthenticationMiddleware)
+	subRouter.Handle("/", auth.Require(ctrl.GetAllRates, user.PermissionFxRead)).Methods(http.MethodGet)
+	subRouter.Handle("/", auth.Require(ctrl.SetRate, user.PermissionFxWrite)).Methods(http.MethodPost)
+}
+
+func (ctrl *FxController) GetAllRates(w http.ResponseWriter, r *http.Request) {
+	query, err := web.ParseListQuery(r, rateListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var rates []fx.ExchangeRate
+	err = ctrl.FxService.WithActor(auth.ActorFromRequest(r)).GetAllRates(query, &rates)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	web.SetListHeaders(w, r, query)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Exchange Rates Retrieved",
+		Data:       rates,
+	})
+}
+
+func (ctrl *FxController) SetRate(w http.ResponseWriter, r *http.Request) {
+	rate := fx.ExchangeRate{}
+	err := web.UnMarshalJSON(r, &rate)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(rate)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	err = ctrl.FxService.WithActor(auth.ActorFromRequest(r)).SetRate(&rate)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusCreated,
+		Message:    "Exchange Rate Saved",
+		Data:       rate,
+	})
+}
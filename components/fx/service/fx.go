@@ -0,0 +1,193 @@
+package service
+
+import (
+	"bankManagement/constants"
+	"bankManagement/models/audit"
+	"bankManagement/models/fx"
+	"bankManagement/repository"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RateProvider quotes how many units of `to` one unit of `from` buys, as a decimal string.
+type RateProvider interface {
+	GetRate(from string, to string) (string, error)
+}
+
+// NewRateProvider picks the provider from FX_PROVIDER: "file" reads FX_RATES_FILE,
+// anything else reads the exchange_rates table.
+func NewRateProvider(DB *gorm.DB, repository repository.Repository, log log.WebLogger) RateProvider {
+	if os.Getenv("FX_PROVIDER") == "file" {
+		return NewFileRateProvider(os.Getenv("FX_RATES_FILE"), log)
+	}
+	return NewDBRateProvider(DB, repository, log)
+}
+
+// ---------------------------- DB backed ---------------------------------
+
+type DBRateProvider struct {
+	DB         *gorm.DB
+	repository repository.Repository
+	log        log.WebLogger
+}
+
+func NewDBRateProvider(DB *gorm.DB, repository repository.Repository, log log.WebLogger) *DBRateProvider {
+	return &DBRateProvider{
+		DB:         DB,
+		repository: repository,
+		log:        log,
+	}
+}
+
+func (provider *DBRateProvider) GetRate(from string, to string) (string, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return "1", nil
+	}
+	uow := repository.NewUnitOfWork(provider.DB)
+	defer uow.RollBack()
+
+	rate := fx.ExchangeRate{}
+	err := provider.repository.GetFirstWhere(uow, &rate, "from_currency = ? AND to_currency = ?", from, to)
+	if err == nil {
+		return rate.Rate, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return "", err
+	}
+	// only the reverse pair is configured
+	err = provider.repository.GetFirstWhere(uow, &rate, "from_currency = ? AND to_currency = ?", to, from)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return "", fmt.Errorf("no exchange rate configured for %s to %s", from, to)
+		}
+		return "", err
+	}
+	return invertRate(rate.Rate)
+}
+
+// ---------------------------- File backed -------------------------------
+
+// FileRateProvider reads a JSON file of the form {"USD/INR": "83.125", "EUR/INR": "90.10"}.
+// The file is re-read on every quote so rates can be updated without a restart.
+type FileRateProvider struct {
+	sync.Mutex
+	path string
+	log  log.WebLogger
+}
+
+func NewFileRateProvider(path string, log log.WebLogger) *FileRateProvider {
+	return &FileRateProvider{
+		path: path,
+		log:  log,
+	}
+}
+
+func (provider *FileRateProvider) GetRate(from string, to string) (string, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return "1", nil
+	}
+	provider.Lock()
+	defer provider.Unlock()
+	content, err := os.ReadFile(provider.path)
+	if err != nil {
+		provider.log.Error(err)
+		return "", errors.New("exchange rates file could not be read")
+	}
+	rates := map[string]string{}
+	if err := json.Unmarshal(content, &rates); err != nil {
+		return "", fmt.Errorf("exchange rates file is malformed: %w", err)
+	}
+	if rate, ok := rates[from+"/"+to]; ok {
+		return rate, nil
+	}
+	if rate, ok := rates[to+"/"+from]; ok {
+		return invertRate(rate)
+	}
+	return "", fmt.Errorf("no exchange rate configured for %s to %s", from, to)
+}
+
+func invertRate(rate string) (string, error) {
+	ratio, ok := new(big.Rat).SetString(rate)
+	if !ok || ratio.Sign() <= 0 {
+		return "", fmt.Errorf("invalid exchange rate %q", rate)
+	}
+	return new(big.Rat).Inv(ratio).FloatString(10), nil
+}
+
+// ---------------------------- Rate management ---------------------------
+
+type FxService struct {
+	DB         *gorm.DB
+	repository repository.Repository
+	log        log.WebLogger
+}
+
+func NewFxService(DB *gorm.DB, repository repository.Repository, log log.WebLogger) *FxService {
+	return &FxService{
+		DB:         DB,
+		repository: repository,
+		log:        log,
+	}
+}
+
+// WithActor returns a copy of the service whose changes the audit log attributes to actor
+// and whose log lines carry the actor's request ID.
+func (service *FxService) WithActor(actor *audit.Actor) *FxService {
+	scoped := *service
+	scoped.DB = repository.BindActor(service.DB, actor)
+	scoped.log = service.log.WithFields(log.Fields{constants.RequestIDKey: actor.RequestId})
+	return &scoped
+}
+
+// SetRate creates or replaces the rate of a currency pair in the exchange_rates table.
+func (service *FxService) SetRate(rate *fx.ExchangeRate) error {
+	rate.FromCurrency = strings.ToUpper(rate.FromCurrency)
+	rate.ToCurrency = strings.ToUpper(rate.ToCurrency)
+	if rate.FromCurrency == rate.ToCurrency {
+		return errors.New("from and to currency must differ")
+	}
+	if ratio, ok := new(big.Rat).SetString(rate.Rate); !ok || ratio.Sign() <= 0 {
+		return errors.New("rate must be a positive decimal")
+	}
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+
+	existing := fx.ExchangeRate{}
+	err := service.repository.GetFirstWhere(uow, &existing, "from_currency = ? AND to_currency = ?", rate.FromCurrency, rate.ToCurrency)
+	if err == nil {
+		existing.Rate = rate.Rate
+		if err := service.repository.Update(uow, &existing); err != nil {
+			return err
+		}
+		*rate = existing
+	} else if gorm.IsRecordNotFoundError(err) {
+		if err := service.repository.Add(uow, rate); err != nil {
+			return err
+		}
+	} else {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+func (service *FxService) GetAllRates(query *web.ListQuery, rates *[]fx.ExchangeRate) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	if err := service.repository.GetAll(uow, rates, query.QueryProcessors(service.repository)...); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"bankManagement/models/idempotency"
+	"bankManagement/repository"
+	"bankManagement/utils/log"
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RecordTTL is how long a key is remembered. A key older than this starts a new request.
+const RecordTTL = 24 * time.Hour
+
+// RecordLease is how long a request keeps its key in progress. No request runs this long
+// (the server times out after a minute), an older one died with the process and its key
+// is taken over by the retry.
+const RecordLease = 5 * time.Minute
+
+var ErrKeyReused = errors.New("idempotency key was already used for a different request")
+var ErrRequestInProgress = errors.New("a request with this idempotency key is still being processed")
+
+type IdempotencyService struct {
+	DB         *gorm.DB
+	repository repository.Repository
+	log        log.WebLogger
+}
+
+func NewIdempotencyService(DB *gorm.DB, repository repository.Repository, log log.WebLogger) *IdempotencyService {
+	return &IdempotencyService{
+		DB:         DB,
+		repository: repository,
+		log:        log,
+	}
+}
+
+// Begin claims the key of record for this request. It returns true, with record filled
+// from the stored response, when the same request was already completed and only has to
+// be replayed. The unique index on (client_id, idempotency_key) decides which of two
+// concurrent requests with the same key wins. A key still in progress after RecordLease
+// is taken over by a retry of the same request.
+func (service *IdempotencyService) Begin(record *idempotency.IdempotencyRecord) (bool, error) {
+	record.Status = idempotency.StatusInProgress
+	uow := repository.NewUnitOfWork(service.DB)
+	err := service.repository.Add(uow, record)
+	if err == nil {
+		uow.Commit()
+		return false, nil
+	}
+	uow.RollBack()
+
+	uow = repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	existing := idempotency.IdempotencyRecord{}
+	if err := service.repository.GetFirstWhere(uow, &existing, "client_id = ? AND idempotency_key = ?", record.ClientID, record.IdempotencyKey); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return false, errors.New("could not store idempotency key")
+		}
+		return false, err
+	}
+	if err := service.repository.GetByIDForUpdate(uow, &existing, existing.ID); err != nil {
+		return false, err
+	}
+
+	sameRequest := existing.RequestHash == record.RequestHash && existing.Method == record.Method && existing.Path == record.Path
+	abandoned := existing.Status == idempotency.StatusInProgress && time.Since(existing.CreatedAt) > RecordLease && sameRequest
+	if existing.Status == idempotency.StatusReleased || abandoned || time.Since(existing.CreatedAt) > RecordTTL {
+		// free to be taken again by this request
+		existing.CreatedAt = time.Now()
+		existing.Method = record.Method
+		existing.Path = record.Path
+		existing.RequestHash = record.RequestHash
+		existing.Status = idempotency.StatusInProgress
+		existing.StatusCode = 0
+		existing.ContentType = ""
+		existing.ResponseBody = ""
+		if err := service.repository.Update(uow, &existing); err != nil {
+			return false, err
+		}
+		*record = existing
+		uow.Commit()
+		return false, nil
+	}
+	if !sameRequest {
+		return false, ErrKeyReused
+	}
+	if existing.Status != idempotency.StatusCompleted {
+		return false, ErrRequestInProgress
+	}
+	*record = existing
+	uow.Commit()
+	return true, nil
+}
+
+// Complete stores the response so retries of the request are answered with it.
+func (service *IdempotencyService) Complete(record *idempotency.IdempotencyRecord, statusCode int, contentType string, body []byte) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	record.Status = idempotency.StatusCompleted
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.ResponseBody = string(body)
+	if err := service.repository.Update(uow, record); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// Release gives the key back after a server side failure so the client can retry it.
+func (service *IdempotencyService) Release(record *idempotency.IdempotencyRecord) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	record.Status = idempotency.StatusReleased
+	if err := service.repository.Update(uow, record); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"bankManagement/components/ledger/service"
+	"bankManagement/constants"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/ledger"
+	"bankManagement/models/user"
+	"bankManagement/utils/encrypt"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type LedgerController struct {
+	LedgerService *service.LedgerService
+	log           log.WebLogger
+}
+
+func NewLedgerController(
+	LedgerService *service.LedgerService,
+	log log.WebLogger,
+) *LedgerController {
+	return &LedgerController{
+		LedgerService: LedgerService,
+		log:           log,
+	}
+}
+
+var entryListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "posted_at": "posted_at"},
+	FilterFields: map[string]string{"entry_type": "entry_type"},
+	DefaultSort:  "-posted_at",
+}
+
+func (ctrl *LedgerController) RegisterRoutes(
+	router *mux.Router) {
+	subRouter := router.PathPrefix("/banks/{bank_id}/ledger").Subrouter()
+	subRouter.Use(auth.AuthenticationMiddleware, auth.ValidateBankPermissionsMiddleware)
+	subRouter.Handle("/trial_balance", auth.Require(ctrl.GetTrialBalance, user.PermissionLedgerRead)).Methods(http.MethodGet)
+	subRouter.Handle("/clients/{client_id}/entries", auth.Require(ctrl.GetClientStatement, user.PermissionLedgerRead)).Methods(http.MethodGet)
+}
+
+func (ctrl *LedgerController) GetTrialBalance(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	var trialBalance ledger.TrialBalance
+	err := ctrl.LedgerService.GetTrialBalance(claims.BankId, &trialBalance)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Trial Balance Generated",
+		Data:       trialBalance,
+	})
+}
+
+func (ctrl *LedgerController) GetClientStatement(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	clientId, err := strconv.Atoi(mux.Vars(r)["client_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Client ID should be a int", http.StatusBadRequest)
+		return
+	}
+	query, err := web.ParseListQuery(r, entryListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var entries []ledger.JournalEntry
+	err = ctrl.LedgerService.GetClientStatement(uint(clientId), claims.BankId, query, &entries)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SetListHeaders(w, r, query)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Ledger Entries Retrieved",
+		Data:       entries,
+	})
+}
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"bankManagement/models/client"
+	"bankManagement/models/ledger"
+	"bankManagement/repository"
+	"bankManagement/utils/log"
+	"bankManagement/utils/money"
+	"bankManagement/utils/web"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// LedgerService owns the double-entry books. The posting helpers take the caller's
+// unit of work so that a journal entry commits or rolls back together with the
+// business change (payment, salary...) that produced it.
+type LedgerService struct {
+	DB         *gorm.DB
+	repository repository.Repository
+	log        log.WebLogger
+}
+
+func NewLedgerService(
+	DB *gorm.DB,
+	repository repository.Repository,
+	log log.WebLogger,
+) *LedgerService {
+	return &LedgerService{
+		DB:         DB,
+		repository: repository,
+		log:        log,
+	}
+}
+
+type balanceResult struct {
+	Balance money.Money
+}
+
+// LockClients takes a row lock on each client for the rest of the unit of work. Every
+// path that checks a client's balance and then posts against it must lock the client
+// first, otherwise two concurrent approvals can both pass the check and overdraw the
+// account. Locks are taken in ascending ID order so two opposite payments between the
+// same clients cannot deadlock.
+func (s *LedgerService) LockClients(uow *repository.UOW, clientIds ...uint) error {
+	ids := append([]uint{}, clientIds...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for i, clientId := range ids {
+		if i > 0 && ids[i-1] == clientId {
+			continue
+		}
+		tempClient := client.Client{}
+		if err := s.repository.GetByIDForUpdate(uow, &tempClient, clientId); err != nil {
+			return fmt.Errorf("client with ID %d not found: %w", clientId, err)
+		}
+	}
+	return nil
+}
+
+// GetClientAccount returns the ledger account of a client, opening it on first use.
+func (s *LedgerService) GetClientAccount(uow *repository.UOW, clientId uint) (*ledger.Account, error) {
+	account := ledger.Account{}
+	err := s.repository.GetFirstWhere(uow, &account, "client_id = ? AND account_type = ?", clientId, ledger.AccountTypeClient)
+	if err == nil {
+		return &account, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	tempClient := client.Client{}
+	if err := s.repository.GetByID(uow, &tempClient, clientId); err != nil {
+		return nil, fmt.Errorf("client with ID %d not found: %w", clientId, err)
+	}
+	return s.openClientAccount(uow, &tempClient, 0)
+}
+
+// PostOpeningBalance opens the ledger account of a newly created client.
+func (s *LedgerService) PostOpeningBalance(uow *repository.UOW, tempClient *client.Client, createdByUserId uint) error {
+	_, err := s.openClientAccount(uow, tempClient, createdByUserId)
+	return err
+}
+
+// openClientAccount creates the client's account and books whatever Client.Balance holds
+// as its opening balance, funded by the bank. For clients created before the ledger existed
+// this carries their legacy balance over the first time they are touched.
+func (s *LedgerService) openClientAccount(uow *repository.UOW, tempClient *client.Client, createdByUserId uint) (*ledger.Account, error) {
+	account := ledger.Account{
+		AccountCode: fmt.Sprintf("CLIENT-%d", tempClient.ID),
+		AccountName: tempClient.ClientName,
+		AccountType: ledger.AccountTypeClient,
+		BankID:      tempClient.BankID,
+		ClientID:    tempClient.ID,
+		Currency:    tempClient.Currency,
+	}
+	if err := s.repository.Add(uow, &account); err != nil {
+		return nil, err
+	}
+	// the column holds minor units of the client's currency whatever Balance was scanned as
+	openingBalance := money.New(tempClient.Balance.Minor, account.Currency)
+	if !openingBalance.IsPositive() {
+		return &account, nil
+	}
+	fundingAccount, err := s.GetBankAccount(uow, tempClient.BankID, ledger.AccountTypeFunding, account.Currency)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Transfer(uow, fundingAccount, &account, openingBalance, &ledger.JournalEntry{
+		EntryType:       ledger.EntryTypeOpeningBalance,
+		ReferenceID:     tempClient.ID,
+		Description:     "Opening balance",
+		BankID:          tempClient.BankID,
+		CreatedByUserId: createdByUserId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GetBankAccount returns one of the internal accounts of a bank (funding, salary clearing, fx)
+// in the given currency, opening it on first use.
+func (s *LedgerService) GetBankAccount(uow *repository.UOW, bankId uint, accountType string, currency string) (*ledger.Account, error) {
+	currency = money.Zero(currency).CurrencyCode()
+	account := ledger.Account{}
+	err := s.repository.GetFirstWhere(uow, &account, "bank_id = ? AND account_type = ? AND client_id = 0 AND currency = ?", bankId, accountType, currency)
+	if err == nil {
+		return &account, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	account = ledger.Account{
+		AccountCode: fmt.Sprintf("BANK-%d-%s-%s", bankId, accountType, currency),
+		AccountName: fmt.Sprintf("%s %s", accountType, currency),
+		AccountType: accountType,
+		BankID:      bankId,
+		Currency:    currency,
+	}
+	if err := s.repository.Add(uow, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GetAccountBalance derives the balance of an account, in the account's currency, from its postings.
+func (s *LedgerService) GetAccountBalance(uow *repository.UOW, account *ledger.Account) (money.Money, error) {
+	result := balanceResult{}
+	err := s.repository.Raw(uow, &result,
+		"SELECT COALESCE(SUM(amount), 0) AS balance FROM postings WHERE account_id = ? AND deleted_at IS NULL", account.ID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return money.New(result.Balance.Minor, account.Currency), nil
+}
+
+func (s *LedgerService) GetClientBalance(uow *repository.UOW, clientId uint) (money.Money, error) {
+	account, err := s.GetClientAccount(uow, clientId)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return s.GetAccountBalance(uow, account)
+}
+
+// PostEntry validates and writes a journal entry balanced in every currency it
+// touches, then refreshes the cached Client.Balance of every client account it touched.
+func (s *LedgerService) PostEntry(uow *repository.UOW, entry *ledger.JournalEntry) error {
+	if len(entry.Postings) < 2 {
+		return errors.New("journal entry needs at least two postings")
+	}
+	totals := map[string]money.Money{}
+	for _, posting := range entry.Postings {
+		if posting.AccountID == 0 {
+			return errors.New("journal entry posting has no account")
+		}
+		if posting.Amount.IsZero() {
+			return errors.New("journal entry posting amount cannot be zero")
+		}
+		currency := posting.Amount.CurrencyCode()
+		if _, ok := totals[currency]; !ok {
+			totals[currency] = money.Zero(currency)
+		}
+		totals[currency] = totals[currency].Add(posting.Amount)
+	}
+	for _, total := range totals {
+		if !total.IsZero() {
+			return fmt.Errorf("journal entry is not balanced: postings sum to %s", total)
+		}
+	}
+	if entry.PostedAt.IsZero() {
+		entry.PostedAt = time.Now()
+	}
+	if err := s.repository.Add(uow, entry); err != nil {
+		return err
+	}
+	for _, posting := range entry.Postings {
+		if err := s.syncClientBalance(uow, posting.AccountID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Transfer posts a two-legged entry moving amount from one account to another.
+func (s *LedgerService) Transfer(uow *repository.UOW, from *ledger.Account, to *ledger.Account, amount money.Money, entry *ledger.JournalEntry) error {
+	if !amount.IsPositive() {
+		return errors.New("transfer amount must be positive")
+	}
+	if amount.CurrencyCode() != from.Currency || amount.CurrencyCode() != to.Currency {
+		return fmt.Errorf("cannot transfer %s between %s and %s accounts", amount, from.Currency, to.Currency)
+	}
+	entry.Postings = []ledger.Posting{
+		{AccountID: from.ID, Amount: amount.Neg()},
+		{AccountID: to.ID, Amount: amount},
+	}
+	return s.PostEntry(uow, entry)
+}
+
+// PostAdjustment brings a client's ledger balance to newBalance against the bank's funding account.
+func (s *LedgerService) PostAdjustment(uow *repository.UOW, clientId uint, newBalance money.Money, createdByUserId uint) error {
+	clientAccount, err := s.GetClientAccount(uow, clientId)
+	if err != nil {
+		return err
+	}
+	currentBalance, err := s.GetAccountBalance(uow, clientAccount)
+	if err != nil {
+		return err
+	}
+	newBalance, err = newBalance.InCurrency(clientAccount.Currency)
+	if err != nil {
+		return err
+	}
+	difference := newBalance.Sub(currentBalance)
+	if difference.IsZero() {
+		return nil
+	}
+	fundingAccount, err := s.GetBankAccount(uow, clientAccount.BankID, ledger.AccountTypeFunding, clientAccount.Currency)
+	if err != nil {
+		return err
+	}
+	entry := &ledger.JournalEntry{
+		EntryType:       ledger.EntryTypeAdjustment,
+		ReferenceID:     clientId,
+		Description:     "Balance adjustment by bank user",
+		BankID:          clientAccount.BankID,
+		CreatedByUserId: createdByUserId,
+	}
+	if difference.IsPositive() {
+		return s.Transfer(uow, fundingAccount, clientAccount, difference, entry)
+	}
+	return s.Transfer(uow, clientAccount, fundingAccount, difference.Neg(), entry)
+}
+
+// PostPayment moves an approved payment from the sender's account to the receiver's. When the
+// two accounts hold different currencies, amount is debited in the sender's currency and received
+// credited in the receiver's, each leg balanced against the bank's fx account of that currency.
+func (s *LedgerService) PostPayment(uow *repository.UOW, senderClientId uint, receiverClientId uint, amount money.Money, received money.Money, paymentId uint, bankId uint, approvedByUserId uint) error {
+	senderAccount, err := s.GetClientAccount(uow, senderClientId)
+	if err != nil {
+		return err
+	}
+	receiverAccount, err := s.GetClientAccount(uow, receiverClientId)
+	if err != nil {
+		return err
+	}
+	entry := &ledger.JournalEntry{
+		EntryType:       ledger.EntryTypePayment,
+		ReferenceID:     paymentId,
+		Description:     fmt.Sprintf("Payment from client %d to client %d", senderClientId, receiverClientId),
+		BankID:          bankId,
+		CreatedByUserId: approvedByUserId,
+	}
+	if senderAccount.Currency == receiverAccount.Currency {
+		return s.Transfer(uow, senderAccount, receiverAccount, amount, entry)
+	}
+	if !amount.IsPositive() || !received.IsPositive() {
+		return errors.New("transfer amount must be positive")
+	}
+	if amount.CurrencyCode() != senderAccount.Currency || received.CurrencyCode() != receiverAccount.Currency {
+		return fmt.Errorf("payment of %s (%s received) does not match %s and %s accounts", amount, received, senderAccount.Currency, receiverAccount.Currency)
+	}
+	sellAccount, err := s.GetBankAccount(uow, bankId, ledger.AccountTypeFx, senderAccount.Currency)
+	if err != nil {
+		return err
+	}
+	buyAccount, err := s.GetBankAccount(uow, bankId, ledger.AccountTypeFx, receiverAccount.Currency)
+	if err != nil {
+		return err
+	}
+	entry.Postings = []ledger.Posting{
+		{AccountID: senderAccount.ID, Amount: amount.Neg()},
+		{AccountID: sellAccount.ID, Amount: amount},
+		{AccountID: buyAccount.ID, Amount: received.Neg()},
+		{AccountID: receiverAccount.ID, Amount: received},
+	}
+	return s.PostEntry(uow, entry)
+}
+
+// PostSalary moves a salary out of the client's account into its bank's salary clearing account.
+func (s *LedgerService) PostSalary(uow *repository.UOW, clientId uint, empId uint, amount money.Money, disbursementId uint, createdByUserId uint) error {
+	clientAccount, err := s.GetClientAccount(uow, clientId)
+	if err != nil {
+		return err
+	}
+	clearingAccount, err := s.GetBankAccount(uow, clientAccount.BankID, ledger.AccountTypeSalaryClearing, clientAccount.Currency)
+	if err != nil {
+		return err
+	}
+	return s.Transfer(uow, clientAccount, clearingAccount, amount, &ledger.JournalEntry{
+		EntryType:       ledger.EntryTypeSalary,
+		ReferenceID:     disbursementId,
+		Description:     fmt.Sprintf("Salary to employee %d", empId),
+		BankID:          clientAccount.BankID,
+		CreatedByUserId: createdByUserId,
+	})
+}
+
+// syncClientBalance keeps Client.Balance as a read cache of the ledger. It is never written anywhere else.
+func (s *LedgerService) syncClientBalance(uow *repository.UOW, accountId uint) error {
+	account := ledger.Account{}
+	if err := s.repository.GetByID(uow, &account, accountId); err != nil {
+		return err
+	}
+	if account.AccountType != ledger.AccountTypeClient {
+		return nil
+	}
+	balance, err := s.GetAccountBalance(uow, &account)
+	if err != nil {
+		return err
+	}
+	tempClient := client.Client{}
+	if err := s.repository.GetByID(uow, &tempClient, account.ClientID); err != nil {
+		return err
+	}
+	tempClient.Balance = balance
+	return s.repository.Update(uow, &tempClient)
+}
+
+// GetClientStatement lists every journal entry that touched the client's account.
+func (s *LedgerService) GetClientStatement(clientId uint, bankId uint, query *web.ListQuery, entries *[]ledger.JournalEntry) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+
+	account := ledger.Account{}
+	if err := s.repository.GetFirstWhere(uow, &account, "client_id = ? AND bank_id = ? AND account_type = ?", clientId, bankId, ledger.AccountTypeClient); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return fmt.Errorf("no ledger account found for client ID %d in bank ID %d", clientId, bankId)
+		}
+		return err
+	}
+	queryProcessors := []repository.QueryProcessor{
+		s.repository.Filter("id IN (SELECT journal_entry_id FROM postings WHERE account_id = ? AND deleted_at IS NULL)", account.ID),
+		s.repository.Preload("Postings"),
+	}
+	err := s.repository.GetAll(uow, entries, append(queryProcessors, query.QueryProcessors(s.repository)...)...)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// GetTrialBalance lists the balance of every account of a bank. A healthy ledger always totals zero in each currency.
+func (s *LedgerService) GetTrialBalance(bankId uint, trialBalance *ledger.TrialBalance) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+
+	var accounts []ledger.Account
+	if err := s.repository.GetAll(uow, &accounts, s.repository.Filter("bank_id = ?", bankId)); err != nil {
+		return err
+	}
+	result := ledger.TrialBalance{BankID: bankId, Totals: map[string]money.Money{}, Balanced: true}
+	for i := range accounts {
+		account := accounts[i]
+		balance, err := s.GetAccountBalance(uow, &account)
+		if err != nil {
+			return err
+		}
+		result.Accounts = append(result.Accounts, ledger.AccountBalanceDTO{
+			AccountID:   account.ID,
+			AccountCode: account.AccountCode,
+			AccountName: account.AccountName,
+			AccountType: account.AccountType,
+			ClientID:    account.ClientID,
+			Balance:     balance,
+		})
+		if _, ok := result.Totals[account.Currency]; !ok {
+			result.Totals[account.Currency] = money.Zero(account.Currency)
+		}
+		result.Totals[account.Currency] = result.Totals[account.Currency].Add(balance)
+	}
+	for _, total := range result.Totals {
+		if !total.IsZero() {
+			result.Balanced = false
+		}
+	}
+	*trialBalance = result
+
+	uow.Commit()
+	return nil
+}
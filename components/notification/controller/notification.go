@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"bankManagement/components/notification/service"
+	"bankManagement/constants"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/notification"
+	"bankManagement/models/user"
+	"bankManagement/utils/encrypt"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type NotificationController struct {
+	NotificationService *service.NotificationService
+	log                 log.WebLogger
+}
+
+func NewNotificationController(
+	NotificationService *service.NotificationService,
+	log log.WebLogger,
+) *NotificationController {
+	return &NotificationController{
+		NotificationService: NotificationService,
+		log:                 log,
+	}
+}
+
+var notificationListOptions = web.ListOptions{
+	SortFields: map[string]string{"id": "id", "created_at": "created_at"},
+	FilterFields: map[string]string{
+		"status":     "status",
+		"event_type": "event_type",
+		"channel":    "channel",
+		"user_id":    "user_id",
+	},
+	DefaultSort: "-id",
+}
+
+func (ctrl *NotificationController) RegisterRoutes(router *mux.Router) {
+	notificationRouter := router.PathPrefix("/notifications").Subrouter()
+	notificationRouter.Use(auth.AuthenticationMiddleware)
+	notificationRouter.HandleFunc("/", ctrl.GetMyNotifications).Methods(http.MethodGet)
+	notificationRouter.HandleFunc("/preferences", ctrl.GetPreferences).Methods(http.MethodGet)
+	notificationRouter.HandleFunc("/preferences", ctrl.UpdatePreferences).Methods(http.MethodPut)
+	notificationRouter.Handle("/all", auth.Require(ctrl.GetAllNotifications, user.PermissionNotificationRead)).Methods(http.MethodGet)
+}
+
+func (ctrl *NotificationController) GetMyNotifications(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	ctrl.getNotifications(w, r, claims.UserId)
+}
+
+func (ctrl *NotificationController) GetAllNotifications(w http.ResponseWriter, r *http.Request) {
+	ctrl.getNotifications(w, r, 0)
+}
+
+func (ctrl *NotificationController) getNotifications(w http.ResponseWriter, r *http.Request, userId uint) {
+	query, err := web.ParseListQuery(r, notificationListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var notifications []notification.Notification
+	err = ctrl.NotificationService.WithActor(auth.ActorFromRequest(r)).GetNotifications(userId, query, &notifications)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	web.SetListHeaders(w, r, query)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Notifications Retrieved",
+		Data:       notifications,
+	})
+}
+
+func (ctrl *NotificationController) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	preferences, err := ctrl.NotificationService.WithActor(auth.ActorFromRequest(r)).GetPreferences(claims.UserId)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Notification Preferences Retrieved",
+		Data:       preferences,
+	})
+}
+
+func (ctrl *NotificationController) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	update := &notification.NotificationPreferencesDTO{}
+	err := web.UnMarshalJSON(r, update)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(update)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	notificationService := ctrl.NotificationService.WithActor(auth.ActorFromRequest(r))
+	err = notificationService.UpdatePreferences(claims.UserId, update)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	preferences, err := notificationService.GetPreferences(claims.UserId)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusAccepted,
+		Message:    "Notification Preferences Updated",
+		Data:       preferences,
+	})
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"bankManagement/constants"
+	"bankManagement/models/audit"
+	"bankManagement/models/notification"
+	"bankManagement/models/user"
+	"bankManagement/repository"
+	"bankManagement/utils/log"
+	"bankManagement/utils/notify"
+	"bankManagement/utils/web"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// NotificationService renders the notification of an event from its template and sends it
+// on the channels the recipient gets it on, recording every send.
+type NotificationService struct {
+	DB         *gorm.DB
+	repository repository.Repository
+	log        log.WebLogger
+	notifiers  map[string]notify.Notifier // by channel, a channel without one is not sent on
+	templates  *notify.Templates
+}
+
+func NewNotificationService(
+	DB *gorm.DB,
+	repository repository.Repository,
+	log log.WebLogger,
+	notifiers map[string]notify.Notifier,
+	templates *notify.Templates,
+) *NotificationService {
+	return &NotificationService{
+		DB:         DB,
+		repository: repository,
+		log:        log,
+		notifiers:  notifiers,
+		templates:  templates,
+	}
+}
+
+// WithActor returns a copy of the service whose changes the audit log attributes to actor
+// and whose log lines carry the actor's request ID.
+func (service *NotificationService) WithActor(actor *audit.Actor) *NotificationService {
+	scoped := *service
+	scoped.DB = repository.BindActor(service.DB, actor)
+	scoped.log = service.log.WithFields(log.Fields{constants.RequestIDKey: actor.RequestId})
+	return &scoped
+}
+
+// Notify sends the notification of the event to the recipient. data fills the template.
+// An error means a channel did not take it, the sends that failed are recorded as such.
+func (service *NotificationService) Notify(eventType string, to notification.Recipient, data interface{}) error {
+	return service.NotifyOnce("", eventType, to, data)
+}
+
+// NotifyOnce is Notify for callers that may run twice, the outbox subscribers: what was
+// sent under the key already is not sent again.
+func (service *NotificationService) NotifyOnce(key string, eventType string, to notification.Recipient, data interface{}) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	addresses, err := service.addresses(uow, eventType, to)
+	if err != nil {
+		return err
+	}
+	sent := map[string]bool{}
+	if key != "" {
+		var earlier []notification.Notification
+		err = service.repository.GetAll(uow, &earlier,
+			service.repository.Filter("idempotency_key = ? AND event_type = ? AND status = ?", key, eventType, notification.StatusSent))
+		if err != nil {
+			return err
+		}
+		for _, record := range earlier {
+			sent[record.Channel+":"+record.Recipient] = true
+		}
+	}
+
+	var failures []error
+	for _, address := range addresses {
+		if sent[address.channel+":"+address.to] {
+			continue
+		}
+		record := notification.Notification{
+			IdempotencyKey: key,
+			UserID:         to.UserID,
+			EventType:      eventType,
+			Channel:        address.channel,
+			Recipient:      address.to,
+			Status:         notification.StatusSent,
+		}
+		err := service.send(eventType, address, data, &record)
+		if err != nil {
+			record.Status = notification.StatusFailed
+			record.Error = err.Error()
+			failures = append(failures, fmt.Errorf("%s to %s: %w", address.channel, address.to, err))
+			service.log.WithFields(log.Fields{"event_type": eventType, "channel": address.channel}).Warning("notification failed:", err)
+		} else {
+			sentAt := time.Now()
+			record.SentAt = &sentAt
+		}
+		if err := service.repository.Add(uow, &record); err != nil {
+			return err
+		}
+	}
+	uow.Commit()
+	return errors.Join(failures...)
+}
+
+func (service *NotificationService) send(eventType string, address address, data interface{}, record *notification.Notification) error {
+	message, err := service.templates.Render(eventType, address.channel, data)
+	if err != nil {
+		return err
+	}
+	message.To = address.to
+	record.Subject = message.Subject
+	return service.notifiers[address.channel].Send(message)
+}
+
+// address is where one channel reaches the recipient.
+type address struct {
+	channel string
+	to      string
+}
+
+// addresses returns where the recipient gets the event: email unless turned off, text
+// messages when turned on and the user has a phone number, on the channels configured.
+func (service *NotificationService) addresses(uow *repository.UOW, eventType string, to notification.Recipient) ([]address, error) {
+	enabled := map[string]bool{notify.ChannelEmail: true}
+	email, phone := to.Email, ""
+	if to.UserID != 0 {
+		userEntity := user.User{}
+		err := service.repository.GetByID(uow, &userEntity, to.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if email == "" {
+			email = userEntity.Email
+		}
+		phone = userEntity.Phone
+		var preferences []notification.NotificationPreference
+		err = service.repository.GetAll(uow, &preferences,
+			service.repository.Filter("user_id = ? AND event_type IN (?)", to.UserID, []string{eventType, notification.AllEvents}),
+			service.repository.Order("event_type = '*' desc"),
+		)
+		if err != nil {
+			return nil, err
+		}
+		// the preferences for every event come first, those for this event override them
+		for _, preference := range preferences {
+			enabled[preference.Channel] = preference.Enabled
+		}
+		if slices.Contains(notification.MandatoryEvents, eventType) {
+			enabled[notify.ChannelEmail] = true
+		}
+	}
+
+	reach := map[string]string{notify.ChannelEmail: email, notify.ChannelSMS: phone}
+	var addresses []address
+	for _, channel := range notify.Channels {
+		if enabled[channel] && reach[channel] != "" && service.notifiers[channel] != nil {
+			addresses = append(addresses, address{channel: channel, to: reach[channel]})
+		}
+	}
+	return addresses, nil
+}
+
+// GetPreferences returns the user's phone number and notification preferences.
+func (service *NotificationService) GetPreferences(userId uint) (*notification.NotificationPreferencesDTO, error) {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	userEntity := user.User{}
+	err := service.repository.GetByID(uow, &userEntity, userId)
+	if err != nil {
+		return nil, errors.New("User not found")
+	}
+	preferences := []notification.NotificationPreference{}
+	err = service.repository.GetAll(uow, &preferences,
+		service.repository.Filter("user_id = ?", userId),
+		service.repository.Order("event_type, channel"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	uow.Commit()
+	return &notification.NotificationPreferencesDTO{
+		Phone:       &userEntity.Phone,
+		Preferences: preferences,
+		EventTypes:  notification.EventTypes,
+		Channels:    notify.Channels,
+	}, nil
+}
+
+// UpdatePreferences sets the preferences given, keeping the others, and the phone number
+// when given.
+func (service *NotificationService) UpdatePreferences(userId uint, update *notification.NotificationPreferencesDTO) error {
+	for _, preference := range update.Preferences {
+		if preference.EventType != notification.AllEvents && !slices.Contains(notification.EventTypes, preference.EventType) {
+			return fmt.Errorf("Unknown event type %s", preference.EventType)
+		}
+		if !slices.Contains(notify.Channels, preference.Channel) {
+			return fmt.Errorf("Unknown channel %s", preference.Channel)
+		}
+	}
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	userEntity := user.User{}
+	err := service.repository.GetByIDForUpdate(uow, &userEntity, userId)
+	if err != nil {
+		return errors.New("User not found")
+	}
+	if update.Phone != nil && *update.Phone != userEntity.Phone {
+		userEntity.Phone = *update.Phone
+		err = service.repository.Update(uow, &userEntity)
+		if err != nil {
+			return err
+		}
+	}
+	for _, preference := range update.Preferences {
+		var existing []notification.NotificationPreference
+		err = service.repository.GetAll(uow, &existing,
+			service.repository.Filter("user_id = ? AND event_type = ? AND channel = ?", userId, preference.EventType, preference.Channel))
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			existing[0].Enabled = preference.Enabled
+			err = service.repository.Update(uow, &existing[0])
+		} else {
+			preference.ID = 0
+			preference.UserID = userId
+			err = service.repository.Add(uow, &preference)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	uow.Commit()
+	return nil
+}
+
+// GetNotifications lists the notifications sent, to the user or, with userId 0, to anyone.
+func (service *NotificationService) GetNotifications(userId uint, query *web.ListQuery, notifications *[]notification.Notification) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	var queryProcessors []repository.QueryProcessor
+	if userId != 0 {
+		queryProcessors = append(queryProcessors, service.repository.Filter("user_id = ?", userId))
+	}
+	err := service.repository.GetAll(uow, notifications, append(queryProcessors, query.QueryProcessors(service.repository)...)...)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
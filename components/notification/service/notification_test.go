@@ -0,0 +1,163 @@
+package service_test
+
+import (
+	"bankManagement/components/notification/service"
+	"bankManagement/models/notification"
+	"bankManagement/models/user"
+	"bankManagement/utils/notify"
+	"bankManagement/utils/testdb"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNotifyOnce(t *testing.T) {
+	appObj := testdb.Open(t)
+	templates, err := notify.LoadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	role := user.Role{}
+	if err := appObj.DB.Where("role_name = ?", user.RoleBankUser).First(&role).Error; err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]interface{}{"PaymentRequestId": 7, "Username": "notified", "Token": "token", "ValidFor": "1 hour"}
+
+	tests := []struct {
+		name        string
+		preferences []notification.NotificationPreference
+		smsFailures int      // how many of the sends the text message gateway refuses
+		events      []string // notified in turn under the same key
+		wantErrors  int
+		wantEmails  int
+		wantSMS     int
+		wantRecords []string // channel:status
+	}{
+		{
+			name:        "records a sent and a failed send",
+			preferences: []notification.NotificationPreference{{EventType: notification.EventPaymentApproved, Channel: notify.ChannelSMS, Enabled: true}},
+			smsFailures: 1,
+			events:      []string{notification.EventPaymentApproved},
+			wantErrors:  1,
+			wantEmails:  1,
+			wantRecords: []string{"email:Sent", "sms:Failed"},
+		},
+		{
+			name: "event preference overrides the one for every event",
+			preferences: []notification.NotificationPreference{
+				{EventType: notification.AllEvents, Channel: notify.ChannelEmail, Enabled: false},
+				{EventType: notification.AllEvents, Channel: notify.ChannelSMS, Enabled: true},
+				{EventType: notification.EventPaymentApproved, Channel: notify.ChannelEmail, Enabled: true},
+				{EventType: notification.EventPaymentApproved, Channel: notify.ChannelSMS, Enabled: false},
+			},
+			events:      []string{notification.EventPaymentApproved},
+			wantEmails:  1,
+			wantRecords: []string{"email:Sent"},
+		},
+		{
+			name: "preference for every event applies to the events without their own",
+			preferences: []notification.NotificationPreference{
+				{EventType: notification.AllEvents, Channel: notify.ChannelEmail, Enabled: false},
+				{EventType: notification.AllEvents, Channel: notify.ChannelSMS, Enabled: true},
+				{EventType: notification.EventPaymentApproved, Channel: notify.ChannelEmail, Enabled: true},
+			},
+			events:      []string{notification.EventClientVerified},
+			wantSMS:     1,
+			wantRecords: []string{"sms:Sent"},
+		},
+		{
+			name: "mandatory event is emailed with email turned off",
+			preferences: []notification.NotificationPreference{
+				{EventType: notification.AllEvents, Channel: notify.ChannelEmail, Enabled: false},
+				{EventType: notification.EventPasswordReset, Channel: notify.ChannelEmail, Enabled: false},
+			},
+			events:      []string{notification.EventPasswordReset},
+			wantEmails:  1,
+			wantRecords: []string{"email:Sent"},
+		},
+		{
+			name:        "optional event is not emailed with email turned off",
+			preferences: []notification.NotificationPreference{{EventType: notification.AllEvents, Channel: notify.ChannelEmail, Enabled: false}},
+			events:      []string{notification.EventPaymentApproved},
+		},
+		{
+			name:        "same key is not sent again",
+			events:      []string{notification.EventPaymentApproved, notification.EventPaymentApproved},
+			wantEmails:  1,
+			wantRecords: []string{"email:Sent"},
+		},
+		{
+			name:        "retry under the same key only sends what failed",
+			preferences: []notification.NotificationPreference{{EventType: notification.EventPaymentApproved, Channel: notify.ChannelSMS, Enabled: true}},
+			smsFailures: 1,
+			events:      []string{notification.EventPaymentApproved, notification.EventPaymentApproved, notification.EventPaymentApproved},
+			wantErrors:  1,
+			wantEmails:  1,
+			wantSMS:     1,
+			wantRecords: []string{"email:Sent", "sms:Failed", "sms:Sent"},
+		},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recipient := user.User{
+				Username: fmt.Sprintf("notified%d", i),
+				Password: "-",
+				Name:     "Notified",
+				Email:    fmt.Sprintf("notified%d@example.com", i),
+				Phone:    fmt.Sprintf("+91980000000%d", i),
+				IsActive: true,
+				RoleID:   role.ID,
+			}
+			if err := appObj.DB.Create(&recipient).Error; err != nil {
+				t.Fatal(err)
+			}
+			for _, preference := range test.preferences {
+				preference.UserID = recipient.ID
+				if err := appObj.DB.Create(&preference).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			email, sms := &notify.FakeNotifier{}, &notify.FakeNotifier{}
+			notifiers := map[string]notify.Notifier{notify.ChannelEmail: email, notify.ChannelSMS: sms}
+			notifications := service.NewNotificationService(appObj.DB, appObj.Repository, appObj.Log, notifiers, templates)
+
+			errorCount := 0
+			for j, eventType := range test.events {
+				sms.Err = nil
+				if j < test.smsFailures {
+					sms.Err = errors.New("gateway down")
+				}
+				if err := notifications.NotifyOnce("key", eventType, notification.Recipient{UserID: recipient.ID}, data); err != nil {
+					errorCount++
+				}
+			}
+			if errorCount != test.wantErrors {
+				t.Errorf("%d sends returned an error, expected %d", errorCount, test.wantErrors)
+			}
+			if len(email.Sent()) != test.wantEmails || len(sms.Sent()) != test.wantSMS {
+				t.Errorf("sent %d emails and %d text messages, expected %d and %d", len(email.Sent()), len(sms.Sent()), test.wantEmails, test.wantSMS)
+			}
+			for _, message := range email.Sent() {
+				if message.To != recipient.Email {
+					t.Errorf("emailed %s, expected %s", message.To, recipient.Email)
+				}
+			}
+
+			var records []notification.Notification
+			if err := appObj.DB.Where("user_id = ?", recipient.ID).Order("id asc").Find(&records).Error; err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, record := range records {
+				got = append(got, record.Channel+":"+record.Status)
+				sent := record.Status == notification.StatusSent
+				if sent != (record.SentAt != nil) || sent != (record.Error == "") {
+					t.Errorf("%s recorded with sent at %v and error %q", record.Status, record.SentAt, record.Error)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.wantRecords) {
+				t.Errorf("recorded %v, expected %v", got, test.wantRecords)
+			}
+		})
+	}
+}
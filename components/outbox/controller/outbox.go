@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"bankManagement/components/outbox/service"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/outbox"
+	"bankManagement/models/user"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type OutboxController struct {
+	OutboxService *service.OutboxService
+	log           log.WebLogger
+}
+
+func NewOutboxController(
+	OutboxService *service.OutboxService,
+	log log.WebLogger,
+) *OutboxController {
+	return &OutboxController{
+		OutboxService: OutboxService,
+		log:           log,
+	}
+}
+
+var eventListOptions = web.ListOptions{
+	SortFields: map[string]string{"id": "id", "created_at": "created_at", "next_attempt_at": "next_attempt_at"},
+	FilterFields: map[string]string{
+		"status":         "status",
+		"event_type":     "event_type",
+		"aggregate_type": "aggregate_type",
+		"aggregate_id":   "aggregate_id",
+		"bank_id":        "bank_id",
+	},
+	DefaultSort: "-id",
+}
+
+func (ctrl *OutboxController) RegisterRoutes(router *mux.Router) {
+	eventRouter := router.PathPrefix("/events").Subrouter()
+	eventRouter.Use(auth.AuthenticationMiddleware)
+	eventRouter.Handle("/", auth.Require(ctrl.GetAllEvents, user.PermissionEventRead)).Methods(http.MethodGet)
+	eventRouter.Handle("/{event_id}/retry", auth.Require(ctrl.RetryEvent, user.PermissionEventRetry)).Methods(http.MethodPost)
+}
+
+func (ctrl *OutboxController) GetAllEvents(w http.ResponseWriter, r *http.Request) {
+	query, err := web.ParseListQuery(r, eventListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var events []outbox.OutboxEvent
+	err = ctrl.OutboxService.GetEvents(query, &events)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	web.SetListHeaders(w, r, query)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Events Retrieved",
+		Data:       events,
+	})
+}
+
+func (ctrl *OutboxController) RetryEvent(w http.ResponseWriter, r *http.Request) {
+	eventId, err := strconv.Atoi(mux.Vars(r)["event_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Event ID should be a int", http.StatusBadRequest)
+		return
+	}
+	var event outbox.OutboxEvent
+	err = ctrl.OutboxService.RetryEvent(uint(eventId), &event)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Event Queued For Delivery",
+		Data:       event,
+	})
+}
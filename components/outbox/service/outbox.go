@@ -0,0 +1,261 @@
+package service
+
+import (
+	"bankManagement/constants"
+	"bankManagement/models/outbox"
+	"bankManagement/repository"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Handler takes one event. Returning an error, or panicking, has the event handed to it
+// again later: handlers must cope with getting the same event twice.
+type Handler func(event *outbox.OutboxEvent) error
+
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+// OutboxService is the domain event bus. Services publish events in their unit of work, the
+// dispatcher delivers the committed ones to the in-process subscribers, at least once each.
+type OutboxService struct {
+	DB          *gorm.DB
+	repository  repository.Repository
+	log         log.WebLogger
+	subscribers map[string][]subscriber
+	wakeUp      chan struct{}
+	startOnce   sync.Once
+}
+
+func NewOutboxService(DB *gorm.DB, repository repository.Repository, log log.WebLogger) *OutboxService {
+	return &OutboxService{
+		DB:          DB,
+		repository:  repository,
+		log:         log,
+		subscribers: map[string][]subscriber{},
+		wakeUp:      make(chan struct{}, 1),
+	}
+}
+
+// Subscribe registers handler for the event types. The name identifies the subscriber in
+// the delivery records, it must stay the same across restarts.
+func (service *OutboxService) Subscribe(name string, handler Handler, eventTypes ...string) {
+	for _, eventType := range eventTypes {
+		service.subscribers[eventType] = append(service.subscribers[eventType], subscriber{name: name, handler: handler})
+	}
+}
+
+// Publish writes the event in uow, it is dispatched once uow is committed.
+func (service *OutboxService) Publish(uow *repository.UOW, event outbox.Event) error {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+	record := outbox.OutboxEvent{
+		EventType:     event.Type,
+		AggregateType: event.AggregateType,
+		AggregateId:   event.AggregateId,
+		BankId:        event.BankId,
+		Payload:       string(payload),
+		Status:        outbox.EventStatusPending,
+		NextAttemptAt: dueNow(),
+	}
+	err = service.repository.Add(uow, &record)
+	if err != nil {
+		return err
+	}
+	uow.AfterCommit(service.wake)
+	return nil
+}
+
+// wake has the dispatch goroutine look for due events now rather than at the next tick.
+func (service *OutboxService) wake() {
+	service.startOnce.Do(func() {
+		go func() {
+			for range service.wakeUp {
+				if err := service.DispatchDue(time.Now()); err != nil {
+					service.log.Error("outbox dispatch failed:", err)
+				}
+			}
+		}()
+	})
+	select {
+	case service.wakeUp <- struct{}{}:
+	default:
+		// a dispatch is already due
+	}
+}
+
+// DispatchDue delivers the pending events whose attempt is due, oldest first.
+func (service *OutboxService) DispatchDue(now time.Time) error {
+	var lastId uint
+	for {
+		var due []outbox.OutboxEvent
+		uow := repository.NewUnitOfWork(service.DB)
+		err := service.repository.GetAll(uow, &due,
+			service.repository.Filter("status = ? AND next_attempt_at <= ? AND id > ?", outbox.EventStatusPending, now, lastId),
+			service.repository.Order("id asc"),
+			service.repository.Limit(constants.OutboxBatchSize),
+		)
+		uow.RollBack()
+		if err != nil {
+			return err
+		}
+		for _, event := range due {
+			lastId = event.ID
+			if err := service.deliver(event.ID, now); err != nil {
+				service.log.WithFields(log.Fields{"event_id": event.ID, "event_type": event.EventType}).Error("outbox delivery failed:", err)
+			}
+		}
+		if len(due) < constants.OutboxBatchSize {
+			return nil
+		}
+	}
+}
+
+// deliver hands the event to the subscribers that have not taken it yet. The event stays
+// locked meanwhile, so concurrent dispatchers do not deliver it twice.
+func (service *OutboxService) deliver(eventId uint, now time.Time) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	var events []outbox.OutboxEvent
+	err := service.repository.GetAll(uow, &events,
+		service.repository.Filter("id = ? AND status = ? AND next_attempt_at <= ?", eventId, outbox.EventStatusPending, now),
+		service.repository.ForUpdate(),
+	)
+	if err != nil || len(events) == 0 {
+		// gone, or delivered by another dispatcher meanwhile
+		return err
+	}
+	event := events[0]
+	var deliveries []outbox.OutboxDelivery
+	err = service.repository.GetAll(uow, &deliveries, service.repository.Filter("event_id = ?", event.ID))
+	if err != nil {
+		return err
+	}
+	delivered := map[string]bool{}
+	for _, delivery := range deliveries {
+		delivered[delivery.Subscriber] = true
+	}
+
+	var failures []string
+	for _, subscriber := range service.subscribers[event.EventType] {
+		if delivered[subscriber.name] {
+			continue
+		}
+		if err := handle(subscriber, &event); err != nil {
+			failures = append(failures, subscriber.name+": "+err.Error())
+			continue
+		}
+		delivered[subscriber.name] = true
+		err = service.repository.Add(uow, &outbox.OutboxDelivery{EventID: event.ID, Subscriber: subscriber.name})
+		if err != nil {
+			return err
+		}
+	}
+
+	event.Attempts++
+	event.LastError = strings.Join(failures, "; ")
+	switch {
+	case len(failures) == 0:
+		event.Status = outbox.EventStatusDelivered
+		event.DeliveredAt = &now
+	case event.Attempts >= constants.OutboxMaxAttempts:
+		event.Status = outbox.EventStatusFailed
+		service.log.WithFields(log.Fields{"event_id": event.ID, "event_type": event.EventType}).Error("outbox event failed for good:", event.LastError)
+	default:
+		event.NextAttemptAt = now.Add(retryDelay(event.Attempts))
+	}
+	err = service.repository.Update(uow, &event)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// handle runs one handler, a panic counts as a failure.
+func handle(subscriber subscriber, event *outbox.OutboxEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panicked: %v", r)
+		}
+	}()
+	return subscriber.handler(event)
+}
+
+// dueNow is now without the fraction of a second, which MySQL would round up: the event
+// must already be due when the dispatcher is woken right after the commit.
+func dueNow() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+func retryDelay(attempts int) time.Duration {
+	if attempts > 30 {
+		return constants.OutboxRetryMax
+	}
+	return min(constants.OutboxRetryBase<<(attempts-1), constants.OutboxRetryMax)
+}
+
+func (service *OutboxService) GetEvents(query *web.ListQuery, events *[]outbox.OutboxEvent) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	err := service.repository.GetAll(uow, events, query.QueryProcessors(service.repository)...)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// RetryEvent puts a failed event back in the queue with a fresh count of attempts. The
+// subscribers that took it already do not get it again.
+func (service *OutboxService) RetryEvent(eventId uint, event *outbox.OutboxEvent) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	err := service.repository.GetByIDForUpdate(uow, event, eventId)
+	if err != nil {
+		return errors.New("Event not found")
+	}
+	if event.Status != outbox.EventStatusFailed {
+		return fmt.Errorf("Event is %s, only failed events can be retried", event.Status)
+	}
+	event.Status = outbox.EventStatusPending
+	event.Attempts = 0
+	event.NextAttemptAt = dueNow()
+	err = service.repository.Update(uow, event)
+	if err != nil {
+		return err
+	}
+	uow.AfterCommit(service.wake)
+	uow.Commit()
+	return nil
+}
+
+// ---------------------------- Scheduler job -----------------------------
+
+// OutboxJob retries the events whose delivery failed, on every scheduler tick.
+type OutboxJob struct {
+	service *OutboxService
+}
+
+func NewOutboxJob(service *OutboxService) *OutboxJob {
+	return &OutboxJob{service: service}
+}
+
+func (job *OutboxJob) Name() string {
+	return "outbox"
+}
+
+func (job *OutboxJob) Run(now time.Time) error {
+	return job.service.DispatchDue(now)
+}
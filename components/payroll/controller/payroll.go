@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"bankManagement/components/payroll/service"
+	"bankManagement/constants"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/payroll"
+	"bankManagement/models/user"
+	"bankManagement/utils/encrypt"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type PayrollController struct {
+	PayrollService *service.PayrollService
+	log            log.WebLogger
+}
+
+func NewPayrollController(
+	PayrollService *service.PayrollService,
+	log log.WebLogger,
+) *PayrollController {
+	return &PayrollController{
+		PayrollService: PayrollService,
+		log:            log,
+	}
+}
+
+var scheduleListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "next_run_at": "next_run_at", "created_at": "created_at"},
+	FilterFields: map[string]string{"is_active": "is_active", "frequency": "frequency", "strategy": "strategy"},
+}
+
+var runListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "period_start": "period_start", "created_at": "created_at"},
+	FilterFields: map[string]string{"status": "status", "schedule_id": "schedule_id"},
+	DefaultSort:  "-period_start",
+}
+
+func (ctrl *PayrollController) RegisterRoutes(
+	router *mux.Router) {
+	subRouter := router.PathPrefix("/clients/{client_id}/").Subrouter()
+	subRouter.Use(auth.AuthenticationMiddleware, auth.ValidateClientPermissionsMiddleware)
+	subRouter.Handle("/payroll_schedules", auth.Require(ctrl.CreateSchedule, user.PermissionPayrollWrite)).Methods(http.MethodPost)
+	subRouter.Handle("/payroll_schedules", auth.Require(ctrl.GetAllSchedules, user.PermissionPayrollRead)).Methods(http.MethodGet)
+	subRouter.Handle("/payroll_schedules/{schedule_id}", auth.Require(ctrl.UpdateSchedule, user.PermissionPayrollWrite)).Methods(http.MethodPut)
+	subRouter.Handle("/payroll_schedules/{schedule_id}", auth.Require(ctrl.DeleteSchedule, user.PermissionPayrollWrite)).Methods(http.MethodDelete)
+	subRouter.Handle("/payroll_runs", auth.Require(ctrl.GetAllRuns, user.PermissionPayrollRead)).Methods(http.MethodGet)
+	subRouter.Handle("/payroll_runs/{run_id}", auth.Require(ctrl.GetRun, user.PermissionPayrollRead)).Methods(http.MethodGet)
+}
+
+func (ctrl *PayrollController) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	if claims.ClientId == 0 || claims.UserId == 0 {
+		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
+		return
+	}
+	schedule := &payroll.PayrollSchedule{}
+	err := web.UnMarshalJSON(r, schedule)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(schedule)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	schedule.ClientID = claims.ClientId
+	schedule.CreatedByUserId = claims.UserId
+
+	err = ctrl.PayrollService.WithActor(auth.ActorFromRequest(r)).CreateSchedule(schedule)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusCreated,
+		Message:    "Payroll Schedule Created Successfully",
+		Data:       schedule,
+	})
+}
+
+func (ctrl *PayrollController) GetAllSchedules(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	if claims.ClientId == 0 {
+		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
+		return
+	}
+	query, err := web.ParseListQuery(r, scheduleListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var schedules []payroll.PayrollSchedule
+	err = ctrl.PayrollService.WithActor(auth.ActorFromRequest(r)).GetAllSchedules(claims.ClientId, query, &schedules)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SetListHeaders(w, r, query)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Payroll Schedules Retrieved Successfully",
+		Data:       schedules,
+	})
+}
+
+func (ctrl *PayrollController) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	if claims.ClientId == 0 {
+		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
+		return
+	}
+	scheduleId, err := strconv.Atoi(mux.Vars(r)["schedule_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Schedule ID should be a int", http.StatusBadRequest)
+		return
+	}
+	schedule := &payroll.PayrollSchedule{}
+	err = web.UnMarshalJSON(r, schedule)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().StructPartial(schedule, "Frequency", "DayOfMonth", "Strategy")
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+
+	err = ctrl.PayrollService.WithActor(auth.ActorFromRequest(r)).UpdateSchedule(claims.ClientId, uint(scheduleId), schedule)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusAccepted,
+		Message:    "Payroll Schedule Updated Successfully",
+		Data:       schedule,
+	})
+}
+
+func (ctrl *PayrollController) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	if claims.ClientId == 0 {
+		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
+		return
+	}
+	scheduleId, err := strconv.Atoi(mux.Vars(r)["schedule_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Schedule ID should be a int", http.StatusBadRequest)
+		return
+	}
+
+	err = ctrl.PayrollService.WithActor(auth.ActorFromRequest(r)).DeleteSchedule(claims.ClientId, uint(scheduleId))
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Payroll Schedule Deleted Successfully",
+	})
+}
+
+func (ctrl *PayrollController) GetAllRuns(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	if claims.ClientId == 0 {
+		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
+		return
+	}
+	query, err := web.ParseListQuery(r, runListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var runs []payroll.PayrollRun
+	err = ctrl.PayrollService.WithActor(auth.ActorFromRequest(r)).GetAllRuns(claims.ClientId, query, &runs)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SetListHeaders(w, r, query)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Payroll Runs Retrieved Successfully",
+		Data:       runs,
+	})
+}
+
+func (ctrl *PayrollController) GetRun(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	if claims.ClientId == 0 {
+		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
+		return
+	}
+	runId, err := strconv.Atoi(mux.Vars(r)["run_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Run ID should be a int", http.StatusBadRequest)
+		return
+	}
+	run := payroll.PayrollRun{}
+	err = ctrl.PayrollService.WithActor(auth.ActorFromRequest(r)).GetRun(claims.ClientId, uint(runId), &run)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Payroll Run Retrieved Successfully",
+		Data:       run,
+	})
+}
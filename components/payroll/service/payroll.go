@@ -0,0 +1,397 @@
+package service
+
+import (
+	clientService "bankManagement/components/client/service"
+	"bankManagement/constants"
+	"bankManagement/models/audit"
+	"bankManagement/models/client"
+	"bankManagement/models/employee"
+	"bankManagement/models/payroll"
+	"bankManagement/models/salaryDisbursement"
+	"bankManagement/repository"
+	"bankManagement/utils/log"
+	"bankManagement/utils/money"
+	"bankManagement/utils/web"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type PayrollService struct {
+	DB            *gorm.DB
+	repository    repository.Repository
+	log           log.WebLogger
+	clientService *clientService.ClientService
+}
+
+func NewPayrollService(
+	DB *gorm.DB,
+	repository repository.Repository,
+	log log.WebLogger,
+	clientService *clientService.ClientService,
+) *PayrollService {
+	return &PayrollService{
+		DB:            DB,
+		repository:    repository,
+		log:           log,
+		clientService: clientService,
+	}
+}
+
+// WithActor returns a copy of the service whose changes the audit log attributes to actor
+// and whose log lines carry the actor's request ID.
+func (service *PayrollService) WithActor(actor *audit.Actor) *PayrollService {
+	scoped := *service
+	scoped.DB = repository.BindActor(service.DB, actor)
+	scoped.log = service.log.WithFields(log.Fields{constants.RequestIDKey: actor.RequestId})
+	return &scoped
+}
+
+func (service *PayrollService) CreateSchedule(schedule *payroll.PayrollSchedule) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	tempClient := client.Client{}
+	err := service.repository.GetByID(uow, &tempClient, schedule.ClientID)
+	if err != nil || tempClient.ID == 0 {
+		return errors.New("Client Not found")
+	}
+	if schedule.Frequency == payroll.FrequencyMonthly && schedule.DayOfMonth == 0 {
+		schedule.DayOfMonth = schedule.StartDate.Day()
+	}
+	schedule.IsActive = true
+	// counted from today when the start date is past, the missed periods are not paid
+	from := time.Now()
+	if schedule.StartDate.After(from) {
+		from = schedule.StartDate
+	}
+	schedule.NextRunAt = firstRunAt(schedule, from)
+	err = service.repository.Add(uow, schedule)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+func (service *PayrollService) GetAllSchedules(clientId uint, query *web.ListQuery, schedules *[]payroll.PayrollSchedule) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	queryProcessors := []repository.QueryProcessor{service.repository.Filter("client_id=?", clientId)}
+	err := service.repository.GetAll(uow, schedules, append(queryProcessors, query.QueryProcessors(service.repository)...)...)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// UpdateSchedule changes the cadence or pauses/resumes a schedule. The next run is
+// recomputed from today, periods already paid stay protected by their PayrollRun.
+func (service *PayrollService) UpdateSchedule(clientId uint, scheduleId uint, updated *payroll.PayrollSchedule) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	schedule := payroll.PayrollSchedule{}
+	err := service.repository.GetByIDForUpdate(uow, &schedule, scheduleId)
+	if err != nil || schedule.ClientID != clientId {
+		return errors.New("Payroll Schedule Not found")
+	}
+	schedule.Frequency = updated.Frequency
+	schedule.DayOfMonth = updated.DayOfMonth
+	if updated.Strategy != "" {
+		schedule.Strategy = updated.Strategy
+	}
+	if schedule.Frequency == payroll.FrequencyMonthly && schedule.DayOfMonth == 0 {
+		schedule.DayOfMonth = schedule.StartDate.Day()
+	}
+	schedule.IsActive = updated.IsActive
+	from := time.Now()
+	if schedule.StartDate.After(from) {
+		from = schedule.StartDate
+	}
+	schedule.NextRunAt = firstRunAt(&schedule, from)
+	err = service.repository.Update(uow, &schedule)
+	if err != nil {
+		return err
+	}
+	*updated = schedule
+	uow.Commit()
+	return nil
+}
+
+func (service *PayrollService) DeleteSchedule(clientId uint, scheduleId uint) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	schedule := payroll.PayrollSchedule{}
+	err := service.repository.GetByID(uow, &schedule, scheduleId)
+	if err != nil || schedule.ClientID != clientId {
+		return errors.New("Payroll Schedule Not found")
+	}
+	err = service.repository.DeleteById(uow, &payroll.PayrollSchedule{}, scheduleId)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+func (service *PayrollService) GetAllRuns(clientId uint, query *web.ListQuery, runs *[]payroll.PayrollRun) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	queryProcessors := []repository.QueryProcessor{service.repository.Filter("client_id=?", clientId)}
+	err := service.repository.GetAll(uow, runs, append(queryProcessors, query.QueryProcessors(service.repository)...)...)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+func (service *PayrollService) GetRun(clientId uint, runId uint, run *payroll.PayrollRun) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	var runs []payroll.PayrollRun
+	err := service.repository.GetAll(uow, &runs,
+		service.repository.Filter("id=? AND client_id=?", runId, clientId),
+		service.repository.Preload("Disbursements"),
+	)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return errors.New("Payroll Run Not found")
+	}
+	*run = runs[0]
+	uow.Commit()
+	return nil
+}
+
+// RunDueSchedules executes every active schedule whose next run is due. A schedule that is
+// several periods behind catches up one period per call.
+func (service *PayrollService) RunDueSchedules(now time.Time) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	var dueSchedules []payroll.PayrollSchedule
+	err := service.repository.GetAll(uow, &dueSchedules,
+		service.repository.Filter("is_active = ? AND next_run_at <= ?", true, now),
+	)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+
+	for _, schedule := range dueSchedules {
+		if err := service.runSchedule(schedule.ID, now); err != nil {
+			service.log.Error(fmt.Sprintf("payroll schedule %d: %v", schedule.ID, err))
+		}
+	}
+	return nil
+}
+
+func (service *PayrollService) runSchedule(scheduleId uint, now time.Time) error {
+	runErr, err := service.executeRun(scheduleId, now)
+	if err != nil {
+		return err
+	}
+	if runErr != nil {
+		return service.recordFailedRun(scheduleId, now, runErr)
+	}
+	return nil
+}
+
+// executeRun submits the salary batch of the due period of a schedule in one unit of work; the
+// bank pays it on approval. It returns runErr when the submission itself failed, which is
+// recorded as a failed run rather than retried.
+func (service *PayrollService) executeRun(scheduleId uint, now time.Time) (runErr error, err error) {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+
+	// the lock makes concurrent schedulers (several app instances) take turns
+	schedule, run, err := service.claimPeriod(uow, scheduleId, now)
+	if err != nil || run == nil {
+		return nil, err
+	}
+	run.Status = payroll.RunStatusSubmitted
+	err = service.repository.Add(uow, run)
+	if err != nil {
+		return nil, err
+	}
+	report, runErr := service.clientService.SubmitSalaryBatch(uow, schedule.ClientID, schedule.CreatedByUserId, run.ID,
+		salaryDisbursement.DisburseSalaryDTO{Strategy: schedule.Strategy})
+	if runErr != nil {
+		return runErr, nil
+	}
+	run.BatchID = report.Batch.ID
+	run.TotalAmount = report.Batch.TotalAmount
+	err = service.repository.Update(uow, run)
+	if err != nil {
+		return nil, err
+	}
+	err = service.advance(uow, schedule)
+	if err != nil {
+		return nil, err
+	}
+	uow.Commit()
+	return nil, nil
+}
+
+// recordFailedRun stores the failed run with a rejected disbursement per employee and moves
+// the schedule on, the period can still be submitted manually through disburse_salary.
+func (service *PayrollService) recordFailedRun(scheduleId uint, now time.Time, runErr error) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+
+	schedule, run, err := service.claimPeriod(uow, scheduleId, now)
+	if err != nil || run == nil {
+		return err
+	}
+	run.Status = payroll.RunStatusFailed
+	run.FailureReason = runErr.Error()
+	err = service.repository.Add(uow, run)
+	if err != nil {
+		return err
+	}
+	var allEmployees []employee.Employee
+	err = service.repository.GetAll(uow, &allEmployees, service.repository.Filter("client_id=?", schedule.ClientID))
+	if err != nil {
+		return err
+	}
+	for _, emp := range allEmployees {
+		err = service.repository.Add(uow, &salaryDisbursement.SalaryDisbursement{
+			ClientID:        schedule.ClientID,
+			EmpID:           emp.ID,
+			SalaryAmount:    emp.SalaryAmount,
+			Status:          salaryDisbursement.DisbursementStatusRejected,
+			CreatedByUserId: schedule.CreatedByUserId,
+			PayrollRunID:    run.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err = service.advance(uow, schedule)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	service.log.Warning(fmt.Sprintf("payroll run for schedule %d period %s failed: %v", scheduleId, run.PeriodKey, runErr))
+	return nil
+}
+
+// claimPeriod locks the schedule and returns the run to create for its due period, or a nil
+// run when there is nothing to do: the schedule was paused, moved on by another instance, or
+// the period already has a run, in which case the schedule is only advanced.
+func (service *PayrollService) claimPeriod(uow *repository.UOW, scheduleId uint, now time.Time) (*payroll.PayrollSchedule, *payroll.PayrollRun, error) {
+	schedule := &payroll.PayrollSchedule{}
+	err := service.repository.GetByIDForUpdate(uow, schedule, scheduleId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !schedule.IsActive || schedule.NextRunAt.After(now) {
+		return nil, nil, nil
+	}
+	periodKey, periodEnd := period(schedule, schedule.NextRunAt)
+	existing := payroll.PayrollRun{}
+	err = service.repository.GetFirstWhere(uow, &existing, "schedule_id = ? AND period_key = ?", schedule.ID, periodKey)
+	if err == nil {
+		if err := service.advance(uow, schedule); err != nil {
+			return nil, nil, err
+		}
+		uow.Commit()
+		return nil, nil, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, nil, err
+	}
+	tempClient := client.Client{}
+	err = service.repository.GetByID(uow, &tempClient, schedule.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return schedule, &payroll.PayrollRun{
+		ScheduleID:  schedule.ID,
+		ClientID:    schedule.ClientID,
+		PeriodKey:   periodKey,
+		PeriodStart: schedule.NextRunAt,
+		PeriodEnd:   periodEnd,
+		TotalAmount: money.Zero(tempClient.Currency),
+	}, nil
+}
+
+func (service *PayrollService) advance(uow *repository.UOW, schedule *payroll.PayrollSchedule) error {
+	schedule.NextRunAt = nextRunAfter(schedule, schedule.NextRunAt)
+	return service.repository.Update(uow, schedule)
+}
+
+// ---------------------------- Calendar ----------------------------------
+
+// firstRunAt is the first run date of the schedule on or after from.
+func firstRunAt(schedule *payroll.PayrollSchedule, from time.Time) time.Time {
+	from = startOfDay(from)
+	if schedule.Frequency == payroll.FrequencyMonthly {
+		candidate := monthlyDate(from.Year(), from.Month(), schedule.DayOfMonth, from.Location())
+		if candidate.Before(from) {
+			candidate = monthlyDate(from.Year(), from.Month()+1, schedule.DayOfMonth, from.Location())
+		}
+		return candidate
+	}
+	candidate := startOfDay(schedule.StartDate)
+	for candidate.Before(from) {
+		candidate = nextRunAfter(schedule, candidate)
+	}
+	return candidate
+}
+
+func nextRunAfter(schedule *payroll.PayrollSchedule, current time.Time) time.Time {
+	switch schedule.Frequency {
+	case payroll.FrequencyMonthly:
+		return monthlyDate(current.Year(), current.Month()+1, schedule.DayOfMonth, current.Location())
+	case payroll.FrequencyBiweekly:
+		return current.AddDate(0, 0, 14)
+	default:
+		return current.AddDate(0, 0, 7)
+	}
+}
+
+// period names the pay period starting at start; monthly periods are keyed by month so a
+// changed DayOfMonth can never pay the same month twice.
+func period(schedule *payroll.PayrollSchedule, start time.Time) (string, time.Time) {
+	end := nextRunAfter(schedule, start)
+	if schedule.Frequency == payroll.FrequencyMonthly {
+		return start.Format("2006-01"), end
+	}
+	return start.Format("2006-01-02"), end
+}
+
+// monthlyDate clamps day to the length of the month, so day 31 runs on Feb 28/29.
+func monthlyDate(year int, month time.Month, day int, location *time.Location) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, location).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, location)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// ---------------------------- Scheduler job -----------------------------
+
+// PayrollJob plugs the payroll runs into the app scheduler.
+type PayrollJob struct {
+	service *PayrollService
+}
+
+func NewPayrollJob(service *PayrollService) *PayrollJob {
+	return &PayrollJob{service: service}
+}
+
+func (job *PayrollJob) Name() string {
+	return "payroll"
+}
+
+func (job *PayrollJob) Run(now time.Time) error {
+	return job.service.RunDueSchedules(now)
+}
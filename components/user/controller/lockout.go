@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"bankManagement/constants"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/user"
+	"bankManagement/utils/encrypt"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/web"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (controller *UserController) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	userId, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "User ID should be a int", http.StatusBadRequest)
+		return
+	}
+	err = controller.UserService.WithActor(auth.ActorFromRequest(r)).UnlockUser(uint(userId), claims.UserId)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "User Unlocked Successfully",
+	})
+}
+
+func (controller *UserController) GetLockoutEvents(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "User ID should be a int", http.StatusBadRequest)
+		return
+	}
+	var events []user.UserLockoutEvent
+	err = controller.UserService.WithActor(auth.ActorFromRequest(r)).GetLockoutEvents(uint(userId), &events)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Lockouts Retrieved",
+		Data:       events,
+	})
+}
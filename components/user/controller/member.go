@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"bankManagement/constants"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/user"
+	"bankManagement/utils/encrypt"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/web"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+var memberListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "username": "username", "name": "name", "created_at": "created_at"},
+	FilterFields: map[string]string{"role_id": "role_id", "email": "email"},
+}
+
+// memberTenant reads the bank or client the members belong to from the path, the route
+// middlewares already checked it is the caller's.
+func memberTenant(r *http.Request) (string, uint, error) {
+	if bankId, ok := mux.Vars(r)["bank_id"]; ok {
+		id, err := strconv.Atoi(bankId)
+		if err != nil {
+			return "", 0, errors.New("Bank ID should be a int")
+		}
+		return user.RoleScopeBank, uint(id), nil
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["client_id"])
+	if err != nil {
+		return "", 0, errors.New("Client ID should be a int")
+	}
+	return user.RoleScopeClient, uint(id), nil
+}
+
+func (controller *UserController) InviteMember(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	scope, tenantId, err := memberTenant(r)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	invite := &user.InviteUserDTO{}
+	err = web.UnMarshalJSON(r, invite)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(invite)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	member, err := controller.UserService.WithActor(auth.ActorFromRequest(r)).InviteMember(scope, tenantId, claims.UserId, invite)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusCreated,
+		Message:    "User Invited Successfully",
+		Data:       member,
+	})
+}
+
+func (controller *UserController) GetMembers(w http.ResponseWriter, r *http.Request) {
+	scope, tenantId, err := memberTenant(r)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query, err := web.ParseListQuery(r, memberListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var members []user.MemberDTO
+	err = controller.UserService.WithActor(auth.ActorFromRequest(r)).GetMembers(scope, tenantId, query, &members)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	web.SetListHeaders(w, r, query)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Users Retrieved",
+		Data:       members,
+	})
+}
+
+func (controller *UserController) DeactivateMember(w http.ResponseWriter, r *http.Request) {
+	controller.setMemberActive(w, r, false)
+}
+
+func (controller *UserController) ActivateMember(w http.ResponseWriter, r *http.Request) {
+	controller.setMemberActive(w, r, true)
+}
+
+func (controller *UserController) setMemberActive(w http.ResponseWriter, r *http.Request, isActive bool) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	scope, tenantId, err := memberTenant(r)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	userId, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "User ID should be a int", http.StatusBadRequest)
+		return
+	}
+	err = controller.UserService.WithActor(auth.ActorFromRequest(r)).SetMemberActive(scope, tenantId, uint(userId), claims.UserId, isActive)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	message := "User Deactivated Successfully"
+	if isActive {
+		message = "User Activated Successfully"
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    message,
+	})
+}
+
+func (controller *UserController) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	scope, tenantId, err := memberTenant(r)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	userId, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "User ID should be a int", http.StatusBadRequest)
+		return
+	}
+	err = controller.UserService.WithActor(auth.ActorFromRequest(r)).RemoveMember(scope, tenantId, uint(userId), claims.UserId)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "User Removed Successfully",
+	})
+}
+
+func (controller *UserController) ResendInvitation(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	scope, tenantId, err := memberTenant(r)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	userId, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "User ID should be a int", http.StatusBadRequest)
+		return
+	}
+	err = controller.UserService.WithActor(auth.ActorFromRequest(r)).ResendInvitation(scope, tenantId, uint(userId), claims.UserId)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Invitation Sent",
+	})
+}
+
+// AcceptInvitation is public: the emailed token proves who the caller is.
+func (controller *UserController) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	accept := &user.AcceptInvitationDTO{}
+	err := web.UnMarshalJSON(r, accept)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(accept)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	err = controller.UserService.WithActor(auth.ActorFromRequest(r)).AcceptInvitation(accept)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Password set, you can now log in",
+	})
+}
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/user"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/web"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+var roleListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "role_name": "role_name", "created_at": "created_at"},
+	FilterFields: map[string]string{"scope": "scope", "is_system": "is_system"},
+}
+
+// GetPermissions lists the permission registry custom roles are built from.
+func (controller *UserController) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Permissions Retrieved",
+		Data:       user.PermissionRegistry,
+	})
+}
+
+func (controller *UserController) GetAllRoles(w http.ResponseWriter, r *http.Request) {
+	query, err := web.ParseListQuery(r, roleListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var roles []user.RoleDTO
+	err = controller.UserService.WithActor(auth.ActorFromRequest(r)).GetAllRoles(query, &roles)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	web.SetListHeaders(w, r, query)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Roles Retrieved",
+		Data:       roles,
+	})
+}
+
+func (controller *UserController) GetRoleByID(w http.ResponseWriter, r *http.Request) {
+	roleId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Role ID should be a int", http.StatusBadRequest)
+		return
+	}
+	role, err := controller.UserService.WithActor(auth.ActorFromRequest(r)).GetRoleByID(uint(roleId))
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Role Retrieved",
+		Data:       role,
+	})
+}
+
+func (controller *UserController) CreateRole(w http.ResponseWriter, r *http.Request) {
+	role := &user.RoleDTO{}
+	err := web.UnMarshalJSON(r, role)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(role)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	err = controller.UserService.WithActor(auth.ActorFromRequest(r)).CreateRole(role)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusCreated,
+		Message:    "Role Created Successfully",
+		Data:       role,
+	})
+}
+
+func (controller *UserController) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	roleId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Role ID should be a int", http.StatusBadRequest)
+		return
+	}
+	role := &user.RoleDTO{}
+	err = web.UnMarshalJSON(r, role)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(role)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	err = controller.UserService.WithActor(auth.ActorFromRequest(r)).UpdateRole(uint(roleId), role)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Role Updated Successfully",
+		Data:       role,
+	})
+}
+
+func (controller *UserController) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	roleId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Role ID should be a int", http.StatusBadRequest)
+		return
+	}
+	err = controller.UserService.WithActor(auth.ActorFromRequest(r)).DeleteRole(uint(roleId))
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Role Deleted Successfully",
+	})
+}
+
+func (controller *UserController) AssignRole(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "User ID should be a int", http.StatusBadRequest)
+		return
+	}
+	assignment := &user.AssignRoleDTO{}
+	err = web.UnMarshalJSON(r, assignment)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return
+	}
+	err = web.GetValidator().Struct(assignment)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return
+	}
+	err = controller.UserService.WithActor(auth.ActorFromRequest(r)).AssignRole(uint(userId), assignment.RoleID)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Role Assigned Successfully",
+	})
+}
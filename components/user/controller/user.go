@@ -2,6 +2,9 @@ package controller
 
 import (
 	"bankManagement/components/user/service"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/user"
+	"bankManagement/utils/encrypt"
 	"bankManagement/utils/log"
 	"encoding/json"
 	"net/http"
@@ -25,6 +28,44 @@ func NewUserController(UserServcice *service.UserService, log log.WebLogger) *Us
 func (controller *UserController) RegisterRoutes(router *mux.Router) {
 	userRouter := router.PathPrefix("/users").Subrouter()
 	userRouter.HandleFunc("/createSuperAdmin", controller.CreateSuperAdmin).Methods("POST")
+
+	userRoleRouter := router.NewRoute().Subrouter()
+	userRoleRouter.Use(auth.AuthenticationMiddleware)
+	userRoleRouter.Handle("/users/{user_id}/role", auth.Require(controller.AssignRole, user.PermissionRoleWrite)).Methods(http.MethodPut)
+	userRoleRouter.Handle("/users/{user_id}/unlock", auth.Require(controller.UnlockUser, user.PermissionUserUnlock)).Methods(http.MethodPost)
+	userRoleRouter.Handle("/users/{user_id}/lockouts", auth.Require(controller.GetLockoutEvents, user.PermissionUserUnlock)).Methods(http.MethodGet)
+
+	roleRouter := router.PathPrefix("/roles").Subrouter()
+	roleRouter.Use(auth.AuthenticationMiddleware)
+	roleRouter.Handle("/", auth.Require(controller.GetAllRoles, user.PermissionRoleRead)).Methods(http.MethodGet)
+	roleRouter.Handle("/", auth.Require(controller.CreateRole, user.PermissionRoleWrite)).Methods(http.MethodPost)
+	roleRouter.Handle("/{id}", auth.Require(controller.GetRoleByID, user.PermissionRoleRead)).Methods(http.MethodGet)
+	roleRouter.Handle("/{id}", auth.Require(controller.UpdateRole, user.PermissionRoleWrite)).Methods(http.MethodPut)
+	roleRouter.Handle("/{id}", auth.Require(controller.DeleteRole, user.PermissionRoleWrite)).Methods(http.MethodDelete)
+
+	bankMemberRouter := router.PathPrefix("/banks/{bank_id}/users").Subrouter()
+	bankMemberRouter.Use(auth.AuthenticationMiddleware, auth.ValidateBankPermissionsMiddleware)
+	controller.registerMemberRoutes(bankMemberRouter, user.PermissionBankUserRead, user.PermissionBankUserWrite)
+
+	clientMemberRouter := router.PathPrefix("/clients/{client_id}/users").Subrouter()
+	clientMemberRouter.Use(auth.AuthenticationMiddleware, auth.ValidateClientPermissionsMiddleware)
+	controller.registerMemberRoutes(clientMemberRouter, user.PermissionClientUserRead, user.PermissionClientUserWrite)
+
+	router.HandleFunc("/invitations/accept", controller.AcceptInvitation).Methods(http.MethodPost)
+
+	permissionRouter := router.PathPrefix("/permissions").Subrouter()
+	permissionRouter.Use(auth.AuthenticationMiddleware)
+	permissionRouter.Handle("/", auth.Require(controller.GetPermissions, user.PermissionRoleRead)).Methods(http.MethodGet)
+}
+
+// registerMemberRoutes serves the users of a bank or of a client, the same way.
+func (controller *UserController) registerMemberRoutes(memberRouter *mux.Router, readPermission string, writePermission string) {
+	memberRouter.Handle("/", auth.Require(controller.GetMembers, readPermission)).Methods(http.MethodGet)
+	memberRouter.Handle("/", auth.Require(controller.InviteMember, writePermission)).Methods(http.MethodPost)
+	memberRouter.Handle("/{user_id}/deactivate", auth.Require(controller.DeactivateMember, writePermission)).Methods(http.MethodPost)
+	memberRouter.Handle("/{user_id}/activate", auth.Require(controller.ActivateMember, writePermission)).Methods(http.MethodPost)
+	memberRouter.Handle("/{user_id}/invitation", auth.Require(controller.ResendInvitation, writePermission)).Methods(http.MethodPost)
+	memberRouter.Handle("/{user_id}", auth.Require(controller.RemoveMember, writePermission)).Methods(http.MethodDelete)
 }
 
 func (controller *UserController) CreateSuperAdmin(w http.ResponseWriter, r *http.Request) {
@@ -44,9 +85,13 @@ func (controller *UserController) CreateSuperAdmin(w http.ResponseWriter, r *htt
 		http.Error(w, "All fields are required", http.StatusBadRequest)
 		return
 	}
+	if err := encrypt.ValidatePassword(requestData.Password, requestData.Username); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// service call to create a SuperAdmin
-	err := controller.UserService.CreateSuperAdmin(requestData.Username, requestData.Password, requestData.Name, requestData.Email)
+	err := controller.UserService.WithActor(auth.ActorFromRequest(r)).CreateSuperAdmin(requestData.Username, requestData.Password, requestData.Name, requestData.Email)
 	if err != nil {
 		if err.Error() == "SuperAdmin already exists" {
 			http.Error(w, "Only one SuperAdmin can exist in the system", http.StatusConflict)
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"bankManagement/models/user"
+	"bankManagement/repository"
+	"errors"
+	"time"
+)
+
+// UnlockUser lets a user locked by failed logins log in again, with a fresh failure count.
+func (s *UserService) UnlockUser(userId uint, unlockedByUserId uint) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	lockedUser := user.User{}
+	if err := s.repository.GetByIDForUpdate(uow, &lockedUser, userId); err != nil {
+		return errors.New("User not found")
+	}
+	if lockedUser.LockedAt == nil {
+		return errors.New("User is not locked")
+	}
+	lockedUser.LockedAt = nil
+	if err := s.repository.Update(uow, &lockedUser); err != nil {
+		return err
+	}
+	now := time.Now()
+	var events []user.UserLockoutEvent
+	if err := s.repository.GetAll(uow, &events, s.repository.Filter("user_id = ? AND unlocked_at IS NULL", userId)); err != nil {
+		return err
+	}
+	for i := range events {
+		events[i].UnlockedAt = &now
+		events[i].UnlockedByUserId = unlockedByUserId
+		if err := s.repository.Update(uow, &events[i]); err != nil {
+			return err
+		}
+	}
+	var throttles []user.LoginThrottle
+	if err := s.repository.GetAll(uow, &throttles, s.repository.Filter("throttle_key = ?", user.UsernameThrottleKey(lockedUser.Username))); err != nil {
+		return err
+	}
+	for i := range throttles {
+		throttles[i].FailedCount = 0
+		throttles[i].BlockedUntil = nil
+		if err := s.repository.Update(uow, &throttles[i]); err != nil {
+			return err
+		}
+	}
+	uow.Commit()
+	return nil
+}
+
+// GetLockoutEvents lists when the user was locked out, latest first.
+func (s *UserService) GetLockoutEvents(userId uint, events *[]user.UserLockoutEvent) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	if err := s.repository.GetAll(uow, events, s.repository.Filter("user_id = ?", userId), s.repository.Order("locked_at desc")); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
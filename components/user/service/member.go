@@ -0,0 +1,513 @@
+package service
+
+import (
+	"bankManagement/constants"
+	"bankManagement/models/bank"
+	"bankManagement/models/client"
+	"bankManagement/models/notification"
+	"bankManagement/models/user"
+	"bankManagement/repository"
+	"bankManagement/utils/encrypt"
+	"bankManagement/utils/web"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/////////////  Bank and Client Member Functions  //////////// Service /////
+
+// A bank or a client has several users (members). The scope (user.RoleScopeBank or
+// user.RoleScopeClient) tells whether tenantId is a bank or a client.
+
+// InviteMember adds a user to the bank or client. A new email gets an inactive user and an
+// emailed invitation to set the password; a user of the same scope who already exists, e.g.
+// operating for another bank, only gets the membership.
+func (s *UserService) InviteMember(scope string, tenantId uint, invitedByUserId uint, invite *user.InviteUserDTO) (*user.MemberDTO, error) {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	tenantName, err := s.getTenantName(uow, scope, tenantId)
+	if err != nil {
+		return nil, err
+	}
+
+	userEntity := user.User{}
+	err = s.repository.GetFirstWhere(uow, &userEntity, "email = ?", strings.TrimSpace(invite.Email))
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	var role *user.Role
+	if err == nil {
+		role, err = s.getRole(uow, userEntity.RoleID)
+		if err != nil {
+			return nil, err
+		}
+		if role.Scope != scope {
+			return nil, fmt.Errorf("%s already belongs to a %s user", invite.Email, role.Scope)
+		}
+		if invite.RoleID != 0 && invite.RoleID != userEntity.RoleID {
+			return nil, fmt.Errorf("%s already has the role %s, change it through the role assignment", invite.Email, role.RoleName)
+		}
+		if _, err := s.getMembership(uow, scope, tenantId, userEntity.ID); err == nil {
+			return nil, fmt.Errorf("%s is already a user of this %s", invite.Email, scope)
+		}
+	} else {
+		role, err = s.inviteRole(uow, scope, invite.RoleID)
+		if err != nil {
+			return nil, err
+		}
+		userEntity, err = s.createInvitedUser(uow, invite, role.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := s.addMembership(uow, scope, tenantId, userEntity.ID, invitedByUserId); err != nil {
+		return nil, err
+	}
+
+	// users who never set a password (or were removed everywhere) need a new invitation
+	var token string
+	if !userEntity.IsActive {
+		token, err = s.issueInvitation(uow, userEntity.ID, invitedByUserId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	uow.Commit()
+
+	if token != "" {
+		go s.notifyInvitation(&userEntity, tenantName, token)
+	} else {
+		go s.notifications.Notify(notification.EventUserAccessGranted, notification.Recipient{UserID: userEntity.ID}, map[string]interface{}{
+			"TenantName": tenantName,
+		})
+	}
+	return &user.MemberDTO{
+		UserID:            userEntity.ID,
+		Username:          userEntity.Username,
+		Name:              userEntity.Name,
+		Email:             userEntity.Email,
+		RoleID:            role.ID,
+		RoleName:          role.RoleName,
+		IsActive:          true,
+		InvitationPending: token != "",
+	}, nil
+}
+
+func (s *UserService) GetMembers(scope string, tenantId uint, query *web.ListQuery, members *[]user.MemberDTO) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	if _, err := s.getTenantName(uow, scope, tenantId); err != nil {
+		return err
+	}
+	active, err := s.tenantMemberships(uow, scope, tenantId)
+	if err != nil {
+		return err
+	}
+	userIds := make([]uint, 0, len(active))
+	for userId := range active {
+		userIds = append(userIds, userId)
+	}
+
+	var users []user.User
+	processors := append([]repository.QueryProcessor{s.repository.Filter("id IN (?)", userIds)}, query.QueryProcessors(s.repository)...)
+	if err := s.repository.GetAll(uow, &users, processors...); err != nil {
+		return err
+	}
+	var roles []user.Role
+	if err := s.repository.GetAll(uow, &roles); err != nil {
+		return err
+	}
+	roleNames := make(map[uint]string)
+	for _, role := range roles {
+		roleNames[role.ID] = role.RoleName
+	}
+	var invitations []user.Invitation
+	if err := s.repository.GetAll(uow, &invitations, s.repository.Filter("user_id IN (?) AND accepted_at IS NULL", userIds)); err != nil {
+		return err
+	}
+	pending := make(map[uint]bool)
+	for _, invitation := range invitations {
+		pending[invitation.UserID] = true
+	}
+	uow.Commit()
+
+	*members = make([]user.MemberDTO, 0, len(users))
+	for _, userEntity := range users {
+		*members = append(*members, user.MemberDTO{
+			UserID:            userEntity.ID,
+			Username:          userEntity.Username,
+			Name:              userEntity.Name,
+			Email:             userEntity.Email,
+			RoleID:            userEntity.RoleID,
+			RoleName:          roleNames[userEntity.RoleID],
+			IsActive:          active[userEntity.ID],
+			InvitationPending: pending[userEntity.ID] && !userEntity.IsActive,
+		})
+	}
+	return nil
+}
+
+// SetMemberActive deactivates or reactivates a member. Deactivation closes the member's
+// sessions on this bank or client at once.
+func (s *UserService) SetMemberActive(scope string, tenantId uint, userId uint, actorUserId uint, isActive bool) error {
+	if userId == actorUserId && !isActive {
+		return errors.New("you cannot deactivate yourself")
+	}
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	membership, err := s.getMembership(uow, scope, tenantId, userId)
+	if err != nil {
+		return err
+	}
+	if membership.isActive() == isActive {
+		return nil
+	}
+	membership.setActive(isActive)
+	if err := s.repository.Update(uow, membership.row()); err != nil {
+		return err
+	}
+	if !isActive {
+		if err := s.closeMemberSessions(uow, scope, tenantId, userId); err != nil {
+			return err
+		}
+	}
+	uow.Commit()
+	return nil
+}
+
+// RemoveMember deletes the membership. A user left without any bank or client is
+// deactivated, and can be invited again later.
+func (s *UserService) RemoveMember(scope string, tenantId uint, userId uint, actorUserId uint) error {
+	if userId == actorUserId {
+		return errors.New("you cannot remove yourself")
+	}
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	membership, err := s.getMembership(uow, scope, tenantId, userId)
+	if err != nil {
+		return err
+	}
+	if err := s.repository.DeleteById(uow, membership.row(), userId); err != nil {
+		return err
+	}
+	if err := s.closeMemberSessions(uow, scope, tenantId, userId); err != nil {
+		return err
+	}
+	remaining, err := s.countMemberships(uow, scope, userId)
+	if err != nil {
+		return err
+	}
+	if remaining == 0 {
+		userEntity := user.User{}
+		if err := s.repository.GetByIDForUpdate(uow, &userEntity, userId); err != nil {
+			return err
+		}
+		userEntity.IsActive = false
+		if err := s.repository.Update(uow, &userEntity); err != nil {
+			return err
+		}
+		if err := s.revokePendingInvitations(uow, userId); err != nil {
+			return err
+		}
+	}
+	uow.Commit()
+	return nil
+}
+
+// ResendInvitation replaces a member's pending invitation with a new token.
+func (s *UserService) ResendInvitation(scope string, tenantId uint, userId uint, invitedByUserId uint) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	tenantName, err := s.getTenantName(uow, scope, tenantId)
+	if err != nil {
+		return err
+	}
+	if _, err := s.getMembership(uow, scope, tenantId, userId); err != nil {
+		return err
+	}
+	userEntity := user.User{}
+	if err := s.repository.GetByID(uow, &userEntity, userId); err != nil {
+		return err
+	}
+	if userEntity.IsActive {
+		return errors.New("user has already set a password")
+	}
+	token, err := s.issueInvitation(uow, userId, invitedByUserId)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	go s.notifyInvitation(&userEntity, tenantName, token)
+	return nil
+}
+
+// AcceptInvitation sets the password of an invited user and activates the account.
+func (s *UserService) AcceptInvitation(accept *user.AcceptInvitationDTO) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	var invitations []user.Invitation
+	err := s.repository.GetAll(uow, &invitations,
+		s.repository.Filter("token_hash = ?", encrypt.HashToken(accept.Token)),
+		s.repository.ForUpdate(),
+	)
+	if err != nil {
+		return err
+	}
+	if len(invitations) == 0 {
+		return errors.New("invalid invitation token")
+	}
+	invitation := invitations[0]
+	if invitation.AcceptedAt != nil {
+		return errors.New("invitation has already been accepted")
+	}
+	now := time.Now()
+	if now.After(invitation.ExpiresAt) {
+		return errors.New("invitation has expired, ask for a new one")
+	}
+
+	userEntity := user.User{}
+	if err := s.repository.GetByIDForUpdate(uow, &userEntity, invitation.UserID); err != nil {
+		return err
+	}
+	if err := encrypt.ValidatePassword(accept.Password, userEntity.Username); err != nil {
+		return err
+	}
+	userEntity.Password = encrypt.HashPassword(accept.Password)
+	userEntity.IsActive = true
+	if err := s.repository.Update(uow, &userEntity); err != nil {
+		return err
+	}
+	invitation.AcceptedAt = &now
+	if err := s.repository.Update(uow, &invitation); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// membership is a BankUser or a ClientUser row.
+type membership struct {
+	bankUser   *bank.BankUser
+	clientUser *client.ClientUser
+}
+
+func (m membership) isActive() bool {
+	if m.bankUser != nil {
+		return m.bankUser.IsActive
+	}
+	return m.clientUser.IsActive
+}
+
+func (m membership) setActive(isActive bool) {
+	if m.bankUser != nil {
+		m.bankUser.IsActive = isActive
+		return
+	}
+	m.clientUser.IsActive = isActive
+}
+
+func (m membership) row() interface{} {
+	if m.bankUser != nil {
+		return m.bankUser
+	}
+	return m.clientUser
+}
+
+func (s *UserService) getMembership(uow *repository.UOW, scope string, tenantId uint, userId uint) (membership, error) {
+	var found membership
+	var err error
+	if scope == user.RoleScopeBank {
+		found.bankUser = &bank.BankUser{}
+		err = s.repository.GetFirstWhere(uow, found.bankUser, "bank_id = ? AND user_id = ?", tenantId, userId)
+	} else {
+		found.clientUser = &client.ClientUser{}
+		err = s.repository.GetFirstWhere(uow, found.clientUser, "client_id = ? AND user_id = ?", tenantId, userId)
+	}
+	if gorm.IsRecordNotFoundError(err) {
+		return found, fmt.Errorf("user with ID %d is not a user of this %s", userId, scope)
+	}
+	return found, err
+}
+
+func (s *UserService) addMembership(uow *repository.UOW, scope string, tenantId uint, userId uint, invitedByUserId uint) error {
+	if scope == user.RoleScopeBank {
+		return s.repository.Add(uow, &bank.BankUser{BankID: tenantId, UserID: userId, IsActive: true, InvitedByUserId: invitedByUserId})
+	}
+	return s.repository.Add(uow, &client.ClientUser{ClientID: tenantId, UserID: userId, IsActive: true, InvitedByUserId: invitedByUserId})
+}
+
+// tenantMemberships maps the user ids of the bank's or client's members to their IsActive.
+func (s *UserService) tenantMemberships(uow *repository.UOW, scope string, tenantId uint) (map[uint]bool, error) {
+	active := make(map[uint]bool)
+	if scope == user.RoleScopeBank {
+		var bankUsers []bank.BankUser
+		if err := s.repository.GetAll(uow, &bankUsers, s.repository.Filter("bank_id = ?", tenantId)); err != nil {
+			return nil, err
+		}
+		for _, bankUser := range bankUsers {
+			active[bankUser.UserID] = bankUser.IsActive
+		}
+		return active, nil
+	}
+	var clientUsers []client.ClientUser
+	if err := s.repository.GetAll(uow, &clientUsers, s.repository.Filter("client_id = ?", tenantId)); err != nil {
+		return nil, err
+	}
+	for _, clientUser := range clientUsers {
+		active[clientUser.UserID] = clientUser.IsActive
+	}
+	return active, nil
+}
+
+func (s *UserService) countMemberships(uow *repository.UOW, scope string, userId uint) (int, error) {
+	if scope == user.RoleScopeBank {
+		var bankUsers []bank.BankUser
+		err := s.repository.GetAll(uow, &bankUsers, s.repository.Filter("user_id = ?", userId))
+		return len(bankUsers), err
+	}
+	var clientUsers []client.ClientUser
+	err := s.repository.GetAll(uow, &clientUsers, s.repository.Filter("user_id = ?", userId))
+	return len(clientUsers), err
+}
+
+func (s *UserService) getTenantName(uow *repository.UOW, scope string, tenantId uint) (string, error) {
+	if scope == user.RoleScopeBank {
+		bankEntity := bank.Bank{}
+		if err := s.repository.GetByID(uow, &bankEntity, tenantId); err != nil {
+			return "", fmt.Errorf("bank with ID %d not found", tenantId)
+		}
+		return bankEntity.BankName, nil
+	}
+	clientEntity := client.Client{}
+	if err := s.repository.GetByID(uow, &clientEntity, tenantId); err != nil {
+		return "", fmt.Errorf("client with ID %d not found", tenantId)
+	}
+	return clientEntity.ClientName, nil
+}
+
+// inviteRole defaults to the built-in role of the scope.
+func (s *UserService) inviteRole(uow *repository.UOW, scope string, roleId uint) (*user.Role, error) {
+	if roleId == 0 {
+		roleName := user.RoleBankUser
+		if scope == user.RoleScopeClient {
+			roleName = user.RoleClientUser
+		}
+		role := user.Role{}
+		if err := s.repository.GetFirstWhere(uow, &role, "role_name = ?", roleName); err != nil {
+			return nil, fmt.Errorf("%s role not found: %w", roleName, err)
+		}
+		return &role, nil
+	}
+	role, err := s.getRole(uow, roleId)
+	if err != nil {
+		return nil, err
+	}
+	if role.Scope != scope {
+		return nil, fmt.Errorf("role %s is %s scoped and cannot be given to a %s user", role.RoleName, role.Scope, scope)
+	}
+	return role, nil
+}
+
+// createInvitedUser creates the login inactive, with a random password nobody knows until
+// the invitation is accepted.
+func (s *UserService) createInvitedUser(uow *repository.UOW, invite *user.InviteUserDTO, roleId uint) (user.User, error) {
+	existing := user.User{}
+	err := s.repository.GetFirstWhere(uow, &existing, "username = ?", invite.Username)
+	if err == nil {
+		return existing, errors.New("username already exists")
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return existing, err
+	}
+	unusablePassword, _, err := encrypt.NewOpaqueToken()
+	if err != nil {
+		return existing, err
+	}
+	userEntity := user.User{
+		Username: strings.TrimSpace(invite.Username),
+		Password: encrypt.HashPassword(unusablePassword),
+		Name:     invite.Name,
+		Email:    strings.TrimSpace(invite.Email),
+		RoleID:   roleId,
+	}
+	if err := s.repository.Add(uow, &userEntity); err != nil {
+		return userEntity, fmt.Errorf("failed to create user: %w", err)
+	}
+	// IsActive defaults to true in the table, so it is switched off after the insert
+	userEntity.IsActive = false
+	if err := s.repository.Update(uow, &userEntity); err != nil {
+		return userEntity, err
+	}
+	return userEntity, nil
+}
+
+// issueInvitation revokes the user's pending invitations and returns a new token.
+func (s *UserService) issueInvitation(uow *repository.UOW, userId uint, invitedByUserId uint) (string, error) {
+	if err := s.revokePendingInvitations(uow, userId); err != nil {
+		return "", err
+	}
+	token, hash, err := encrypt.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.repository.Add(uow, &user.Invitation{
+		UserID:          userId,
+		TokenHash:       hash,
+		ExpiresAt:       time.Now().Add(constants.InvitationTTL),
+		InvitedByUserId: invitedByUserId,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *UserService) revokePendingInvitations(uow *repository.UOW, userId uint) error {
+	var pending []user.Invitation
+	if err := s.repository.GetAll(uow, &pending, s.repository.Filter("user_id = ? AND accepted_at IS NULL", userId)); err != nil {
+		return err
+	}
+	for _, invitation := range pending {
+		if err := s.repository.DeleteById(uow, &user.Invitation{}, invitation.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeMemberSessions logs the user out of the sessions opened for this bank or client.
+func (s *UserService) closeMemberSessions(uow *repository.UOW, scope string, tenantId uint, userId uint) error {
+	tenantColumn := "bank_id"
+	if scope == user.RoleScopeClient {
+		tenantColumn = "client_id"
+	}
+	var sessions []user.UserLoginInfo
+	err := s.repository.GetAll(uow, &sessions,
+		s.repository.Filter("user_id = ? AND is_active = ? AND "+tenantColumn+" = ?", userId, true, tenantId),
+		s.repository.ForUpdate(),
+	)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range sessions {
+		sessions[i].IsActive = false
+		sessions[i].LogoutTime = &now
+		if err := s.repository.Update(uow, &sessions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *UserService) notifyInvitation(invited *user.User, tenantName string, token string) error {
+	return s.notifications.Notify(notification.EventUserInvited, notification.Recipient{UserID: invited.ID}, map[string]interface{}{
+		"TenantName": tenantName,
+		"Username":   invited.Username,
+		"Token":      token,
+		"ValidFor":   constants.InvitationTTL,
+	})
+}
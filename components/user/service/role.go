@@ -0,0 +1,240 @@
+package service
+
+import (
+	"bankManagement/models/user"
+	"bankManagement/repository"
+	"bankManagement/utils/web"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+/////////////  Role Functions  //////////// Service /////
+
+func (s *UserService) GetAllRoles(query *web.ListQuery, roles *[]user.RoleDTO) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	var roleEntities []user.Role
+	processors := append([]repository.QueryProcessor{s.repository.Preload("Permissions")}, query.QueryProcessors(s.repository)...)
+	if err := s.repository.GetAll(uow, &roleEntities, processors...); err != nil {
+		return err
+	}
+	uow.Commit()
+	*roles = make([]user.RoleDTO, 0, len(roleEntities))
+	for i := range roleEntities {
+		*roles = append(*roles, toRoleDTO(&roleEntities[i]))
+	}
+	return nil
+}
+
+func (s *UserService) GetRoleByID(id uint) (*user.RoleDTO, error) {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	role, err := s.getRole(uow, id)
+	if err != nil {
+		return nil, err
+	}
+	uow.Commit()
+	roleDTO := toRoleDTO(role)
+	return &roleDTO, nil
+}
+
+// CreateRole adds a custom bank or client role, e.g. a read-only auditor.
+func (s *UserService) CreateRole(roleDTO *user.RoleDTO) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	permissions, err := validateRolePermissions(roleDTO.Scope, roleDTO.Permissions)
+	if err != nil {
+		return err
+	}
+	roleDTO.RoleName = strings.TrimSpace(roleDTO.RoleName)
+	if err := s.checkRoleNameFree(uow, roleDTO.RoleName, 0); err != nil {
+		return err
+	}
+	role := &user.Role{RoleName: roleDTO.RoleName, Scope: roleDTO.Scope, Description: roleDTO.Description}
+	if err := s.repository.Add(uow, role); err != nil {
+		return err
+	}
+	if err := s.grantPermissions(uow, role.ID, permissions); err != nil {
+		return err
+	}
+	uow.Commit()
+	roleDTO.ID, roleDTO.IsSystem, roleDTO.Permissions = role.ID, false, permissions
+	return nil
+}
+
+// UpdateRole renames a custom role and replaces its permissions, its scope is fixed.
+// Users holding the role get the new permissions on their next token refresh.
+func (s *UserService) UpdateRole(id uint, roleDTO *user.RoleDTO) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	role, err := s.getCustomRole(uow, id)
+	if err != nil {
+		return err
+	}
+	if roleDTO.Scope != role.Scope {
+		return fmt.Errorf("the scope of a role cannot be changed, role is %s scoped", role.Scope)
+	}
+	permissions, err := validateRolePermissions(role.Scope, roleDTO.Permissions)
+	if err != nil {
+		return err
+	}
+	roleDTO.RoleName = strings.TrimSpace(roleDTO.RoleName)
+	if err := s.checkRoleNameFree(uow, roleDTO.RoleName, role.ID); err != nil {
+		return err
+	}
+	role.RoleName, role.Description = roleDTO.RoleName, roleDTO.Description
+	for _, granted := range role.Permissions {
+		if err := s.repository.DeleteById(uow, &user.RolePermission{}, granted.ID); err != nil {
+			return err
+		}
+	}
+	role.Permissions = nil
+	if err := s.repository.Update(uow, role); err != nil {
+		return err
+	}
+	if err := s.grantPermissions(uow, role.ID, permissions); err != nil {
+		return err
+	}
+	uow.Commit()
+	roleDTO.ID, roleDTO.IsSystem, roleDTO.Permissions = role.ID, false, permissions
+	return nil
+}
+
+// DeleteRole removes a custom role that no user holds any more.
+func (s *UserService) DeleteRole(id uint) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	role, err := s.getCustomRole(uow, id)
+	if err != nil {
+		return err
+	}
+	holder := user.User{}
+	err = s.repository.GetFirstWhere(uow, &holder, "role_id = ?", role.ID)
+	if err == nil {
+		return errors.New("role is still assigned to users, assign them another role first")
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+	for _, granted := range role.Permissions {
+		if err := s.repository.DeleteById(uow, &user.RolePermission{}, granted.ID); err != nil {
+			return err
+		}
+	}
+	if err := s.repository.DeleteById(uow, &user.Role{}, role.ID); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// AssignRole moves a user to another role of the same scope, so a bank user stays bound
+// to its bank. The new permissions apply from the user's next token refresh.
+func (s *UserService) AssignRole(userId uint, roleId uint) error {
+	uow := repository.NewUnitOfWork(s.DB)
+	defer uow.RollBack()
+	userEntity := user.User{}
+	if err := s.repository.GetByIDForUpdate(uow, &userEntity, userId); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return fmt.Errorf("user with ID %d not found", userId)
+		}
+		return err
+	}
+	currentRole, err := s.getRole(uow, userEntity.RoleID)
+	if err != nil {
+		return err
+	}
+	newRole, err := s.getRole(uow, roleId)
+	if err != nil {
+		return err
+	}
+	if newRole.Scope != currentRole.Scope {
+		return fmt.Errorf("a %s user can only be given a %s scoped role", currentRole.Scope, currentRole.Scope)
+	}
+	userEntity.RoleID = newRole.ID
+	if err := s.repository.Update(uow, &userEntity); err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+func (s *UserService) getRole(uow *repository.UOW, id uint) (*user.Role, error) {
+	roles := []user.Role{}
+	if err := s.repository.GetAll(uow, &roles, s.repository.Filter("id = ?", id), s.repository.Preload("Permissions")); err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("role with ID %d not found", id)
+	}
+	return &roles[0], nil
+}
+
+// getCustomRole refuses the built-in roles, the seeder owns their permissions.
+func (s *UserService) getCustomRole(uow *repository.UOW, id uint) (*user.Role, error) {
+	role, err := s.getRole(uow, id)
+	if err != nil {
+		return nil, err
+	}
+	if role.IsSystem {
+		return nil, fmt.Errorf("%s is a built-in role and cannot be changed", role.RoleName)
+	}
+	return role, nil
+}
+
+func (s *UserService) checkRoleNameFree(uow *repository.UOW, roleName string, exceptId uint) error {
+	existing := user.Role{}
+	err := s.repository.GetFirstWhere(uow, &existing, "role_name = ? AND id <> ?", roleName, exceptId)
+	if err == nil {
+		return fmt.Errorf("role %s already exists", roleName)
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *UserService) grantPermissions(uow *repository.UOW, roleId uint, permissions []string) error {
+	for _, permission := range permissions {
+		if err := s.repository.Add(uow, &user.RolePermission{RoleID: roleId, Permission: permission}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateRolePermissions checks every permission is registered for the role's scope and
+// returns them sorted without duplicates.
+func validateRolePermissions(scope string, permissions []string) ([]string, error) {
+	var validated []string
+	for _, permission := range permissions {
+		permission = strings.TrimSpace(permission)
+		definition, ok := user.LookupPermission(permission)
+		if !ok {
+			return nil, fmt.Errorf("unknown permission %s", permission)
+		}
+		if definition.Scope != scope {
+			return nil, fmt.Errorf("permission %s is %s scoped and cannot be granted to a %s role", permission, definition.Scope, scope)
+		}
+		if !slices.Contains(validated, permission) {
+			validated = append(validated, permission)
+		}
+	}
+	slices.Sort(validated)
+	return validated, nil
+}
+
+func toRoleDTO(role *user.Role) user.RoleDTO {
+	return user.RoleDTO{
+		ID:          role.ID,
+		RoleName:    role.RoleName,
+		Scope:       role.Scope,
+		Description: role.Description,
+		IsSystem:    role.IsSystem,
+		Permissions: role.PermissionNames(),
+	}
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	notificationService "bankManagement/components/notification/service"
+	"bankManagement/constants"
+	"bankManagement/models/audit"
 	"bankManagement/models/user"
 	"bankManagement/repository"
 	"bankManagement/utils/encrypt"
@@ -11,23 +14,35 @@ import (
 )
 
 type UserService struct {
-	DB         *gorm.DB
-	repository repository.Repository
-	log        log.WebLogger
+	DB            *gorm.DB
+	repository    repository.Repository
+	log           log.WebLogger
+	notifications *notificationService.NotificationService
 }
 
 func NewUserService(
 	DB *gorm.DB,
 	repository repository.Repository,
 	log log.WebLogger,
+	notifications *notificationService.NotificationService,
 ) *UserService {
 	return &UserService{
-		DB:         DB,
-		repository: repository,
-		log:        log,
+		DB:            DB,
+		repository:    repository,
+		log:           log,
+		notifications: notifications,
 	}
 }
 
+// WithActor returns a copy of the service whose changes the audit log attributes to actor
+// and whose log lines carry the actor's request ID.
+func (s *UserService) WithActor(actor *audit.Actor) *UserService {
+	scoped := *s
+	scoped.DB = repository.BindActor(s.DB, actor)
+	scoped.log = s.log.WithFields(log.Fields{constants.RequestIDKey: actor.RequestId})
+	return &scoped
+}
+
 // CreateSuperAdmin function
 func (s *UserService) CreateSuperAdmin(username, password string, name, email string) error {
 	uow := repository.NewUnitOfWork(s.DB)
@@ -35,7 +50,7 @@ func (s *UserService) CreateSuperAdmin(username, password string, name, email st
 
 	// 1. Retrieve the SUPER_ADMIN role or create it if it doesn't exist   [ role table ]
 	var superAdminRole user.Role
-	if err := s.DB.Where("role_name = ?", "SUPER_ADMIN").First(&superAdminRole).Error; err != nil {
+	if err := s.DB.Where("role_name = ?", user.RoleSuperAdmin).First(&superAdminRole).Error; err != nil {
 		return fmt.Errorf("SUPER_ADMIN role not found. Plz check seeder should have executed from main.go): %w", err)
 	}
 
@@ -0,0 +1,279 @@
+package controller
+
+import (
+	"bankManagement/components/webhook/service"
+	"bankManagement/constants"
+	"bankManagement/middlewares/auth"
+	"bankManagement/models/user"
+	"bankManagement/models/webhook"
+	"bankManagement/utils/encrypt"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type WebhookController struct {
+	WebhookService *service.WebhookService
+	log            log.WebLogger
+}
+
+func NewWebhookController(
+	WebhookService *service.WebhookService,
+	log log.WebLogger,
+) *WebhookController {
+	return &WebhookController{
+		WebhookService: WebhookService,
+		log:            log,
+	}
+}
+
+var endpointListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "created_at": "created_at"},
+	FilterFields: map[string]string{"is_active": "is_active"},
+}
+
+var deliveryListOptions = web.ListOptions{
+	SortFields:   map[string]string{"id": "id", "created_at": "created_at", "next_attempt_at": "next_attempt_at"},
+	FilterFields: map[string]string{"status": "status", "event_type": "event_type", "event_id": "event_id"},
+	DefaultSort:  "-id",
+}
+
+func (ctrl *WebhookController) RegisterRoutes(router *mux.Router) {
+	subRouter := router.PathPrefix("/clients/{client_id}/webhooks").Subrouter()
+	subRouter.Use(auth.AuthenticationMiddleware, auth.ValidateClientPermissionsMiddleware)
+	subRouter.Handle("/", auth.Require(ctrl.CreateEndpoint, user.PermissionWebhookWrite)).Methods(http.MethodPost)
+	subRouter.Handle("/", auth.Require(ctrl.GetAllEndpoints, user.PermissionWebhookRead)).Methods(http.MethodGet)
+	subRouter.Handle("/{webhook_id}", auth.Require(ctrl.GetEndpoint, user.PermissionWebhookRead)).Methods(http.MethodGet)
+	subRouter.Handle("/{webhook_id}", auth.Require(ctrl.UpdateEndpoint, user.PermissionWebhookWrite)).Methods(http.MethodPut)
+	subRouter.Handle("/{webhook_id}", auth.Require(ctrl.DeleteEndpoint, user.PermissionWebhookWrite)).Methods(http.MethodDelete)
+	subRouter.Handle("/{webhook_id}/rotate_secret", auth.Require(ctrl.RotateSecret, user.PermissionWebhookWrite)).Methods(http.MethodPost)
+	subRouter.Handle("/{webhook_id}/deliveries", auth.Require(ctrl.GetDeliveries, user.PermissionWebhookRead)).Methods(http.MethodGet)
+	subRouter.Handle("/{webhook_id}/deliveries/{delivery_id}/redeliver", auth.Require(ctrl.Redeliver, user.PermissionWebhookWrite)).Methods(http.MethodPost)
+}
+
+// clientClaims returns the claims of a client user, or writes the error and returns nil.
+func clientClaims(w http.ResponseWriter, r *http.Request) *encrypt.Claims {
+	claims := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+	if claims.ClientId == 0 || claims.UserId == 0 {
+		errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
+		return nil
+	}
+	return claims
+}
+
+func webhookId(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["webhook_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Webhook ID should be a int", http.StatusBadRequest)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func readEndpointDTO(w http.ResponseWriter, r *http.Request) *webhook.WebhookEndpointDTO {
+	dto := &webhook.WebhookEndpointDTO{}
+	err := web.UnMarshalJSON(r, dto)
+	if err != nil {
+		errorsUtils.SendInvalidBodyError(w)
+		return nil
+	}
+	err = web.GetValidator().Struct(dto)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, web.GetValidationError(err), http.StatusBadRequest)
+		return nil
+	}
+	return dto
+}
+
+func (ctrl *WebhookController) CreateEndpoint(w http.ResponseWriter, r *http.Request) {
+	claims := clientClaims(w, r)
+	if claims == nil {
+		return
+	}
+	dto := readEndpointDTO(w, r)
+	if dto == nil {
+		return
+	}
+	created, err := ctrl.WebhookService.WithActor(auth.ActorFromRequest(r)).CreateEndpoint(claims.ClientId, claims.UserId, dto)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusCreated,
+		Message:    "Webhook Created Successfully, store the secret now: it is not shown again",
+		Data:       created,
+	})
+}
+
+func (ctrl *WebhookController) GetAllEndpoints(w http.ResponseWriter, r *http.Request) {
+	claims := clientClaims(w, r)
+	if claims == nil {
+		return
+	}
+	query, err := web.ParseListQuery(r, endpointListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var endpoints []webhook.WebhookEndpoint
+	err = ctrl.WebhookService.WithActor(auth.ActorFromRequest(r)).GetAllEndpoints(claims.ClientId, query, &endpoints)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SetListHeaders(w, r, query)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Webhooks Retrieved Successfully",
+		Data:       endpoints,
+	})
+}
+
+func (ctrl *WebhookController) GetEndpoint(w http.ResponseWriter, r *http.Request) {
+	claims := clientClaims(w, r)
+	if claims == nil {
+		return
+	}
+	endpointId, ok := webhookId(w, r)
+	if !ok {
+		return
+	}
+	var endpoint webhook.WebhookEndpoint
+	err := ctrl.WebhookService.WithActor(auth.ActorFromRequest(r)).GetEndpoint(claims.ClientId, endpointId, &endpoint)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Webhook Retrieved Successfully",
+		Data:       endpoint,
+	})
+}
+
+func (ctrl *WebhookController) UpdateEndpoint(w http.ResponseWriter, r *http.Request) {
+	claims := clientClaims(w, r)
+	if claims == nil {
+		return
+	}
+	endpointId, ok := webhookId(w, r)
+	if !ok {
+		return
+	}
+	dto := readEndpointDTO(w, r)
+	if dto == nil {
+		return
+	}
+	var endpoint webhook.WebhookEndpoint
+	err := ctrl.WebhookService.WithActor(auth.ActorFromRequest(r)).UpdateEndpoint(claims.ClientId, endpointId, dto, &endpoint)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusAccepted,
+		Message:    "Webhook Updated Successfully",
+		Data:       endpoint,
+	})
+}
+
+func (ctrl *WebhookController) DeleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	claims := clientClaims(w, r)
+	if claims == nil {
+		return
+	}
+	endpointId, ok := webhookId(w, r)
+	if !ok {
+		return
+	}
+	err := ctrl.WebhookService.WithActor(auth.ActorFromRequest(r)).DeleteEndpoint(claims.ClientId, endpointId)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Webhook Deleted Successfully",
+	})
+}
+
+func (ctrl *WebhookController) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	claims := clientClaims(w, r)
+	if claims == nil {
+		return
+	}
+	endpointId, ok := webhookId(w, r)
+	if !ok {
+		return
+	}
+	rotated, err := ctrl.WebhookService.WithActor(auth.ActorFromRequest(r)).RotateSecret(claims.ClientId, endpointId)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Webhook Secret Rotated, store the secret now: it is not shown again",
+		Data:       rotated,
+	})
+}
+
+func (ctrl *WebhookController) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	claims := clientClaims(w, r)
+	if claims == nil {
+		return
+	}
+	endpointId, ok := webhookId(w, r)
+	if !ok {
+		return
+	}
+	query, err := web.ParseListQuery(r, deliveryListOptions)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var deliveries []webhook.WebhookDelivery
+	err = ctrl.WebhookService.WithActor(auth.ActorFromRequest(r)).GetDeliveries(claims.ClientId, endpointId, query, &deliveries)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SetListHeaders(w, r, query)
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Webhook Deliveries Retrieved Successfully",
+		Data:       deliveries,
+	})
+}
+
+func (ctrl *WebhookController) Redeliver(w http.ResponseWriter, r *http.Request) {
+	claims := clientClaims(w, r)
+	if claims == nil {
+		return
+	}
+	endpointId, ok := webhookId(w, r)
+	if !ok {
+		return
+	}
+	deliveryId, err := strconv.Atoi(mux.Vars(r)["delivery_id"])
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, "Delivery ID should be a int", http.StatusBadRequest)
+		return
+	}
+	var delivery webhook.WebhookDelivery
+	err = ctrl.WebhookService.WithActor(auth.ActorFromRequest(r)).Redeliver(claims.ClientId, endpointId, uint(deliveryId), &delivery)
+	if err != nil {
+		errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	web.SendResponse(w, web.WebResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Webhook Delivery Queued",
+		Data:       delivery,
+	})
+}
@@ -0,0 +1,377 @@
+package service
+
+import (
+	outboxService "bankManagement/components/outbox/service"
+	"bankManagement/constants"
+	"bankManagement/models/outbox"
+	"bankManagement/models/webhook"
+	"bankManagement/repository"
+	"bankManagement/utils/log"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Subscribe queues the webhook deliveries of the payment and salary events once the change
+// they announce is committed.
+func (service *WebhookService) Subscribe(events *outboxService.OutboxService) {
+	events.Subscribe("webhooks", service.queueDeliveries,
+		outbox.EventPaymentApproved, outbox.EventPaymentRejected, outbox.EventSalaryDisbursed, outbox.EventSalaryBatchReviewed)
+}
+
+// recipient is a client to tell about an event, and the webhook event type it gets.
+type recipient struct {
+	clientId  uint
+	eventType string
+}
+
+// recipients maps an outbox event to the clients it concerns: an approved payment is
+// payment.approved for the sender and payment.received for the receiver.
+func recipients(event *outbox.OutboxEvent) ([]recipient, error) {
+	switch event.EventType {
+	case outbox.EventPaymentApproved, outbox.EventPaymentRejected:
+		var payload outbox.PaymentEvent
+		if err := event.Decode(&payload); err != nil {
+			return nil, err
+		}
+		if event.EventType == outbox.EventPaymentRejected {
+			return []recipient{{payload.SenderClientId, webhook.EventPaymentRejected}}, nil
+		}
+		return []recipient{
+			{payload.SenderClientId, webhook.EventPaymentApproved},
+			{payload.ReceiverClientId, webhook.EventPaymentReceived},
+		}, nil
+	case outbox.EventSalaryDisbursed, outbox.EventSalaryBatchReviewed:
+		var payload outbox.SalaryBatchEvent
+		if err := event.Decode(&payload); err != nil {
+			return nil, err
+		}
+		eventType := webhook.EventSalaryDisbursed
+		if event.EventType == outbox.EventSalaryBatchReviewed {
+			eventType = webhook.EventSalaryBatchReviewed
+		}
+		return []recipient{{payload.ClientId, eventType}}, nil
+	}
+	return nil, nil
+}
+
+// queueDeliveries adds a delivery for every active endpoint subscribed to the event. The
+// outbox may hand the same event over again, the deliveries already queued are kept.
+func (service *WebhookService) queueDeliveries(event *outbox.OutboxEvent) error {
+	targets, err := recipients(event)
+	if err != nil {
+		return err
+	}
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	queued := 0
+	for _, target := range targets {
+		if target.clientId == 0 {
+			continue
+		}
+		var endpoints []webhook.WebhookEndpoint
+		err = service.repository.GetAll(uow, &endpoints, service.repository.Filter("client_id = ? AND is_active = ?", target.clientId, true))
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(webhook.WebhookBody{
+			EventID:   event.ID,
+			Type:      target.eventType,
+			CreatedAt: event.CreatedAt,
+			Data:      json.RawMessage(event.Payload),
+		})
+		if err != nil {
+			return err
+		}
+		for _, endpoint := range endpoints {
+			if !endpoint.Subscribes(target.eventType) {
+				continue
+			}
+			var existing []webhook.WebhookDelivery
+			err = service.repository.GetAll(uow, &existing,
+				service.repository.Filter("endpoint_id = ? AND event_id = ? AND event_type = ?", endpoint.ID, event.ID, target.eventType))
+			if err != nil {
+				return err
+			}
+			if len(existing) > 0 {
+				continue
+			}
+			err = service.repository.Add(uow, &webhook.WebhookDelivery{
+				EndpointID:    endpoint.ID,
+				EventID:       event.ID,
+				EventType:     target.eventType,
+				Payload:       string(body),
+				Status:        webhook.DeliveryStatusPending,
+				NextAttemptAt: dueNow(),
+			})
+			if err != nil {
+				return err
+			}
+			queued++
+		}
+	}
+	if queued > 0 {
+		uow.AfterCommit(service.wake)
+	}
+	uow.Commit()
+	return nil
+}
+
+// wake has the dispatch goroutine send the due deliveries now rather than at the next tick.
+func (service *WebhookService) wake() {
+	dispatcher := service.dispatcher
+	service.startOnce.Do(func() {
+		go func() {
+			for range dispatcher.wakeUp {
+				if err := dispatcher.DispatchDue(time.Now()); err != nil {
+					dispatcher.log.Error("webhook dispatch failed:", err)
+				}
+			}
+		}()
+	})
+	select {
+	case service.wakeUp <- struct{}{}:
+	default:
+		// a dispatch is already due
+	}
+}
+
+// DispatchDue sends the pending deliveries whose attempt is due, oldest first, and those
+// whose dispatcher died while sending them.
+func (service *WebhookService) DispatchDue(now time.Time) error {
+	var lastId uint
+	for {
+		var due []webhook.WebhookDelivery
+		uow := repository.NewUnitOfWork(service.DB)
+		err := service.repository.GetAll(uow, &due,
+			service.repository.Filter("status IN (?) AND next_attempt_at <= ? AND id > ?",
+				[]string{webhook.DeliveryStatusPending, webhook.DeliveryStatusSending}, now, lastId),
+			service.repository.Order("id asc"),
+			service.repository.Limit(constants.WebhookBatchSize),
+		)
+		uow.RollBack()
+		if err != nil {
+			return err
+		}
+		for _, delivery := range due {
+			lastId = delivery.ID
+			if err := service.send(delivery.ID, now); err != nil {
+				service.log.WithFields(log.Fields{"delivery_id": delivery.ID, "endpoint_id": delivery.EndpointID}).Error("webhook delivery failed:", err)
+			}
+		}
+		if len(due) < constants.WebhookBatchSize {
+			return nil
+		}
+	}
+}
+
+// send POSTs one delivery and records the outcome. No transaction is held open during the
+// POST: the delivery is claimed first, marked Sending until deliveryLease runs out so
+// concurrent dispatchers leave it alone, and the outcome is recorded afterwards.
+func (service *WebhookService) send(deliveryId uint, now time.Time) error {
+	delivery, endpoint, err := service.claim(deliveryId, now)
+	if err != nil || delivery == nil {
+		// gone, or sent by another dispatcher meanwhile
+		return err
+	}
+	attemptedAt := *delivery.LastAttemptAt
+	outcome := webhook.WebhookDelivery{Attempts: delivery.Attempts, NextAttemptAt: delivery.NextAttemptAt}
+	if endpoint == nil || !endpoint.IsActive {
+		outcome.Status = webhook.DeliveryStatusFailed
+		outcome.LastError = "webhook deleted or disabled"
+	} else {
+		outcome.ResponseStatus, outcome.ResponseBody, err = service.post(endpoint, delivery, attemptedAt)
+		switch {
+		case err == nil:
+			outcome.Status = webhook.DeliveryStatusDelivered
+			outcome.DeliveredAt = &attemptedAt
+		case delivery.Attempts >= constants.WebhookMaxAttempts:
+			outcome.Status = webhook.DeliveryStatusFailed
+			outcome.LastError = err.Error()
+			service.log.WithFields(log.Fields{"delivery_id": delivery.ID, "endpoint_id": endpoint.ID}).Warning("webhook delivery failed for good:", err)
+		default:
+			outcome.Status = webhook.DeliveryStatusPending
+			outcome.LastError = err.Error()
+			outcome.NextAttemptAt = now.Add(retryDelay(delivery.Attempts))
+		}
+	}
+	return service.recordOutcome(delivery.ID, &outcome)
+}
+
+// deliveryLease is how long a claimed delivery is left to its dispatcher, after which it is
+// due again: the dispatcher died during the POST.
+func deliveryLease() time.Duration {
+	return constants.WebhookTimeout + time.Minute
+}
+
+// claim marks the delivery Sending and counts the attempt, in a transaction of its own. It
+// returns no delivery when it is not due, and no endpoint when the endpoint is gone.
+func (service *WebhookService) claim(deliveryId uint, now time.Time) (*webhook.WebhookDelivery, *webhook.WebhookEndpoint, error) {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	var deliveries []webhook.WebhookDelivery
+	err := service.repository.GetAll(uow, &deliveries,
+		service.repository.Filter("id = ? AND status IN (?) AND next_attempt_at <= ?",
+			deliveryId, []string{webhook.DeliveryStatusPending, webhook.DeliveryStatusSending}, now),
+		service.repository.ForUpdate(),
+	)
+	if err != nil || len(deliveries) == 0 {
+		return nil, nil, err
+	}
+	delivery := deliveries[0]
+	attemptedAt := time.Now()
+	delivery.Status = webhook.DeliveryStatusSending
+	delivery.Attempts++
+	delivery.LastAttemptAt = &attemptedAt
+	delivery.NextAttemptAt = attemptedAt.Add(deliveryLease())
+	err = service.repository.Update(uow, &delivery)
+	if err != nil {
+		return nil, nil, err
+	}
+	var endpoint *webhook.WebhookEndpoint
+	found := webhook.WebhookEndpoint{}
+	if err := service.repository.GetByID(uow, &found, delivery.EndpointID); err == nil {
+		endpoint = &found
+	}
+	uow.Commit()
+	return &delivery, endpoint, nil
+}
+
+// recordOutcome stores how the attempt went, unless the delivery was claimed again
+// meanwhile (the lease ran out) or redelivered.
+func (service *WebhookService) recordOutcome(deliveryId uint, outcome *webhook.WebhookDelivery) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	delivery := webhook.WebhookDelivery{}
+	err := service.repository.GetByIDForUpdate(uow, &delivery, deliveryId)
+	if err != nil {
+		return err
+	}
+	if delivery.Status != webhook.DeliveryStatusSending || delivery.Attempts != outcome.Attempts {
+		return nil
+	}
+	delivery.Status = outcome.Status
+	delivery.NextAttemptAt = outcome.NextAttemptAt
+	delivery.ResponseStatus = outcome.ResponseStatus
+	delivery.ResponseBody = outcome.ResponseBody
+	delivery.LastError = outcome.LastError
+	if outcome.DeliveredAt != nil {
+		delivery.DeliveredAt = outcome.DeliveredAt
+	}
+	err = service.repository.Update(uow, &delivery)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// post sends the delivery to the endpoint, signed with its secret. Any answer but a 2xx is
+// a failure; the status and the start of the answer are returned either way.
+func (service *WebhookService) post(endpoint *webhook.WebhookEndpoint, delivery *webhook.WebhookDelivery, now time.Time) (int, string, error) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	request, err := http.NewRequest(http.MethodPost, endpoint.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "BankManagement-Webhooks/1.0")
+	request.Header.Set("X-Webhook-Id", strconv.Itoa(int(delivery.ID)))
+	request.Header.Set("X-Webhook-Event", delivery.EventType)
+	request.Header.Set("X-Webhook-Timestamp", timestamp)
+	request.Header.Set("X-Webhook-Signature", "t="+timestamp+",v1="+Sign(endpoint.Secret, timestamp, []byte(delivery.Payload)))
+
+	response, err := service.client.Do(request)
+	if err != nil {
+		return 0, "", err
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(response.Body, int64(constants.WebhookResponseBodyLimit)))
+	answer := strings.ToValidUTF8(string(body), "")
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, answer, fmt.Errorf("endpoint answered %d", response.StatusCode)
+	}
+	return response.StatusCode, answer, nil
+}
+
+// Sign is the v1 signature of a delivery: the hex HMAC-SHA256, keyed with the endpoint's
+// secret, of the timestamp, a dot and the body. Receivers recompute it from the
+// X-Webhook-Timestamp header and the raw body, and reject old timestamps to stop replays.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookClient does not follow redirects, and refuses to connect to private, loopback
+// and link-local addresses, checked after the name is resolved, so an endpoint cannot be
+// pointed at the internal network.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: constants.WebhookTimeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			if constants.WebhookAllowPrivateNetworks {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+				return errors.New("webhook address " + host + " is not public")
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout:   constants.WebhookTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// dueNow is now without the fraction of a second, which MySQL would round up: the delivery
+// must already be due when the dispatcher is woken right after the commit.
+func dueNow() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+func retryDelay(attempts int) time.Duration {
+	if attempts > 30 {
+		return constants.WebhookRetryMax
+	}
+	return min(constants.WebhookRetryBase<<(attempts-1), constants.WebhookRetryMax)
+}
+
+// ---------------------------- Scheduler job -----------------------------
+
+// WebhookJob retries the deliveries that failed, on every scheduler tick.
+type WebhookJob struct {
+	service *WebhookService
+}
+
+func NewWebhookJob(service *WebhookService) *WebhookJob {
+	return &WebhookJob{service: service}
+}
+
+func (job *WebhookJob) Name() string {
+	return "webhooks"
+}
+
+func (job *WebhookJob) Run(now time.Time) error {
+	return job.service.DispatchDue(now)
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"bankManagement/constants"
+	"bankManagement/models/audit"
+	"bankManagement/models/client"
+	"bankManagement/models/webhook"
+	"bankManagement/repository"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+)
+
+// WebhookService keeps the clients' webhook endpoints and POSTs them the payment and
+// salary events they subscribed to.
+type WebhookService struct {
+	DB         *gorm.DB
+	repository repository.Repository
+	log        log.WebLogger
+	client     *http.Client
+	wakeUp     chan struct{}
+	startOnce  *sync.Once
+	dispatcher *WebhookService // the service as created, which the dispatch goroutine runs with
+}
+
+func NewWebhookService(DB *gorm.DB, repository repository.Repository, log log.WebLogger) *WebhookService {
+	service := &WebhookService{
+		DB:         DB,
+		repository: repository,
+		log:        log,
+		client:     newWebhookClient(),
+		wakeUp:     make(chan struct{}, 1),
+		startOnce:  &sync.Once{},
+	}
+	service.dispatcher = service
+	return service
+}
+
+// WithActor returns a copy of the service whose changes the audit log attributes to actor
+// and whose log lines carry the actor's request ID.
+func (service *WebhookService) WithActor(actor *audit.Actor) *WebhookService {
+	scoped := *service
+	scoped.DB = repository.BindActor(service.DB, actor)
+	scoped.log = service.log.WithFields(log.Fields{constants.RequestIDKey: actor.RequestId})
+	return &scoped
+}
+
+// CreateEndpoint registers an endpoint of the client. The secret is generated here and only
+// returned now, the client keeps it to check the signatures.
+func (service *WebhookService) CreateEndpoint(clientId uint, userId uint, dto *webhook.WebhookEndpointDTO) (*webhook.WebhookSecretDTO, error) {
+	err := validateEndpoint(dto)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	tempClient := client.Client{}
+	err = service.repository.GetByID(uow, &tempClient, clientId)
+	if err != nil || tempClient.ID == 0 {
+		return nil, errors.New("Client Not found")
+	}
+	endpoint := webhook.WebhookEndpoint{
+		ClientID:        clientId,
+		URL:             dto.URL,
+		Description:     dto.Description,
+		EventTypes:      strings.Join(dto.EventTypes, ","),
+		Secret:          secret,
+		IsActive:        dto.IsActive == nil || *dto.IsActive,
+		CreatedByUserId: userId,
+	}
+	err = service.repository.Add(uow, &endpoint)
+	if err != nil {
+		return nil, err
+	}
+	uow.Commit()
+	endpoint.EventTypeList = dto.EventTypes
+	return &webhook.WebhookSecretDTO{Endpoint: &endpoint, Secret: secret}, nil
+}
+
+func (service *WebhookService) GetAllEndpoints(clientId uint, query *web.ListQuery, endpoints *[]webhook.WebhookEndpoint) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	queryProcessors := []repository.QueryProcessor{service.repository.Filter("client_id=?", clientId)}
+	err := service.repository.GetAll(uow, endpoints, append(queryProcessors, query.QueryProcessors(service.repository)...)...)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+func (service *WebhookService) GetEndpoint(clientId uint, endpointId uint, endpoint *webhook.WebhookEndpoint) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	err := service.repository.GetByID(uow, endpoint, endpointId)
+	if err != nil || endpoint.ClientID != clientId {
+		return errors.New("Webhook Not found")
+	}
+	uow.Commit()
+	return nil
+}
+
+// UpdateEndpoint replaces the URL, description and event types of the endpoint, and
+// enables or disables it when is_active is given.
+func (service *WebhookService) UpdateEndpoint(clientId uint, endpointId uint, dto *webhook.WebhookEndpointDTO, endpoint *webhook.WebhookEndpoint) error {
+	err := validateEndpoint(dto)
+	if err != nil {
+		return err
+	}
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	err = service.repository.GetByIDForUpdate(uow, endpoint, endpointId)
+	if err != nil || endpoint.ClientID != clientId {
+		return errors.New("Webhook Not found")
+	}
+	endpoint.URL = dto.URL
+	endpoint.Description = dto.Description
+	endpoint.EventTypes = strings.Join(dto.EventTypes, ",")
+	endpoint.EventTypeList = dto.EventTypes
+	if dto.IsActive != nil {
+		endpoint.IsActive = *dto.IsActive
+	}
+	err = service.repository.Update(uow, endpoint)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// DeleteEndpoint removes the endpoint, its pending deliveries fail when they come due.
+func (service *WebhookService) DeleteEndpoint(clientId uint, endpointId uint) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	endpoint := webhook.WebhookEndpoint{}
+	err := service.repository.GetByID(uow, &endpoint, endpointId)
+	if err != nil || endpoint.ClientID != clientId {
+		return errors.New("Webhook Not found")
+	}
+	err = service.repository.DeleteById(uow, &webhook.WebhookEndpoint{}, endpointId)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// RotateSecret replaces the secret of the endpoint, deliveries are signed with the new one
+// from now on, retries of earlier ones included.
+func (service *WebhookService) RotateSecret(clientId uint, endpointId uint) (*webhook.WebhookSecretDTO, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	endpoint := webhook.WebhookEndpoint{}
+	err = service.repository.GetByIDForUpdate(uow, &endpoint, endpointId)
+	if err != nil || endpoint.ClientID != clientId {
+		return nil, errors.New("Webhook Not found")
+	}
+	endpoint.Secret = secret
+	err = service.repository.Update(uow, &endpoint)
+	if err != nil {
+		return nil, err
+	}
+	uow.Commit()
+	return &webhook.WebhookSecretDTO{Endpoint: &endpoint, Secret: secret}, nil
+}
+
+// GetDeliveries lists the delivery log of an endpoint of the client.
+func (service *WebhookService) GetDeliveries(clientId uint, endpointId uint, query *web.ListQuery, deliveries *[]webhook.WebhookDelivery) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	endpoint := webhook.WebhookEndpoint{}
+	err := service.repository.GetByID(uow, &endpoint, endpointId)
+	if err != nil || endpoint.ClientID != clientId {
+		return errors.New("Webhook Not found")
+	}
+	queryProcessors := []repository.QueryProcessor{service.repository.Filter("endpoint_id=?", endpointId)}
+	err = service.repository.GetAll(uow, deliveries, append(queryProcessors, query.QueryProcessors(service.repository)...)...)
+	if err != nil {
+		return err
+	}
+	uow.Commit()
+	return nil
+}
+
+// Redeliver sends a delivery again now, whatever its status, with a fresh count of attempts.
+func (service *WebhookService) Redeliver(clientId uint, endpointId uint, deliveryId uint, delivery *webhook.WebhookDelivery) error {
+	uow := repository.NewUnitOfWork(service.DB)
+	defer uow.RollBack()
+	endpoint := webhook.WebhookEndpoint{}
+	err := service.repository.GetByID(uow, &endpoint, endpointId)
+	if err != nil || endpoint.ClientID != clientId {
+		return errors.New("Webhook Not found")
+	}
+	if !endpoint.IsActive {
+		return errors.New("Webhook is disabled, enable it before redelivering")
+	}
+	err = service.repository.GetByIDForUpdate(uow, delivery, deliveryId)
+	if err != nil || delivery.EndpointID != endpointId {
+		return errors.New("Delivery Not found")
+	}
+	delivery.Status = webhook.DeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = dueNow()
+	err = service.repository.Update(uow, delivery)
+	if err != nil {
+		return err
+	}
+	uow.AfterCommit(service.wake)
+	uow.Commit()
+	return nil
+}
+
+func validateEndpoint(dto *webhook.WebhookEndpointDTO) error {
+	target, err := url.Parse(dto.URL)
+	if err != nil || target.Host == "" {
+		return errors.New("Invalid webhook url")
+	}
+	if target.Scheme != "https" && !(constants.WebhookAllowPrivateNetworks && target.Scheme == "http") {
+		return errors.New("Webhook url must use https")
+	}
+	seen := map[string]bool{}
+	for _, eventType := range dto.EventTypes {
+		if !isEventType(eventType) {
+			return fmt.Errorf("Unknown event type %s, expected one of %s", eventType, strings.Join(webhook.EventTypes, ", "))
+		}
+		if seen[eventType] {
+			return fmt.Errorf("Event type %s is listed twice", eventType)
+		}
+		seen[eventType] = true
+	}
+	return nil
+}
+
+func isEventType(eventType string) bool {
+	for _, known := range webhook.EventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func newSecret() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(random), nil
+}
@@ -1,10 +1,80 @@
 package constants
 
+import "time"
+
 var APIPrefix = "/api/v1/bankManagement"
 var ServerAddress = "0.0.0.0:4000"
 
+// how often the background scheduler looks for due work (payroll runs...)
+var SchedulerInterval = time.Minute
+
 var AdminKeyValue = "admin"
 
 var BankUserKeyValue = "bank-user"
 var ClientUserKeyValue = "client-user"
 var ClaimKey = "claims"
+
+// limits of a bulk employee import upload
+var EmployeeImportMaxBytes int64 = 10 << 20
+var EmployeeImportMaxRows = 5000
+
+// paging of list endpoints (?page=&size=)
+var DefaultPageSize = 20
+var MaxPageSize = 100
+
+// limits of an uploaded KYC document, the type is sniffed from the content
+var DocumentMaxBytes int64 = 10 << 20
+var DocumentAllowedTypes = []string{"application/pdf", "image/jpeg", "image/png"}
+
+// lifetime of the JWT access token and of each rotating refresh token
+var AccessTokenTTL = 15 * time.Minute
+var RefreshTokenTTL = 7 * 24 * time.Hour
+
+// how long an emailed user invitation can be accepted
+var InvitationTTL = 72 * time.Hour
+
+// two-factor authentication: lifetime of the token between password and code, the name
+// authenticator apps show, and the header carrying the code on step-up operations
+var PreAuthTokenTTL = 5 * time.Minute
+var TOTPIssuer = "Bank Management"
+var StepUpCodeHeader = "X-2FA-Code"
+var RecoveryCodeCount = 10
+
+// login brute-force protection: past the free attempts each failure doubles the wait before
+// the next one, from LoginBackoffBase up to LoginBackoffMax, and LoginLockoutThreshold
+// failures lock the user until an admin unlocks it. Counts restart after LoginFailureWindow
+// without failures. An IP address gets more free attempts, it may be shared.
+var LoginFreeAttempts = 3
+var LoginIPFreeAttempts = 20
+var LoginBackoffBase = time.Second
+var LoginBackoffMax = 15 * time.Minute
+var LoginLockoutThreshold = 10
+var LoginFailureWindow = time.Hour
+
+// how long an emailed password reset token can be used
+var PasswordResetTTL = 30 * time.Minute
+
+// request correlation: the header a caller may set its own request ID in, echoed on the
+// response, and the context key the ID travels under to every log line of the request
+var RequestIDHeader = "X-Request-ID"
+var RequestIDKey = "request_id"
+
+// outbox dispatch: events are handed to their subscribers right after the commit and then
+// retried every scheduler tick, waiting from OutboxRetryBase doubling up to OutboxRetryMax
+// between attempts, and marked failed after OutboxMaxAttempts
+var OutboxBatchSize = 100
+var OutboxRetryBase = 10 * time.Second
+var OutboxRetryMax = time.Hour
+var OutboxMaxAttempts = 10
+
+// webhook delivery: a POST taking longer than WebhookTimeout fails, failures are retried
+// waiting from WebhookRetryBase doubling up to WebhookRetryMax, until WebhookMaxAttempts.
+// Endpoints on private and loopback addresses are refused unless WebhookAllowPrivateNetworks,
+// for development only
+var WebhookTimeout = 10 * time.Second
+var WebhookRetryBase = 30 * time.Second
+var WebhookRetryMax = 6 * time.Hour
+var WebhookMaxAttempts = 8
+var WebhookBatchSize = 100
+var WebhookResponseBodyLimit = 1024
+var WebhookAllowPrivateNetworks = false
@@ -34,13 +34,12 @@ func main() {
 	modules.RegisterTableMigrations(appObj)
 	modules.RegisterAllModules(appObj)
 	seeder.SeedRoles(db)
-	// email.GetSMTPService().SendEmail("Temp Email", "This is temp Email", "crazinessspeaks@gmail.com")
 	// modules.SeedData(appObj)
 	appObj.StartServer()
 
 }
 
-func NewDBConnection(log log.WebLogger) *gorm.DB {
+func NewDBConnection(logger log.WebLogger) *gorm.DB {
 	host, b1 := os.LookupEnv("HOST")
 	user, b2 := os.LookupEnv("USER")
 	password, b3 := os.LookupEnv("PASSWORD")
@@ -50,16 +49,22 @@ func NewDBConnection(log log.WebLogger) *gorm.DB {
 	}
 	db, err := gorm.Open("mysql", user+":"+password+"@("+host+")/"+database+"?charset=utf8&parseTime=True&loc=Local")
 	if err != nil {
-		log.Error(err.Error())
+		logger.Error(err.Error())
 		return nil
 	}
-	log.Info("Database Connected")
+	logger.Info("Database Connected")
+	db.SetLogger(log.GormLogger{Log: logger})
 	db.LogMode(true)
 	return db
 }
 
 func NewLogger() log.WebLogger {
-	return log.GetLogger()
+	logger, err := log.NewLoggerFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	log.SetLogger(logger)
+	return logger
 }
 
 func NewWaitGroup() *sync.WaitGroup {
@@ -2,33 +2,75 @@ package auth
 
 import (
 	"bankManagement/constants"
+	"bankManagement/models/audit"
 	"bankManagement/utils/encrypt"
 	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
 	"context"
 	"errors"
-	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
 )
 
+// sessionValidator rejects tokens of closed login sessions (logout, refresh token reuse),
+// it is registered by the auth module.
+var sessionValidator func(loginSessionId uint) error
+
+func SetSessionValidator(validator func(loginSessionId uint) error) {
+	sessionValidator = validator
+}
+
+// stepUpVerifier checks the two-factor code of a sensitive operation, it is registered by
+// the auth module.
+var stepUpVerifier func(userId uint, code string, ipAddress string) error
+
+func SetStepUpVerifier(verifier func(userId uint, code string, ipAddress string) error) {
+	stepUpVerifier = verifier
+}
+
 func AuthenticationMiddleware(next http.Handler) http.Handler {
+	return authenticate(next, false)
+}
+
+// PreAuthMiddleware also lets through the pre-auth token of a login waiting for its
+// two-factor code, for the endpoints that complete it (enrolment, code verification).
+func PreAuthMiddleware(next http.Handler) http.Handler {
+	return authenticate(next, true)
+}
+
+func authenticate(next http.Handler, allowPreAuth bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("Authentication Middleware Called")
 		token, err := getAuthTokenFromHeader(r)
 		if err != nil {
 			errorsUtils.SendInvalidAuthError(w)
 			return
 		}
 		claims, err1 := encrypt.ValidateJwtToken(token)
-		fmt.Println("Validation Completed")
 		if err1 != nil {
-			fmt.Println(err1)
+			log.GetLogger().WithContext(r.Context()).Warning("rejected token:", err1)
 			errorsUtils.SendErrorWithCustomMessage(w, err1.Error(), http.StatusUnauthorized)
 			return
 		}
-		fmt.Println("Claims", claims)
+		if claims.Purpose == encrypt.PurposePreAuth && allowPreAuth {
+			ctx := context.WithValue(r.Context(), constants.ClaimKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		if claims.Purpose != "" {
+			errorsUtils.SendErrorWithCustomMessage(w, "Login is not complete, verify your two-factor code", http.StatusUnauthorized)
+			return
+		}
+		if sessionValidator == nil {
+			errorsUtils.SendErrorWithCustomMessage(w, "Session validation is not configured", http.StatusUnauthorized)
+			return
+		}
+		if err := sessionValidator(claims.LoginSessionId); err != nil {
+			errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
 
 		ctx := context.WithValue(r.Context(), constants.ClaimKey, claims)
 
@@ -42,15 +84,12 @@ func ValidateClientPermissionsMiddleware(next http.Handler) http.Handler {
 		defer func(w http.ResponseWriter, r *http.Request) {
 			err := recover()
 			if err != nil {
-				fmt.Println(err)
+				log.GetLogger().WithContext(r.Context()).Warning("tenant check failed:", err)
 				errorsUtils.SendErrorWithCustomMessage(w, err.(error).Error(), http.StatusUnauthorized)
 				return
 			}
 		}(w, r)
-		fmt.Println("Client Validation Middleware Called")
-		fmt.Print(r.Context())
 		claims := r.Context().Value("claims").(*encrypt.Claims)
-		fmt.Print(claims)
 
 		if claims.ClientId == 0 {
 			errorsUtils.SendErrorWithCustomMessage(w, "Client Privileges Denied", http.StatusUnauthorized)
@@ -84,15 +123,12 @@ func ValidateBankPermissionsMiddleware(next http.Handler) http.Handler {
 		defer func(w http.ResponseWriter, r *http.Request) {
 			err := recover()
 			if err != nil {
-				fmt.Println(err)
+				log.GetLogger().WithContext(r.Context()).Warning("tenant check failed:", err)
 				errorsUtils.SendErrorWithCustomMessage(w, err.(error).Error(), http.StatusUnauthorized)
 				return
 			}
 		}(w, r)
-		fmt.Println("Bank Admin Validation Middleware Called")
-		fmt.Print(r.Context())
 		claims := r.Context().Value("claims").(*encrypt.Claims)
-		fmt.Print(claims)
 
 		if claims.BankId == 0 {
 			errorsUtils.SendErrorWithCustomMessage(w, "Bank Privileges Denied", http.StatusUnauthorized)
@@ -120,54 +156,73 @@ func ValidateBankPermissionsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// -----------------------
-
-// (SUPER_ADMIN/ Admin can only CRUD on Banks)
-func ValidateAdminPermissionsMiddleware(next http.Handler) http.Handler {
+// Require runs the handler only when the caller's token grants every listed permission,
+// it is how routes declare what they need. Use it behind AuthenticationMiddleware.
+func Require(handler http.HandlerFunc, permissions ...string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func(w http.ResponseWriter, r *http.Request) {
-			err := recover()
-			if err != nil {
-				fmt.Println(err)
-				errorsUtils.SendErrorWithCustomMessage(w, err.(error).Error(), http.StatusUnauthorized)
+		claims, ok := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+		if !ok {
+			errorsUtils.SendInvalidAuthError(w)
+			return
+		}
+		for _, permission := range permissions {
+			if !claims.HasPermission(permission) {
+				errorsUtils.SendErrorWithCustomMessage(w, "Permission "+permission+" is required", http.StatusForbidden)
 				return
 			}
-		}(w, r)
-		fmt.Println("Admin Validation Middleware Called")
-		fmt.Print(r.Context())
-		claims := r.Context().Value("claims").(*encrypt.Claims)
-		fmt.Print(claims)
+		}
+		handler(w, r)
+	})
+}
 
-		if claims.UserId == 0 {
-			errorsUtils.SendErrorWithCustomMessage(w, "Admin Privileges Denied", http.StatusUnauthorized)
+// RequireStepUp makes the caller prove again, with a fresh two-factor code in the
+// constants.StepUpCodeHeader header, that they are at the keyboard. Use it behind
+// AuthenticationMiddleware, inside Require so no code is spent on a caller lacking permissions.
+func RequireStepUp(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+		if !ok {
+			errorsUtils.SendInvalidAuthError(w)
 			return
 		}
-
-		if claims.RoleId != 1 {
-			errorsUtils.SendErrorWithCustomMessage(w, "Admin Privileges Denied", http.StatusUnauthorized)
+		code := r.Header.Get(constants.StepUpCodeHeader)
+		if code == "" {
+			errorsUtils.SendErrorWithCustomMessage(w, "Two-factor code is required in the "+constants.StepUpCodeHeader+" header", http.StatusUnauthorized)
 			return
 		}
+		if stepUpVerifier == nil {
+			errorsUtils.SendErrorWithCustomMessage(w, "Two-factor verification is not configured", http.StatusUnauthorized)
+			return
+		}
+		if err := stepUpVerifier(claims.UserId, code, web.ClientIP(r)); err != nil {
+			errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
 
-		// requested_admin_access, ok := mux.Vars(r)["user_id"]
-		// if !ok {
-		// 	errorsUtils.SendErrorWithCustomMessage(w, "User_Id not found. Please put User Id in Path", http.StatusUnauthorized)
-		// 	return
-		// }
-		// requested_admin_access_int, err := strconv.Atoi(requested_admin_access)
-		// if err != nil {
-		// 	errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusUnauthorized)
-		// 	return
-		// }
-
-		// if requested_admin_access_int != int(claims.UserId) {
-		// 	errorsUtils.SendErrorWithCustomMessage(w, "You are not authorized to access this Bank", http.StatusUnauthorized)
-		// 	return
-		// }
-
-		// ctx := context.WithValue(r.Context(), constants.ClaimsAdminKey, admin)
-
-		next.ServeHTTP(w, r)
-	})
+// ActorFromRequest is who the audit log attributes the request's changes to: the caller of
+// an authenticated request, only the address and the operation otherwise.
+func ActorFromRequest(r *http.Request) *audit.Actor {
+	actor := &audit.Actor{
+		IPAddress: web.ClientIP(r),
+		Operation: r.Method + " " + r.URL.Path,
+		RequestId: log.RequestIDFromContext(r.Context()),
+	}
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			actor.Operation = r.Method + " " + template
+		}
+	}
+	if claims, ok := r.Context().Value(constants.ClaimKey).(*encrypt.Claims); ok {
+		actor.UserId = claims.UserId
+		actor.RoleId = claims.RoleId
+		actor.BankId = claims.BankId
+		actor.ClientId = claims.ClientId
+		actor.LoginSessionId = claims.LoginSessionId
+	}
+	return actor
 }
 
 ///----------------------------
@@ -176,7 +231,7 @@ func ValidateAdminPermissionsMiddleware(next http.Handler) http.Handler {
 // 		defer func(w http.ResponseWriter, r *http.Request) {
 // 			err := recover()
 // 			if err != nil {
-// 				fmt.Println(err)
+// 				log.GetLogger().WithContext(r.Context()).Warning("tenant check failed:", err)
 // 				errorsUtils.SendErrorWithCustomMessage(w, err.(error).Error())
 // 				return
 // 			}
@@ -214,7 +269,6 @@ func ValidateAdminPermissionsMiddleware(next http.Handler) http.Handler {
 
 func getAuthTokenFromHeader(r *http.Request) (string, error) {
 	headers := r.Header
-	fmt.Println(headers)
 	tempTokenHeader, ok := headers["Authorization"]
 	if !ok || len(tempTokenHeader) == 0 {
 		return "", errors.New("Token Not found")
@@ -0,0 +1,121 @@
+package idempotency
+
+import (
+	"bankManagement/components/idempotency/service"
+	"bankManagement/constants"
+	"bankManagement/models/idempotency"
+	"bankManagement/utils/encrypt"
+	errorsUtils "bankManagement/utils/errors"
+	"bankManagement/utils/log"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+)
+
+const HeaderKey = "Idempotency-Key"
+
+// Idempotent wraps a client handler so that a request carrying an Idempotency-Key header
+// is executed at most once per client and key. Retries get the stored response back,
+// the same key with a different body gets 409 Conflict. Requests without the header are
+// passed through untouched. Must run behind auth.AuthenticationMiddleware.
+func Idempotent(idempotencyService *service.IdempotencyService, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderKey)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > 255 {
+			errorsUtils.SendErrorWithCustomMessage(w, HeaderKey+" must be at most 255 characters", http.StatusBadRequest)
+			return
+		}
+		claims, ok := r.Context().Value(constants.ClaimKey).(*encrypt.Claims)
+		if !ok || claims.ClientId == 0 {
+			errorsUtils.SendErrorWithCustomMessage(w, "Invalid JWT Token for Access", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			errorsUtils.SendInvalidBodyError(w)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(body)
+
+		record := &idempotency.IdempotencyRecord{
+			ClientID:       claims.ClientId,
+			IdempotencyKey: key,
+			Method:         r.Method,
+			Path:           r.URL.Path,
+			RequestHash:    hex.EncodeToString(hash[:]),
+		}
+		replay, err := idempotencyService.Begin(record)
+		if err != nil {
+			if errors.Is(err, service.ErrKeyReused) || errors.Is(err, service.ErrRequestInProgress) {
+				errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusConflict)
+				return
+			}
+			errorsUtils.SendErrorWithCustomMessage(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if replay {
+			if record.ContentType != "" {
+				w.Header().Set("Content-Type", record.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.StatusCode)
+			w.Write([]byte(record.ResponseBody))
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			// a panic is a server side failure too, the key is given back before it goes on up
+			if p := recover(); p != nil {
+				if err := idempotencyService.Release(record); err != nil {
+					log.GetLogger().WithContext(r.Context()).Error("failed to release idempotency key:", err)
+				}
+				panic(p)
+			}
+		}()
+		next(recorder, r)
+		if recorder.statusCode == 0 {
+			recorder.statusCode = http.StatusOK
+		}
+		// server side failures are not remembered, the client may retry them with the same key
+		if recorder.statusCode >= http.StatusInternalServerError {
+			err = idempotencyService.Release(record)
+		} else {
+			err = idempotencyService.Complete(record, recorder.statusCode, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		}
+		if err != nil {
+			log.GetLogger().WithContext(r.Context()).Error("failed to store idempotent response:", err)
+		}
+	}
+}
+
+// responseRecorder passes the response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (recorder *responseRecorder) WriteHeader(statusCode int) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = statusCode
+	}
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = http.StatusOK
+	}
+	recorder.body.Write(data)
+	return recorder.ResponseWriter.Write(data)
+}
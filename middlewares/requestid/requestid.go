@@ -0,0 +1,73 @@
+package requestid
+
+import (
+	"bankManagement/constants"
+	"bankManagement/utils/log"
+	"bankManagement/utils/web"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// a request ID set by the caller is kept when it looks like one, else a new one is made
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestIDMiddleware gives each request a correlation ID, taken from the X-Request-ID
+// header or generated, echoes it on the response and puts it in the request context for
+// log.WebLogger.WithContext. Each request is logged once done, with its status and duration.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get(constants.RequestIDHeader)
+		if !validRequestID.MatchString(requestId) {
+			requestId = newRequestID()
+		}
+		w.Header().Set(constants.RequestIDHeader, requestId)
+		r = r.WithContext(log.ContextWithRequestID(r.Context(), requestId))
+
+		started := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		if recorder.statusCode == 0 {
+			recorder.statusCode = http.StatusOK
+		}
+		logger := log.GetLogger().WithContext(r.Context()).WithFields(log.Fields{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      recorder.statusCode,
+			"duration_ms": float64(time.Since(started).Microseconds()) / 1000,
+			"ip":          web.ClientIP(r),
+		})
+		if recorder.statusCode >= http.StatusInternalServerError {
+			logger.Error("request failed")
+		} else {
+			logger.Info("request served")
+		}
+	})
+}
+
+func newRequestID() string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	return hex.EncodeToString(random)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (recorder *statusRecorder) WriteHeader(statusCode int) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = statusCode
+	}
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (recorder *statusRecorder) Write(data []byte) (int, error) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = http.StatusOK
+	}
+	return recorder.ResponseWriter.Write(data)
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Actor is who a change is attributed to: the caller of the request, empty for changes
+// made by the system (scheduler, seeding).
+type Actor struct {
+	UserId         uint
+	RoleId         uint
+	BankId         uint
+	ClientId       uint
+	LoginSessionId uint
+	IPAddress      string
+	Operation      string // method and route of the request, e.g. POST /banks/{bank_id}/clients/
+	RequestId      string // correlation ID of the request, the one on its log lines
+}
+
+// AuditEntry is one change of one row. Entries are only ever appended: each hashes the
+// previous entry's hash with its own content, so editing, removing or reordering entries
+// breaks the chain.
+type AuditEntry struct {
+	ID             uint      `gorm:"primary_key" json:"id"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+	Sequence       int64     `gorm:"not null;unique_index" json:"sequence"`
+	Timestamp      int64     `gorm:"not null" json:"timestamp"` // unix milliseconds, hashed instead of CreatedAt which depends on the DB time zone
+	ActorUserId    uint      `gorm:"index" json:"actor_user_id"`
+	ActorRoleId    uint      `json:"actor_role_id"`
+	ActorBankId    uint      `json:"actor_bank_id,omitempty"`
+	ActorClientId  uint      `json:"actor_client_id,omitempty"`
+	LoginSessionId uint      `json:"login_session_id,omitempty"`
+	IPAddress      string    `json:"ip_address"`
+	Operation      string    `json:"operation"`
+	RequestId      string    `gorm:"index" json:"request_id,omitempty"`
+	Action         string    `gorm:"not null" json:"action"` // create, update or delete
+	EntityType     string    `gorm:"not null;index:idx_audit_entity" json:"entity_type"`
+	EntityId       string    `gorm:"index:idx_audit_entity" json:"entity_id"`
+	BankId         uint      `gorm:"index" json:"bank_id,omitempty"` // the bank the change concerns, what bank users can see
+	Before         string    `gorm:"type:mediumtext" json:"before,omitempty"`
+	After          string    `gorm:"type:mediumtext" json:"after,omitempty"`
+	PrevHash       string    `gorm:"type:char(64);not null" json:"prev_hash"`
+	Hash           string    `gorm:"type:char(64);not null" json:"hash"`
+}
+
+// ComputeHash is the SHA-256 of the previous hash and of every field but ID, CreatedAt and Hash.
+func (entry *AuditEntry) ComputeHash() string {
+	content, _ := json.Marshal([]interface{}{
+		entry.Sequence, entry.Timestamp,
+		entry.ActorUserId, entry.ActorRoleId, entry.ActorBankId, entry.ActorClientId, entry.LoginSessionId,
+		entry.IPAddress, entry.Operation, entry.RequestId, entry.Action, entry.EntityType, entry.EntityId, entry.BankId,
+		entry.Before, entry.After,
+	})
+	sum := sha256.Sum256(append([]byte(entry.PrevHash+"\n"), content...))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditChainHead is the single row holding the end of the chain. Appending locks it, so
+// entries get consecutive sequences and each links to the one before.
+type AuditChainHead struct {
+	ID       uint   `gorm:"primary_key"`
+	Sequence int64  `gorm:"not null"`
+	LastHash string `gorm:"type:char(64);not null"`
+}
+
+const ChainHeadID = 1
+
+// GenesisHash is the previous hash of the first entry.
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+type AuditVerificationDTO struct {
+	Valid                bool   `json:"valid"`
+	EntriesChecked       int64  `json:"entries_checked"`
+	FirstInvalidSequence int64  `json:"first_invalid_sequence,omitempty"`
+	Problem              string `json:"problem,omitempty"`
+}
@@ -0,0 +1,15 @@
+package audit
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+type AuditConfig struct {
+	DB *gorm.DB
+}
+
+func (config *AuditConfig) TableMigration() {
+	config.DB.AutoMigrate(&AuditEntry{}, &AuditChainHead{})
+	// the chain starts from an empty head, created once
+	config.DB.Attrs(AuditChainHead{LastHash: GenesisHash}).FirstOrCreate(&AuditChainHead{}, AuditChainHead{ID: ChainHeadID})
+}
@@ -9,6 +9,11 @@ type Bank struct {
 	BankName         string `gorm:"unique_index;not null" json:"bank_name"`
 	BankAbbreviation string `gorm:"not null" json:"bank_abbreviation"`
 	IsActive         bool   `gorm:"default:true" json:"is_active"`
+	RequireTwoFactor bool   `json:"require_two_factor"` // its users must log in with a TOTP code
+}
+
+type BankSecurityDTO struct {
+	RequireTwoFactor *bool `json:"require_two_factor" validate:"required"`
 }
 
 type BankAndUserDTO struct {
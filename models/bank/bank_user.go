@@ -7,6 +7,9 @@ import (
 type BankUser struct {
 	UserID uint `gorm:"primary_key;auto_increment:false" json:"user_id"`
 	BankID uint `gorm:"primary_key;auto_increment:false" json:"bank_id"`
+	// a deactivated operator keeps the membership but cannot log in to the bank
+	IsActive        bool `gorm:"default:true" json:"is_active"`
+	InvitedByUserId uint `json:"invited_by_user_id,omitempty"`
 
 	Bank Bank      `gorm:"foreignkey:BankID;association_foreignkey:ID" json:"bank"`
 	User user.User `gorm:"foreignkey:UserID;association_foreignkey:ID" json:"user"`
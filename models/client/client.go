@@ -2,39 +2,74 @@ package client
 
 import (
 	"bankManagement/models/bank"
+	"bankManagement/utils/money"
+	"fmt"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
 
 type Client struct {
-	gorm.Model                   // ClientID
-	ClientName         string    `gorm:"unique_index;not null" json:"client_name"`
-	ClientEmail        string    `gorm:"unique_index;not null" json:"client_email"`
-	Balance            float64   `gorm:"default:1000" json:"balance"`
-	IsActive           bool      `gorm:"default:true" json:"is_active"`
-	VerificationStatus string    `gorm:"default:'Pending';not null" json:"verification_status"`
-	BankID             uint      `gorm:"not null;index" json:"bank_id"`
-	Bank               bank.Bank `gorm:"foreignkey:BankID;association_foreignkey:ID" json:"bank"`
+	gorm.Model                     // ClientID
+	ClientName         string      `gorm:"unique_index;not null" json:"client_name"`
+	ClientEmail        string      `gorm:"unique_index;not null" json:"client_email"`
+	Balance            money.Money `gorm:"type:bigint;default:100000" json:"balance"`
+	Currency           string      `gorm:"type:char(3);default:'INR';not null" json:"currency"` // fixed at creation
+	IsActive           bool        `gorm:"default:true" json:"is_active"`
+	VerificationStatus string      `gorm:"default:'Pending';not null" json:"verification_status"` // moved only by the KYC workflow
+	VerificationNote   string      `json:"verification_note,omitempty"`                           // reason of the last rejection
+	VerifiedByUserId   uint        `json:"verified_by_user_id,omitempty"`
+	VerifiedAt         *time.Time  `json:"verified_at,omitempty"`
+	BankID             uint        `gorm:"not null;index" json:"bank_id"`
+	Bank               bank.Bank   `gorm:"foreignkey:BankID;association_foreignkey:ID" json:"bank"`
+}
+
+// KYC verification statuses: Pending until every document type the bank requires is
+// uploaded, then ReadyForReview until a bank user verifies or rejects the client.
+var VerificationStatusPending = "Pending"
+var VerificationStatusReadyForReview = "ReadyForReview"
+var VerificationStatusVerified = "Verified"
+var VerificationStatusRejected = "Rejected"
+
+// KycReviewDTO is the body of a KYC rejection.
+type KycReviewDTO struct {
+	Reason string `json:"reason" validate:"required,max=500"`
 }
 
 type ClientDTO struct {
-	ClientName         string  `json:"client_name"`
-	ClientEmail        string  `json:"client_email"`
-	Balance            float64 `json:"balance"`
-	IsActive           bool    `json:"is_active"`
-	VerificationStatus string  `json:"verification_status"`
-	BankID             uint    `json:"bank_id"`
-	Username           string  `json:"username"` //for client_user
-	Password           string  `json:"password"` //for client_user
+	ClientName         string      `json:"client_name"`
+	ClientEmail        string      `json:"client_email"`
+	Balance            money.Money `json:"balance"`
+	Currency           string      `json:"currency"`
+	IsActive           bool        `json:"is_active"`
+	VerificationStatus string      `json:"verification_status"`
+	BankID             uint        `json:"bank_id"`
+	Username           string      `json:"username"` //for client_user
+	Password           string      `json:"password"` //for client_user
 }
 
 type ClientResponseDTO struct {
-	ID                 uint    `json:"id"`
-	ClientName         string  `json:"client_name"`
-	ClientEmail        string  `json:"client_email"`
-	Balance            float64 `json:"balance"`
-	IsActive           bool    `json:"is_active"`
-	VerificationStatus string  `json:"verification_status"`
-	BankID             uint    `json:"bank_id"`
-	Username           string  `json:"username"` // client_user
+	ID                 uint        `json:"id"`
+	ClientName         string      `json:"client_name"`
+	ClientEmail        string      `json:"client_email"`
+	Balance            money.Money `json:"balance"`
+	Currency           string      `json:"currency"`
+	IsActive           bool        `json:"is_active"`
+	VerificationStatus string      `json:"verification_status"`
+	BankID             uint        `json:"bank_id"`
+	Username           string      `json:"username"` // client_user
+}
+
+// CheckVerified blocks money movement for clients that did not pass KYC.
+func (c *Client) CheckVerified() error {
+	if c.VerificationStatus != VerificationStatusVerified {
+		return fmt.Errorf("client %s is not KYC verified (status %s)", c.ClientName, c.VerificationStatus)
+	}
+	return nil
+}
+
+// AfterFind binds the balance to the client's own currency; the column only stores minor units.
+func (c *Client) AfterFind() error {
+	c.Balance.Currency = c.Currency
+	return nil
 }
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"bankManagement/utils/money"
+
 	"github.com/jinzhu/gorm"
 )
 
@@ -10,6 +12,9 @@ type ClientConfig struct {
 
 func (config *ClientConfig) TableMigration() {
 	config.DB.AutoMigrate(&Client{})
+	if err := money.MigrateFloatColumn(config.DB, "clients", "balance", "BIGINT DEFAULT 100000"); err != nil {
+		panic(err)
+	}
 
 	config.DB.Model(&Client{}).AddForeignKey("bank_id", "banks(id)", "CASCADE", "CASCADE")
 }
@@ -5,6 +5,9 @@ import "os/user"
 type ClientUser struct {
 	UserID   uint `gorm:"primary_key;auto_increment:false" json:"user_id"`
 	ClientID uint `gorm:"primary_key;auto_increment:false" json:"client_id"`
+	// a deactivated staff member keeps the membership but cannot log in to the client
+	IsActive        bool `gorm:"default:true" json:"is_active"`
+	InvitedByUserId uint `json:"invited_by_user_id,omitempty"`
 
 	User   user.User `gorm:"foreignkey:UserID;association_foreignkey:ID" json:"user"`
 	Client Client    `gorm:"foreignkey:ClientID;association_foreignkey:ID" json:"client"`
@@ -13,7 +13,10 @@ type Document struct {
 	gorm.Model
 	FileName         string        `gorm:"not null" json:"file_name"`
 	FileType         string        `gorm:"not null" json:"file_type"`
-	FileURL          string        `gorm:"not null" json:"file_url"`            // store file path
+	DocumentType     string        `gorm:"index" json:"document_type"`        // KYC category, one of the bank's RequiredDocumentType
+	FileURL          string        `gorm:"not null;index" json:"file_url"`    // storage key of the content addressed blob
+	ContentHash      string        `gorm:"type:char(64)" json:"content_hash"` // hex SHA-256 of the file
+	Size             int64         `json:"size"`
 	UploadedByUserId uint          `gorm:"not null" json:"uploaded_by_user_id"` //Bank_User can upload
 	ClientId         uint          `gorm:"not null" json:"client_id"`
 	Client           client.Client `gorm:"foreignkey:ClientId" json:"client"`
@@ -7,5 +7,6 @@ type DocumentConfig struct {
 }
 
 func (tconf *DocumentConfig) TableMigration() {
-	tconf.DB.AutoMigrate(&Document{})
+	tconf.DB.AutoMigrate(&Document{}, &RequiredDocumentType{})
+	tconf.DB.Model(&RequiredDocumentType{}).AddForeignKey("bank_id", "banks(id)", "CASCADE", "CASCADE")
 }
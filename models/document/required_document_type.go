@@ -0,0 +1,24 @@
+package document
+
+import "time"
+
+// RequiredDocumentType is a document a bank asks every client for before KYC review.
+// Rows are hard deleted so a removed type can be required again.
+type RequiredDocumentType struct {
+	ID           uint      `gorm:"primary_key" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	BankID       uint      `gorm:"not null;unique_index:idx_bank_document_type" json:"bank_id"`
+	DocumentType string    `gorm:"not null;unique_index:idx_bank_document_type" json:"document_type" validate:"required,max=50"`
+	Description  string    `json:"description" validate:"max=255"`
+}
+
+// KycStatusDTO shows how far a client is in the KYC workflow.
+type KycStatusDTO struct {
+	ClientID              uint       `json:"client_id"`
+	VerificationStatus    string     `json:"verification_status"`
+	VerificationNote      string     `json:"verification_note,omitempty"`
+	VerifiedAt            *time.Time `json:"verified_at,omitempty"`
+	RequiredDocumentTypes []string   `json:"required_document_types"`
+	UploadedDocumentTypes []string   `json:"uploaded_document_types"`
+	MissingDocumentTypes  []string   `json:"missing_document_types"`
+}
@@ -2,6 +2,7 @@ package employee
 
 import (
 	"bankManagement/models/client"
+	"bankManagement/utils/money"
 
 	"github.com/jinzhu/gorm"
 )
@@ -11,7 +12,20 @@ type Employee struct {
 	ClientID            uint          `gorm:"not null" json:"client_id"` // Foreign key to Client
 	Client              client.Client `gorm:"foreignkey:ClientID;association_foreignkey:ID" json:"-"`
 	EmployeeName        string        `gorm:"not null; default:'Test'" json:"name" validate:"required"`
-	SalaryAmount        float64       `gorm:"not null" json:"salary_amount" validate:"required"`
+	SalaryAmount        money.Money   `gorm:"type:bigint;not null" json:"salary_amount" validate:"required,gt=0"`
 	AccountNo           string        `gorm:"unique_index;not null" json:"account_no" validate:"required"`
-	TotalSalaryReceived float64       `gorm:"default:0" json:"total_salary_received"`
+	TotalSalaryReceived money.Money   `gorm:"type:bigint;default:0" json:"total_salary_received"`
+	Priority            int           `gorm:"not null;default:0" json:"priority"`           // lower is paid first by the Priority strategy
+	Currency            string        `gorm:"type:char(3);default:'INR';not null" json:"-"` // the client's currency
+}
+
+func (e *Employee) BeforeSave() error {
+	e.Currency = e.SalaryAmount.CurrencyCode()
+	return nil
+}
+
+func (e *Employee) AfterFind() error {
+	e.SalaryAmount.Currency = e.Currency
+	e.TotalSalaryReceived.Currency = e.Currency
+	return nil
 }
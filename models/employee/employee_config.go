@@ -1,6 +1,8 @@
 package employee
 
 import (
+	"bankManagement/utils/money"
+
 	"github.com/jinzhu/gorm"
 )
 
@@ -10,6 +12,12 @@ type EmployeeConfig struct {
 
 func (config *EmployeeConfig) TableMigration() {
 	config.DB.AutoMigrate(&Employee{})
+	if err := money.MigrateFloatColumn(config.DB, "employees", "salary_amount", "BIGINT NOT NULL"); err != nil {
+		panic(err)
+	}
+	if err := money.MigrateFloatColumn(config.DB, "employees", "total_salary_received", "BIGINT DEFAULT 0"); err != nil {
+		panic(err)
+	}
 
 	config.DB.Model(&Employee{}).AddForeignKey("client_id", "clients(id)", "CASCADE", "CASCADE")
 }
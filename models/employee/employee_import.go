@@ -0,0 +1,37 @@
+package employee
+
+// column headers of the bulk import/export files, matched case-insensitively on import;
+// unknown columns are ignored so an export can be edited and imported back
+var ColumnName = "name"
+var ColumnSalaryAmount = "salary_amount"
+var ColumnAccountNo = "account_no"
+var ColumnPriority = "priority"
+var ColumnCurrency = "currency"
+var ColumnTotalSalaryReceived = "total_salary_received"
+
+var ImportRequiredColumns = []string{ColumnName, ColumnSalaryAmount, ColumnAccountNo}
+var ExportColumns = []string{ColumnName, ColumnSalaryAmount, ColumnAccountNo, ColumnPriority, ColumnCurrency, ColumnTotalSalaryReceived}
+
+var ImportActionCreate = "Create"
+var ImportActionUpdate = "Update"
+
+// ImportRowResult is the outcome of one data row; Row is the line number in the file,
+// the header being row 1.
+type ImportRowResult struct {
+	Row       int      `json:"row"`
+	AccountNo string   `json:"account_no"`
+	Action    string   `json:"action,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// ImportReport summarises a bulk import. Rows are only saved when the import is not a
+// dry run and no row has errors.
+type ImportReport struct {
+	DryRun    bool              `json:"dry_run"`
+	Applied   bool              `json:"applied"`
+	TotalRows int               `json:"total_rows"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Failed    int               `json:"failed"`
+	Rows      []ImportRowResult `json:"rows"`
+}
@@ -0,0 +1,12 @@
+package fx
+
+import "github.com/jinzhu/gorm"
+
+// ExchangeRate says how many units of ToCurrency one unit of FromCurrency buys.
+// Rate is kept as a decimal string so it is never rounded through a float.
+type ExchangeRate struct {
+	gorm.Model
+	FromCurrency string `gorm:"not null;unique_index:idx_currency_pair" json:"from_currency" validate:"required,len=3"`
+	ToCurrency   string `gorm:"not null;unique_index:idx_currency_pair" json:"to_currency" validate:"required,len=3"`
+	Rate         string `gorm:"not null" json:"rate" validate:"required,numeric"`
+}
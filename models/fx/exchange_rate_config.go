@@ -0,0 +1,11 @@
+package fx
+
+import "github.com/jinzhu/gorm"
+
+type ExchangeRateConfig struct {
+	DB *gorm.DB
+}
+
+func (config *ExchangeRateConfig) TableMigration() {
+	config.DB.AutoMigrate(&ExchangeRate{})
+}
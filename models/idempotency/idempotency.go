@@ -0,0 +1,22 @@
+package idempotency
+
+import "github.com/jinzhu/gorm"
+
+// IdempotencyRecord remembers the first response to a request sent with an
+// Idempotency-Key header so that retries of it are replayed instead of re-executed.
+type IdempotencyRecord struct {
+	gorm.Model
+	ClientID       uint   `gorm:"not null;unique_index:idx_client_idempotency_key"`
+	IdempotencyKey string `gorm:"type:varchar(255);not null;unique_index:idx_client_idempotency_key"`
+	Method         string `gorm:"not null"`
+	Path           string `gorm:"not null"`
+	RequestHash    string `gorm:"type:char(64);not null"` // sha256 of the request body
+	Status         string `gorm:"not null;default:'InProgress'"`
+	StatusCode     int
+	ContentType    string
+	ResponseBody   string `gorm:"type:mediumtext"`
+}
+
+var StatusInProgress = "InProgress"
+var StatusCompleted = "Completed"
+var StatusReleased = "Released" // the request failed on our side, the key may be retried
@@ -0,0 +1,13 @@
+package idempotency
+
+import "github.com/jinzhu/gorm"
+
+type IdempotencyConfig struct {
+	DB *gorm.DB
+}
+
+func (config *IdempotencyConfig) TableMigration() {
+	config.DB.AutoMigrate(&IdempotencyRecord{})
+
+	config.DB.Model(&IdempotencyRecord{}).AddForeignKey("client_id", "clients(id)", "CASCADE", "CASCADE")
+}
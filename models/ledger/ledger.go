@@ -0,0 +1,82 @@
+package ledger
+
+import (
+	"bankManagement/utils/money"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Account is a ledger account. Every client owns exactly one ClientAccount,
+// each bank owns, per currency, a FundingAccount (source of opening balances and
+// manual adjustments), a SalaryClearingAccount (money leaving the system to
+// employees) and an FxAccount (the bank's position in cross-currency payments).
+type Account struct {
+	gorm.Model
+	AccountCode string `gorm:"unique_index;not null" json:"account_code"`
+	AccountName string `gorm:"not null" json:"account_name"`
+	AccountType string `gorm:"not null" json:"account_type"`
+	BankID      uint   `gorm:"not null;index" json:"bank_id"`
+	ClientID    uint   `gorm:"index" json:"client_id,omitempty"`
+	Currency    string `gorm:"type:char(3);default:'INR';not null" json:"currency"`
+}
+
+// JournalEntry groups the postings of one business event (payment, salary, adjustment...).
+// The postings of an entry always sum to zero in each currency.
+type JournalEntry struct {
+	gorm.Model
+	EntryType       string    `gorm:"not null" json:"entry_type"`
+	ReferenceID     uint      `gorm:"index" json:"reference_id"`
+	Description     string    `json:"description"`
+	BankID          uint      `gorm:"not null;index" json:"bank_id"`
+	CreatedByUserId uint      `json:"created_by_user_id"`
+	PostedAt        time.Time `gorm:"not null" json:"posted_at"`
+	Postings        []Posting `gorm:"foreignkey:JournalEntryID" json:"postings"`
+}
+
+// Posting moves Amount into (positive) or out of (negative) an account.
+type Posting struct {
+	gorm.Model
+	JournalEntryID uint        `gorm:"not null;index" json:"journal_entry_id"`
+	AccountID      uint        `gorm:"not null;index" json:"account_id"`
+	Account        Account     `gorm:"foreignkey:AccountID" json:"-"`
+	Amount         money.Money `gorm:"type:bigint;not null" json:"amount"`
+	Currency       string      `gorm:"type:char(3);default:'INR';not null" json:"-"`
+}
+
+func (p *Posting) BeforeSave() error {
+	p.Currency = p.Amount.CurrencyCode()
+	return nil
+}
+
+func (p *Posting) AfterFind() error {
+	p.Amount.Currency = p.Currency
+	return nil
+}
+
+type AccountBalanceDTO struct {
+	AccountID   uint        `json:"account_id"`
+	AccountCode string      `json:"account_code"`
+	AccountName string      `json:"account_name"`
+	AccountType string      `json:"account_type"`
+	ClientID    uint        `json:"client_id,omitempty"`
+	Balance     money.Money `json:"balance"`
+}
+
+// TrialBalance totals are kept per currency, amounts in different currencies never add up.
+type TrialBalance struct {
+	BankID   uint                   `json:"bank_id"`
+	Accounts []AccountBalanceDTO    `json:"accounts"`
+	Totals   map[string]money.Money `json:"totals"`
+	Balanced bool                   `json:"balanced"`
+}
+
+var AccountTypeClient = "Client"
+var AccountTypeFunding = "Funding"
+var AccountTypeSalaryClearing = "SalaryClearing"
+var AccountTypeFx = "Fx"
+
+var EntryTypeOpeningBalance = "OpeningBalance"
+var EntryTypeAdjustment = "Adjustment"
+var EntryTypePayment = "Payment"
+var EntryTypeSalary = "SalaryDisbursement"
@@ -0,0 +1,21 @@
+package ledger
+
+import (
+	"bankManagement/utils/money"
+
+	"github.com/jinzhu/gorm"
+)
+
+type LedgerConfig struct {
+	DB *gorm.DB
+}
+
+func (config *LedgerConfig) TableMigration() {
+	config.DB.AutoMigrate(&Account{}, &JournalEntry{}, &Posting{})
+	if err := money.MigrateFloatColumn(config.DB, "postings", "amount", "BIGINT NOT NULL"); err != nil {
+		panic(err)
+	}
+
+	config.DB.Model(&Posting{}).AddForeignKey("journal_entry_id", "journal_entries(id)", "CASCADE", "CASCADE")
+	config.DB.Model(&Posting{}).AddForeignKey("account_id", "accounts(id)", "RESTRICT", "CASCADE")
+}
@@ -0,0 +1,78 @@
+package notification
+
+import (
+	"time"
+)
+
+// Notification event types, one template each in utils/notify/templates.
+var (
+	EventUserInvited         = "user.invited"
+	EventUserAccessGranted   = "user.access_granted"
+	EventPasswordReset       = "password.reset"
+	EventAccountLocked       = "account.locked"
+	EventClientVerified      = "client.verified"
+	EventClientRejected      = "client.rejected"
+	EventPaymentApproved     = "payment.approved"
+	EventPaymentRejected     = "payment.rejected"
+	EventSalaryBatchReviewed = "salary_batch.reviewed"
+)
+
+var EventTypes = []string{
+	EventUserInvited, EventUserAccessGranted, EventPasswordReset, EventAccountLocked,
+	EventClientVerified, EventClientRejected, EventPaymentApproved, EventPaymentRejected, EventSalaryBatchReviewed,
+}
+
+// MandatoryEvents are always emailed, whatever the preferences: turning them off would lock
+// the user out.
+var MandatoryEvents = []string{EventUserInvited, EventPasswordReset, EventAccountLocked}
+
+// AllEvents is the event type of a preference that applies to every event.
+var AllEvents = "*"
+
+var StatusSent = "Sent"
+var StatusFailed = "Failed"
+
+// Recipient is who a notification is for. A user gets it on the channels its preferences
+// turn on, at the addresses of its account; an address that is not a user's (a client's
+// contact email) only gets the email.
+type Recipient struct {
+	UserID uint
+	Email  string
+}
+
+// Notification records one message sent to one recipient over one channel, and whether the
+// channel took it. The body is not kept, it may hold a token.
+type Notification struct {
+	ID             uint       `gorm:"primary_key" json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	IdempotencyKey string     `gorm:"index" json:"idempotency_key,omitempty"` // sending again with the same key skips what was sent
+	UserID         uint       `gorm:"index" json:"user_id,omitempty"`
+	EventType      string     `gorm:"not null;index" json:"event_type"`
+	Channel        string     `gorm:"not null" json:"channel"`
+	Recipient      string     `gorm:"not null" json:"recipient"`
+	Subject        string     `json:"subject"`
+	Status         string     `gorm:"not null;index" json:"status"`
+	Error          string     `gorm:"type:text" json:"error,omitempty"`
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+}
+
+// NotificationPreference turns a channel on or off for a user, for one event type or, with
+// the event type AllEvents, for those without their own preference. Without any, email is
+// on and SMS off.
+type NotificationPreference struct {
+	ID        uint      `gorm:"primary_key" json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uint      `gorm:"not null;unique_index:idx_notification_preference" json:"-"`
+	EventType string    `gorm:"not null;unique_index:idx_notification_preference" json:"event_type" validate:"required"`
+	Channel   string    `gorm:"not null;unique_index:idx_notification_preference" json:"channel" validate:"required"`
+	Enabled   bool      `json:"enabled"`
+}
+
+// NotificationPreferencesDTO is what a user reads and sets: the phone number text messages
+// go to and the preferences, the events and channels there are to choose from.
+type NotificationPreferencesDTO struct {
+	Phone       *string                  `json:"phone" validate:"omitempty,e164"`
+	Preferences []NotificationPreference `json:"preferences" validate:"dive"`
+	EventTypes  []string                 `json:"event_types,omitempty"`
+	Channels    []string                 `json:"channels,omitempty"`
+}
@@ -0,0 +1,13 @@
+package notification
+
+import "github.com/jinzhu/gorm"
+
+type NotificationConfig struct {
+	DB *gorm.DB
+}
+
+func (config *NotificationConfig) TableMigration() {
+	config.DB.AutoMigrate(&Notification{}, &NotificationPreference{})
+
+	config.DB.Model(&NotificationPreference{}).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE")
+}
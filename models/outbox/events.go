@@ -0,0 +1,57 @@
+package outbox
+
+import "bankManagement/utils/money"
+
+// Event types, named <aggregate>.<what happened>.
+var (
+	EventClientCreated       = "client.created"
+	EventClientVerified      = "client.verified"
+	EventClientRejected      = "client.rejected"
+	EventPaymentApproved     = "payment.approved"
+	EventPaymentRejected     = "payment.rejected"
+	EventSalaryDisbursed     = "salary.disbursed"
+	EventSalaryBatchReviewed = "salary_batch.reviewed"
+)
+
+// Event is what a service publishes: the type, the row it is about and the payload, one of
+// the structs below.
+type Event struct {
+	Type          string
+	AggregateType string
+	AggregateId   uint
+	BankId        uint
+	Payload       interface{}
+}
+
+type ClientEvent struct {
+	ClientId    uint   `json:"client_id"`
+	BankId      uint   `json:"bank_id"`
+	ClientName  string `json:"client_name"`
+	ClientEmail string `json:"client_email"`
+	ActorUserId uint   `json:"actor_user_id"`
+	Reason      string `json:"reason,omitempty"` // why KYC was rejected
+}
+
+type PaymentEvent struct {
+	PaymentRequestId uint         `json:"payment_request_id"`
+	PaymentId        uint         `json:"payment_id,omitempty"` // the payment made on approval
+	SenderClientId   uint         `json:"sender_client_id"`
+	ReceiverClientId uint         `json:"receiver_client_id"`
+	BankId           uint         `json:"bank_id"`
+	Amount           money.Money  `json:"amount"`
+	ReceivedAmount   *money.Money `json:"received_amount,omitempty"`
+	ActorUserId      uint         `json:"actor_user_id"`
+	Reason           string       `json:"reason,omitempty"` // why the payment was rejected
+}
+
+// SalaryBatchEvent is published for each review of a batch, salary.disbursed when the
+// review paid lines and salary_batch.reviewed once the batch is closed.
+type SalaryBatchEvent struct {
+	BatchId     uint        `json:"batch_id"`
+	ClientId    uint        `json:"client_id"`
+	BankId      uint        `json:"bank_id"`
+	Status      string      `json:"status"`
+	PaidLines   int         `json:"paid_lines"`
+	PaidAmount  money.Money `json:"paid_amount"`
+	ActorUserId uint        `json:"actor_user_id"`
+}
@@ -0,0 +1,54 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+var EventStatusPending = "Pending"
+var EventStatusDelivered = "Delivered"
+var EventStatusFailed = "Failed" // gave up after constants.OutboxMaxAttempts, retried by hand
+
+// OutboxEvent is a domain event written in the transaction of the change it announces, so
+// it exists if and only if the change was committed. The dispatcher then hands it to every
+// subscriber of its type until each one has taken it.
+type OutboxEvent struct {
+	ID            uint       `gorm:"primary_key" json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	EventType     string     `gorm:"not null;index" json:"event_type"`
+	AggregateType string     `gorm:"not null;index:idx_outbox_aggregate" json:"aggregate_type"` // the table of the row the event is about
+	AggregateId   uint       `gorm:"index:idx_outbox_aggregate" json:"aggregate_id"`
+	BankId        uint       `gorm:"index" json:"bank_id"`
+	Payload       string     `gorm:"type:mediumtext" json:"-"`
+	Status        string     `gorm:"not null;index:idx_outbox_due" json:"status"`
+	NextAttemptAt time.Time  `gorm:"index:idx_outbox_due" json:"next_attempt_at"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Decode reads the payload into the event's payload struct.
+func (event *OutboxEvent) Decode(payload interface{}) error {
+	return json.Unmarshal([]byte(event.Payload), payload)
+}
+
+// MarshalJSON shows the payload as JSON rather than as a string.
+func (event OutboxEvent) MarshalJSON() ([]byte, error) {
+	type plain OutboxEvent
+	payload := json.RawMessage(event.Payload)
+	if event.Payload == "" {
+		payload = json.RawMessage("null")
+	}
+	return json.Marshal(struct {
+		plain
+		Payload json.RawMessage `json:"payload"`
+	}{plain(event), payload})
+}
+
+// OutboxDelivery records that a subscriber took an event, so a retry of the event skips it.
+type OutboxDelivery struct {
+	ID         uint      `gorm:"primary_key"`
+	CreatedAt  time.Time `gorm:"not null"`
+	EventID    uint      `gorm:"not null;unique_index:idx_outbox_delivery"`
+	Subscriber string    `gorm:"not null;unique_index:idx_outbox_delivery"`
+}
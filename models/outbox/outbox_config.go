@@ -0,0 +1,13 @@
+package outbox
+
+import "github.com/jinzhu/gorm"
+
+type OutboxConfig struct {
+	DB *gorm.DB
+}
+
+func (config *OutboxConfig) TableMigration() {
+	config.DB.AutoMigrate(&OutboxEvent{}, &OutboxDelivery{})
+
+	config.DB.Model(&OutboxDelivery{}).AddForeignKey("event_id", "outbox_events(id)", "CASCADE", "CASCADE")
+}
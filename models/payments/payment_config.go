@@ -1,6 +1,10 @@
 package payments
 
-import "github.com/jinzhu/gorm"
+import (
+	"bankManagement/utils/money"
+
+	"github.com/jinzhu/gorm"
+)
 
 type PaymentConfig struct {
 	DB *gorm.DB
@@ -8,4 +12,15 @@ type PaymentConfig struct {
 
 func (tconf *PaymentConfig) TableMigration() {
 	tconf.DB.AutoMigrate(&Payment{}, &PaymentRequest{})
+	if err := money.MigrateFloatColumn(tconf.DB, "payments", "payment_amount", "BIGINT NOT NULL"); err != nil {
+		panic(err)
+	}
+	if err := money.MigrateFloatColumn(tconf.DB, "payment_requests", "amount", "BIGINT NOT NULL"); err != nil {
+		panic(err)
+	}
+	// payments made before multi-currency support were received in the sender's currency
+	tconf.DB.Exec("UPDATE payments SET received_amount = payment_amount WHERE received_amount = 0")
+	tconf.DB.Exec("UPDATE payment_requests SET received_amount = amount WHERE received_amount = 0 AND status = 'Approved'")
+	// resolved used to default to true, pending requests included
+	tconf.DB.Exec("UPDATE payment_requests SET resolved = false WHERE status = 'Pending'")
 }
@@ -4,7 +4,9 @@ import (
 	"bankManagement/models/bank"
 	"bankManagement/models/client"
 	"bankManagement/models/transaction"
+	"bankManagement/utils/money"
 	"os/user"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
@@ -21,7 +23,11 @@ type Payment struct {
 	CreditTransaction   transaction.Transaction `gorm:"foreignkey:CreditTransactionID"`
 	DebitTransactionID  uint                    `gorm:"not null"`
 	DebitTransaction    transaction.Transaction `gorm:"foreignkey:DebitTransactionID"`
-	PaymentAmount       float64                 `gorm:"not null"`
+	PaymentAmount       money.Money             `gorm:"type:bigint;not null"`
+	Currency            string                  `gorm:"type:char(3);default:'INR';not null"` // sender's currency
+	ReceivedAmount      money.Money             `gorm:"type:bigint;not null"`                // PaymentAmount converted to the receiver's currency
+	ReceivedCurrency    string                  `gorm:"type:char(3);default:'INR';not null"`
+	ExchangeRate        string                  `gorm:"default:'1';not null"` // rate applied at approval
 
 	Status           string    `gorm:"default:'Pending'"` //// 'Pending', 'Approved', 'Rejected'
 	CreatedByUserId  uint      `gorm:"not null"`
@@ -38,22 +44,57 @@ type PaymentRequest struct {
 	ReceiverClient   client.Client `gorm:"foreignkey:ReceiverClientID"  json:"-"`
 	AuthorizerBankId uint          `gorm:"not null"`
 	AuthorizedBank   bank.Bank     `gorm:"foreignkey:AuthorizedBankID"  json:"-"`
-	PaymentAmount    float64       `gorm:"not null; column:amount"`
-	Status           string        `gorm:"default:'Pending'"` // Approve or Reject Payment - BankUser will decide
-	Resolved         bool          `gorm:"default:true"`
+	PaymentAmount    money.Money   `gorm:"type:bigint;not null; column:amount"`
+	Currency         string        `gorm:"type:char(3);default:'INR';not null"` // sender's currency
+	ReceivedAmount   money.Money   `gorm:"type:bigint;not null"`                // set on approval, in the receiver's currency
+	ReceivedCurrency string        `gorm:"type:char(3);default:'INR';not null"`
+	Status           string        `gorm:"default:'Pending'"` // Approve or Reject Payment - BankUser will decide, or the client cancels it
+	Resolved         bool          // once approved, rejected or cancelled
 	CreatedByUserId  uint          `gorm:"not null"`
 	CreatedByUser    user.User     `gorm:"foreignkey:CreatedByUserId"  json:"-"`
-	PostApprovalNote string        `gorm:"default:null"`
+	PostApprovalNote string        `gorm:"default:null"` // the reason of a rejection
+	ResolvedByUserId uint          `gorm:"default:null"` // the bank user who approved or rejected it, the client user who cancelled it
+	ResolvedAt       *time.Time
 }
+
+func (p *Payment) BeforeSave() error {
+	p.Currency = p.PaymentAmount.CurrencyCode()
+	p.ReceivedCurrency = p.ReceivedAmount.CurrencyCode()
+	return nil
+}
+
+func (p *Payment) AfterFind() error {
+	p.PaymentAmount.Currency = p.Currency
+	p.ReceivedAmount.Currency = p.ReceivedCurrency
+	return nil
+}
+
+func (p *PaymentRequest) BeforeSave() error {
+	p.Currency = p.PaymentAmount.CurrencyCode()
+	p.ReceivedCurrency = p.ReceivedAmount.CurrencyCode()
+	return nil
+}
+
+func (p *PaymentRequest) AfterFind() error {
+	p.PaymentAmount.Currency = p.Currency
+	p.ReceivedAmount.Currency = p.ReceivedCurrency
+	return nil
+}
+
 type PaymentRequestDTO struct {
-	PaymentAmount float64 `json:"amount"`
-	BeneficiaryId uint    `json:"beneficiary_id"`
+	PaymentAmount money.Money `json:"amount" validate:"gt=0"`
+	BeneficiaryId uint        `json:"beneficiary_id"`
+}
+
+// PaymentRejectionDTO is the body of a payment request rejection.
+type PaymentRejectionDTO struct {
+	Reason string `json:"reason" validate:"required,max=500"`
 }
 
 type PaymentResponseDTO struct {
-	PaymentAmount float64 `json:"amount"`
-	ClientId      uint    `json:"client_id"`
-	PaymentId     uint    `json:"payment_id"`
-	PaymentStatus uint    `json:"payment_status"`
-	Timestamp     string  `json:"created_at"`
+	PaymentAmount money.Money `json:"amount"`
+	ClientId      uint        `json:"client_id"`
+	PaymentId     uint        `json:"payment_id"`
+	PaymentStatus uint        `json:"payment_status"`
+	Timestamp     string      `json:"created_at"`
 }
@@ -0,0 +1,59 @@
+package payroll
+
+import (
+	"bankManagement/models/client"
+	"bankManagement/models/salaryDisbursement"
+	"bankManagement/utils/money"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PayrollSchedule makes the scheduler submit the salaries of all the client's employees
+// for bank approval at a fixed cadence. NextRunAt is the start of the next period to be paid.
+type PayrollSchedule struct {
+	gorm.Model
+	ClientID        uint          `gorm:"not null;index" json:"client_id"`
+	Client          client.Client `gorm:"foreignkey:ClientID" json:"-"`
+	Frequency       string        `gorm:"not null" json:"frequency" validate:"required,oneof=Monthly Biweekly Weekly"`
+	DayOfMonth      int           `json:"day_of_month,omitempty" validate:"omitempty,min=1,max=31"` // Monthly only, clamped to the month's last day
+	StartDate       time.Time     `gorm:"not null" json:"start_date" validate:"required"`
+	Strategy        string        `gorm:"default:'AllOrNothing';not null" json:"strategy" validate:"omitempty,oneof=AllOrNothing Priority"`
+	NextRunAt       time.Time     `gorm:"not null;index" json:"next_run_at"`
+	IsActive        bool          `gorm:"not null" json:"is_active"`
+	CreatedByUserId uint          `gorm:"not null" json:"created_by_user_id"`
+}
+
+// PayrollRun records one execution of a schedule for one period. The unique
+// (schedule_id, period_key) index guarantees a period is never paid twice.
+type PayrollRun struct {
+	gorm.Model
+	ScheduleID    uint                                    `gorm:"not null;unique_index:idx_schedule_period" json:"schedule_id"`
+	ClientID      uint                                    `gorm:"not null;index" json:"client_id"`
+	PeriodKey     string                                  `gorm:"not null;unique_index:idx_schedule_period" json:"period_key"`
+	PeriodStart   time.Time                               `gorm:"not null" json:"period_start"`
+	PeriodEnd     time.Time                               `gorm:"not null" json:"period_end"`
+	Status        string                                  `gorm:"not null" json:"status"`
+	FailureReason string                                  `json:"failure_reason,omitempty"`
+	BatchID       uint                                    `gorm:"index" json:"batch_id,omitempty"` // the salary batch submitted for bank approval
+	TotalAmount   money.Money                             `gorm:"type:bigint;not null" json:"total_amount"`
+	Currency      string                                  `gorm:"type:char(3);default:'INR';not null" json:"-"`
+	Disbursements []salaryDisbursement.SalaryDisbursement `gorm:"foreignkey:PayrollRunID" json:"disbursements,omitempty"`
+}
+
+func (r *PayrollRun) BeforeSave() error {
+	r.Currency = r.TotalAmount.CurrencyCode()
+	return nil
+}
+
+func (r *PayrollRun) AfterFind() error {
+	r.TotalAmount.Currency = r.Currency
+	return nil
+}
+
+var FrequencyMonthly = "Monthly"
+var FrequencyBiweekly = "Biweekly"
+var FrequencyWeekly = "Weekly"
+
+var RunStatusSubmitted = "Submitted"
+var RunStatusFailed = "Failed"
@@ -0,0 +1,15 @@
+package payroll
+
+import "github.com/jinzhu/gorm"
+
+type PayrollConfig struct {
+	DB *gorm.DB
+}
+
+func (config *PayrollConfig) TableMigration() {
+	config.DB.AutoMigrate(&PayrollSchedule{}, &PayrollRun{})
+
+	config.DB.Model(&PayrollSchedule{}).AddForeignKey("client_id", "clients(id)", "CASCADE", "CASCADE")
+	config.DB.Model(&PayrollRun{}).AddForeignKey("schedule_id", "payroll_schedules(id)", "CASCADE", "CASCADE")
+	config.DB.Model(&PayrollRun{}).AddForeignKey("client_id", "clients(id)", "CASCADE", "CASCADE")
+}
@@ -1,22 +1,24 @@
 package reports
 
+import "bankManagement/utils/money"
+
 type EmployeePaymentDTO struct {
 	EmpId           uint
-	SalaryDisbursed float64
-	MonthlySalary   float64
+	SalaryDisbursed money.Money
+	MonthlySalary   money.Money
 }
 type ClientPaymentDTO struct {
 	ClientID                         uint
 	ClientName                       string
 	CreatedBy                        uint
-	TotalPaymentSentByThisClient     float64
-	TotalPaymentReceivedByThisClient float64
+	TotalPaymentSentByThisClient     money.Money
+	TotalPaymentReceivedByThisClient money.Money
 }
 
 type SalaryReport struct {
-	AverageSalary                  float64
-	TotalSalaryDisbursed           float64
-	ExpectedMonthlySalaryDisbursal float64
+	AverageSalary                  money.Money
+	TotalSalaryDisbursed           money.Money
+	ExpectedMonthlySalaryDisbursal money.Money
 	TotalEmployees                 int
 	EmployeeDisbursementData       []EmployeePaymentDTO
 	StartDate                      string
@@ -26,11 +28,11 @@ type SalaryReport struct {
 }
 
 type PaymentReport struct {
-	AveragePaymentValue       float64
+	AveragePaymentValue       money.Money
 	TotalPaymentsSent         int
 	TotalPaymentsReceived     int
-	TotalPaymentReceivedValue float64
-	TotalPaymentSentValue     float64
+	TotalPaymentReceivedValue money.Money
+	TotalPaymentSentValue     money.Money
 	ApprovedPaymentRequests   int
 	RejectedPaymentRequests   int
 	TotalPaymentRequests      int
@@ -0,0 +1,91 @@
+package salaryDisbursement
+
+import (
+	"bankManagement/models/client"
+	"bankManagement/utils/money"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SalaryBatch groups the salary lines a client submits in one go. Nothing is paid until a
+// user of the client's bank reviews it; each line is then approved (paid) or rejected on
+// its own. The batch stays Pending until every line is resolved, then becomes Approved if
+// at least one line was paid and Rejected otherwise.
+type SalaryBatch struct {
+	gorm.Model
+	ClientID         uint                 `gorm:"not null;index" json:"client_id"`
+	Client           client.Client        `gorm:"foreignkey:ClientID" json:"-"`
+	BankID           uint                 `gorm:"not null;index" json:"bank_id"`
+	Status           string               `gorm:"default:'Pending';not null" json:"status"`
+	Strategy         string               `gorm:"default:'AllOrNothing';not null" json:"strategy"`
+	TotalAmount      money.Money          `gorm:"type:bigint;not null" json:"total_amount"`
+	Currency         string               `gorm:"type:char(3);default:'INR';not null" json:"-"`
+	CreatedByUserId  uint                 `gorm:"not null" json:"created_by_user_id"`
+	ReviewedByUserId uint                 `json:"reviewed_by_user_id,omitempty"`
+	ReviewNote       string               `json:"review_note,omitempty"`
+	Lines            []SalaryDisbursement `gorm:"foreignkey:BatchID" json:"lines,omitempty"`
+}
+
+func (b *SalaryBatch) BeforeSave() error {
+	b.Currency = b.TotalAmount.CurrencyCode()
+	return nil
+}
+
+func (b *SalaryBatch) AfterFind() error {
+	b.TotalAmount.Currency = b.Currency
+	return nil
+}
+
+// DisburseSalaryDTO picks how a batch is paid. AllOrNothing (the default) pays every line or
+// none, Priority pays lines by ascending employee priority until the funds run out, Subset
+// only puts EmployeeIDs in the batch and then behaves like AllOrNothing.
+type DisburseSalaryDTO struct {
+	Strategy    string `json:"strategy" validate:"omitempty,oneof=AllOrNothing Priority Subset"`
+	EmployeeIDs []uint `json:"employee_ids"`
+}
+
+// SalaryBatchReport is the per-employee outcome of submitting or reviewing a batch.
+type SalaryBatchReport struct {
+	Batch   *SalaryBatch            `json:"batch"`
+	Results []DisbursementResultDTO `json:"results"`
+}
+
+type DisbursementResultDTO struct {
+	EmployeeID   uint        `json:"employee_id"`
+	EmployeeName string      `json:"employee_name"`
+	Amount       money.Money `json:"amount"`
+	Result       string      `json:"result"` // Pending, Paid or Skipped
+	Reason       string      `json:"reason,omitempty"`
+}
+
+// SalaryBatchReviewDTO is the bank user's decision. Action (taken from the approve/reject
+// route) applies to every pending line, or only to LineIDs when given, so a batch can be
+// resolved over several reviews.
+type SalaryBatchReviewDTO struct {
+	Action  string `json:"-"`
+	LineIDs []uint `json:"line_ids"`
+	Note    string `json:"note"`
+}
+
+var StrategyAllOrNothing = "AllOrNothing"
+var StrategyPriority = "Priority"
+var StrategySubset = "Subset"
+
+var ResultPending = "Pending"
+var ResultPaid = "Paid"
+var ResultSkipped = "Skipped"
+
+var ReviewActionApprove = "Approve"
+var ReviewActionReject = "Reject"
+
+// ResultOf reports a line status as the outcome shown to the client.
+func ResultOf(status string) string {
+	switch status {
+	case DisbursementStatusApproved:
+		return ResultPaid
+	case DisbursementStatusRejected:
+		return ResultSkipped
+	default:
+		return ResultPending
+	}
+}
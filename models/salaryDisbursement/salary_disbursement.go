@@ -4,6 +4,7 @@ import (
 	"bankManagement/models/client"
 	"bankManagement/models/employee"
 	"bankManagement/models/user"
+	"bankManagement/utils/money"
 
 	"github.com/jinzhu/gorm"
 )
@@ -14,13 +15,27 @@ type SalaryDisbursement struct {
 	Client          client.Client     `gorm:"foreignkey:ClientID"`
 	EmpID           uint              `gorm:"not null"`
 	Employee        employee.Employee `gorm:"foreignkey:EmpID"`
-	TransactionID   uint              `gorm:"not null"`
-	SalaryAmount    float64           `gorm:"not null"`
+	TransactionID   uint              `gorm:"not null"` // set once the line is paid
+	PayrollRunID    uint              `gorm:"index"`    // 0 when disbursed manually
+	BatchID         uint              `gorm:"index"`
+	SalaryAmount    money.Money       `gorm:"type:bigint;not null"`
+	Currency        string            `gorm:"type:char(3);default:'INR';not null"`
 	Status          string            `gorm:"default:'Pending'"`
+	StatusReason    string            // why a line was skipped (rejected)
 	CreatedByUserId uint              `gorm:"not null"`
 	CreatedByUser   user.User         `gorm:"foreignkey:CreatedByUserId"`
 }
 
+func (d *SalaryDisbursement) BeforeSave() error {
+	d.Currency = d.SalaryAmount.CurrencyCode()
+	return nil
+}
+
+func (d *SalaryDisbursement) AfterFind() error {
+	d.SalaryAmount.Currency = d.Currency
+	return nil
+}
+
 var DisbursementStatusApproved = "Approved"
 var DisbursementStatusPending = "Pending"
 var DisbursementStatusRejected = "Rejected"
@@ -1,11 +1,18 @@
 package salaryDisbursement
 
-import "github.com/jinzhu/gorm"
+import (
+	"bankManagement/utils/money"
+
+	"github.com/jinzhu/gorm"
+)
 
 type SalaryDisbursementConfig struct {
 	DB *gorm.DB
 }
 
 func (tconf *SalaryDisbursementConfig) TableMigration() {
-	tconf.DB.AutoMigrate(&SalaryDisbursement{})
+	tconf.DB.AutoMigrate(&SalaryDisbursement{}, &SalaryBatch{})
+	if err := money.MigrateFloatColumn(tconf.DB, "salary_disbursements", "salary_amount", "BIGINT NOT NULL"); err != nil {
+		panic(err)
+	}
 }
@@ -2,6 +2,7 @@ package transaction
 
 import (
 	"bankManagement/models/client"
+	"bankManagement/utils/money"
 
 	"github.com/jinzhu/gorm"
 )
@@ -12,13 +13,25 @@ type Transaction struct {
 	Client            client.Client `gorm:"foreignkey:ClientID;association_foreignkey:ID" json:"client"`
 	PaymentType       string        `gorm:"not null" json:"payment_type"`     // Deposit, Withdraw, Transfer
 	TransactionType   string        `gorm:"not null" json:"transaction_type"` // Credit, Debit
-	TransactionAmount float64       `gorm:"not null" json:"transaction_amount"`
+	TransactionAmount money.Money   `gorm:"type:bigint;not null" json:"transaction_amount"`
+	Currency          string        `gorm:"type:char(3);default:'INR';not null" json:"-"`
 	TransactionStatus string        `gorm:"default:'Pending';not null" json:"transaction_status"`
 }
 
+func (t *Transaction) BeforeSave() error {
+	t.Currency = t.TransactionAmount.CurrencyCode()
+	return nil
+}
+
+func (t *Transaction) AfterFind() error {
+	t.TransactionAmount.Currency = t.Currency
+	return nil
+}
+
 var TransactionDebit = "Debit"
 var TransactionCredit = "Credit"
 
 var TransactionStatusPending = "Pending"
 var TransactionStatusApproved = "Approved"
 var TransactionStatusRejected = "Rejected"
+var TransactionStatusCancelled = "Cancelled" // a payment request the client withdrew before review
@@ -1,6 +1,8 @@
 package transaction
 
 import (
+	"bankManagement/utils/money"
+
 	"github.com/jinzhu/gorm"
 )
 
@@ -10,6 +12,9 @@ type TransactionConfig struct {
 
 func (config *TransactionConfig) TableMigration() {
 	config.DB.AutoMigrate(&Transaction{})
+	if err := money.MigrateFloatColumn(config.DB, "transactions", "transaction_amount", "BIGINT NOT NULL"); err != nil {
+		panic(err)
+	}
 
 	config.DB.Model(&Transaction{}).AddForeignKey("client_id", "clients(id)", "CASCADE", "CASCADE")
 }
@@ -0,0 +1,42 @@
+package user
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Invitation lets an invited user set a password on first login. Only the SHA-256 of the
+// emailed token is stored.
+type Invitation struct {
+	gorm.Model
+	UserID          uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash       string     `gorm:"type:char(64);unique_index;not null" json:"-"`
+	ExpiresAt       time.Time  `gorm:"not null" json:"expires_at"`
+	AcceptedAt      *time.Time `json:"accepted_at"`
+	InvitedByUserId uint       `json:"invited_by_user_id"`
+}
+
+type InviteUserDTO struct {
+	Username string `json:"username" validate:"required,max=50"` // ignored when the email belongs to an existing user
+	Name     string `json:"name" validate:"required,max=100"`
+	Email    string `json:"email" validate:"required,email"`
+	RoleID   uint   `json:"role_id"` // defaults to the built-in role of the bank or client
+}
+
+type AcceptInvitationDTO struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// MemberDTO is a user of a bank or client as listed to its administrators.
+type MemberDTO struct {
+	UserID            uint   `json:"user_id"`
+	Username          string `json:"username"`
+	Name              string `json:"name"`
+	Email             string `json:"email"`
+	RoleID            uint   `json:"role_id"`
+	RoleName          string `json:"role_name"`
+	IsActive          bool   `json:"is_active"`          // membership active, the user can log in to this bank or client
+	InvitationPending bool   `json:"invitation_pending"` // the password was never set
+}
@@ -0,0 +1,26 @@
+package user
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// LoginThrottle counts the recent failed logins of a username or of an IP address, keyed
+// "user:<username>" or "ip:<address>", and until when the next attempt has to wait.
+type LoginThrottle struct {
+	gorm.Model
+	ThrottleKey  string     `gorm:"type:varchar(191);unique_index;not null" json:"throttle_key"`
+	FailedCount  int        `json:"failed_count"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	BlockedUntil *time.Time `json:"blocked_until"`
+}
+
+func UsernameThrottleKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func IPThrottleKey(ipAddress string) string {
+	return "ip:" + ipAddress
+}
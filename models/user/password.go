@@ -0,0 +1,38 @@
+package user
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PasswordHistory keeps the hashes of a user's previous passwords so they are not reused.
+type PasswordHistory struct {
+	gorm.Model
+	UserID       uint   `gorm:"not null;index" json:"user_id"`
+	PasswordHash string `gorm:"not null" json:"-"`
+}
+
+// PasswordResetToken is the one-time token a forgot-password request emails. Only the
+// SHA-256 of the token is stored.
+type PasswordResetToken struct {
+	gorm.Model
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"type:char(64);unique_index;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordDTO struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
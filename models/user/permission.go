@@ -0,0 +1,138 @@
+package user
+
+// Role scopes: which tenant the users of a role act for. Admin users manage the whole
+// system, bank and client users are bound to their bank or client.
+var RoleScopeAdmin = "admin"
+var RoleScopeBank = "bank"
+var RoleScopeClient = "client"
+
+// names of the built-in roles, seeded with every permission of their scope
+var RoleSuperAdmin = "SUPER_ADMIN"
+var RoleBankUser = "BANK_USER"
+var RoleClientUser = "CLIENT_USER"
+
+// Permissions, granted to users through their role and declared by each route.
+var (
+	PermissionBankRead         = "bank.read"
+	PermissionBankWrite        = "bank.write"
+	PermissionFxRead           = "fx.read"
+	PermissionFxWrite          = "fx.write"
+	PermissionRoleRead         = "role.read"
+	PermissionRoleWrite        = "role.write"
+	PermissionUserUnlock       = "user.unlock"
+	PermissionAuditRead        = "audit.read"
+	PermissionEventRead        = "event.read"
+	PermissionEventRetry       = "event.retry"
+	PermissionNotificationRead = "notification.read"
+
+	PermissionClientRead         = "client.read"
+	PermissionClientCreate       = "client.create"
+	PermissionClientUpdate       = "client.update"
+	PermissionClientDelete       = "client.delete"
+	PermissionKycReview          = "kyc.review"
+	PermissionKycConfigure       = "kyc.configure"
+	PermissionDocumentRead       = "document.read"
+	PermissionDocumentWrite      = "document.write"
+	PermissionPaymentRequestRead = "payment_request.read"
+	PermissionPaymentApprove     = "payment.approve"
+	PermissionSalaryBatchRead    = "salary_batch.read"
+	PermissionSalaryBatchApprove = "salary_batch.approve"
+	PermissionLedgerRead         = "ledger.read"
+	PermissionTransactionRead    = "transaction.read"
+	PermissionBankUserRead       = "bank_user.read"
+	PermissionBankUserWrite      = "bank_user.write"
+	PermissionBankSecurityWrite  = "bank_security.write"
+	PermissionBankAuditRead      = "bank_audit.read"
+
+	PermissionEmployeeRead     = "employee.read"
+	PermissionEmployeeWrite    = "employee.write"
+	PermissionSalaryDisburse   = "salary.disburse"
+	PermissionReportRead       = "report.read"
+	PermissionBeneficiaryRead  = "beneficiary.read"
+	PermissionBeneficiaryWrite = "beneficiary.write"
+	PermissionPaymentRead      = "payment.read"
+	PermissionPaymentCreate    = "payment.create"
+	PermissionPaymentCancel    = "payment.cancel"
+	PermissionPayrollRead      = "payroll.read"
+	PermissionPayrollWrite     = "payroll.write"
+	PermissionClientUserRead   = "client_user.read"
+	PermissionClientUserWrite  = "client_user.write"
+	PermissionWebhookRead      = "webhook.read"
+	PermissionWebhookWrite     = "webhook.write"
+)
+
+type PermissionDefinition struct {
+	Name        string `json:"name"`
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// PermissionRegistry lists every permission a role can be granted.
+var PermissionRegistry = []PermissionDefinition{
+	{PermissionBankRead, RoleScopeAdmin, "List and view banks"},
+	{PermissionBankWrite, RoleScopeAdmin, "Create, update and delete banks"},
+	{PermissionFxRead, RoleScopeAdmin, "View exchange rates"},
+	{PermissionFxWrite, RoleScopeAdmin, "Set exchange rates"},
+	{PermissionRoleRead, RoleScopeAdmin, "View roles and the permission registry"},
+	{PermissionRoleWrite, RoleScopeAdmin, "Create, update and delete custom roles and assign roles to users"},
+	{PermissionUserUnlock, RoleScopeAdmin, "View lockouts and unlock users locked by failed logins"},
+	{PermissionAuditRead, RoleScopeAdmin, "View and verify the audit log"},
+	{PermissionEventRead, RoleScopeAdmin, "View domain events and their delivery"},
+	{PermissionEventRetry, RoleScopeAdmin, "Retry domain events whose delivery failed"},
+	{PermissionNotificationRead, RoleScopeAdmin, "View the notifications sent to users and clients"},
+
+	{PermissionClientRead, RoleScopeBank, "List and view the bank's clients and their KYC status"},
+	{PermissionClientCreate, RoleScopeBank, "Onboard clients"},
+	{PermissionClientUpdate, RoleScopeBank, "Update clients"},
+	{PermissionClientDelete, RoleScopeBank, "Delete clients"},
+	{PermissionKycReview, RoleScopeBank, "Verify and reject clients"},
+	{PermissionKycConfigure, RoleScopeBank, "Configure the document types required for KYC"},
+	{PermissionDocumentRead, RoleScopeBank, "List, view and download client documents"},
+	{PermissionDocumentWrite, RoleScopeBank, "Upload and delete client documents"},
+	{PermissionPaymentRequestRead, RoleScopeBank, "View payment requests"},
+	{PermissionPaymentApprove, RoleScopeBank, "Approve and reject payment requests"},
+	{PermissionSalaryBatchRead, RoleScopeBank, "View salary batches"},
+	{PermissionSalaryBatchApprove, RoleScopeBank, "Approve and reject salary batches"},
+	{PermissionLedgerRead, RoleScopeBank, "View the ledger and client statements"},
+	{PermissionTransactionRead, RoleScopeBank, "View client transaction reports"},
+	{PermissionBankUserRead, RoleScopeBank, "List the bank's users"},
+	{PermissionBankUserWrite, RoleScopeBank, "Invite, deactivate and remove the bank's users"},
+	{PermissionBankSecurityWrite, RoleScopeBank, "Require two-factor authentication for the bank's users"},
+	{PermissionBankAuditRead, RoleScopeBank, "View the audit log of the bank"},
+
+	{PermissionEmployeeRead, RoleScopeClient, "List, view and export employees"},
+	{PermissionEmployeeWrite, RoleScopeClient, "Create, update, delete and import employees"},
+	{PermissionSalaryDisburse, RoleScopeClient, "Disburse salaries"},
+	{PermissionReportRead, RoleScopeClient, "View salary and payment reports"},
+	{PermissionBeneficiaryRead, RoleScopeClient, "List beneficiaries"},
+	{PermissionBeneficiaryWrite, RoleScopeClient, "Add and delete beneficiaries"},
+	{PermissionPaymentRead, RoleScopeClient, "List payments"},
+	{PermissionPaymentCreate, RoleScopeClient, "Make payments"},
+	{PermissionPaymentCancel, RoleScopeClient, "Cancel pending payment requests"},
+	{PermissionPayrollRead, RoleScopeClient, "View payroll schedules and runs"},
+	{PermissionPayrollWrite, RoleScopeClient, "Create, update and delete payroll schedules"},
+	{PermissionClientUserRead, RoleScopeClient, "List the client's users"},
+	{PermissionClientUserWrite, RoleScopeClient, "Invite, deactivate and remove the client's users"},
+	{PermissionWebhookRead, RoleScopeClient, "List webhook endpoints and their delivery log"},
+	{PermissionWebhookWrite, RoleScopeClient, "Register, update and delete webhook endpoints, rotate their secrets and redeliver"},
+}
+
+func LookupPermission(name string) (PermissionDefinition, bool) {
+	for _, definition := range PermissionRegistry {
+		if definition.Name == name {
+			return definition, true
+		}
+	}
+	return PermissionDefinition{}, false
+}
+
+// ScopePermissions returns every permission of a scope, what the built-in role of the scope holds.
+func ScopePermissions(scope string) []string {
+	var permissions []string
+	for _, definition := range PermissionRegistry {
+		if definition.Scope == scope {
+			permissions = append(permissions, definition.Name)
+		}
+	}
+	return permissions
+}
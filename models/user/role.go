@@ -6,5 +6,38 @@ import (
 
 type Role struct {
 	gorm.Model
-	RoleName string `gorm:"not null" json:"role_name"` //  'SUPER_ADMIN', 'BANK_USER', 'CLIENT_USER'
+	RoleName    string           `gorm:"not null" json:"role_name"` //  'SUPER_ADMIN', 'BANK_USER', 'CLIENT_USER' or a custom role
+	Scope       string           `gorm:"type:varchar(20)" json:"scope"`
+	Description string           `json:"description"`
+	IsSystem    bool             `gorm:"default:false" json:"is_system"` // built-in roles cannot be changed through the API
+	Permissions []RolePermission `gorm:"foreignkey:RoleID" json:"-"`
+}
+
+// RolePermission grants one permission of the registry to a role.
+type RolePermission struct {
+	ID         uint   `gorm:"primary_key" json:"-"`
+	RoleID     uint   `gorm:"not null;unique_index:idx_role_permission" json:"-"`
+	Permission string `gorm:"not null;unique_index:idx_role_permission" json:"permission"`
+}
+
+type RoleDTO struct {
+	ID          uint     `json:"id"`
+	RoleName    string   `json:"role_name" validate:"required,max=50"`
+	Scope       string   `json:"scope" validate:"required,oneof=bank client"` // only bank and client roles can be custom
+	Description string   `json:"description" validate:"max=255"`
+	IsSystem    bool     `json:"is_system"`
+	Permissions []string `json:"permissions" validate:"required,min=1,dive,required"`
+}
+
+type AssignRoleDTO struct {
+	RoleID uint `json:"role_id" validate:"required"`
+}
+
+// PermissionNames flattens the role's loaded permissions.
+func (role *Role) PermissionNames() []string {
+	names := make([]string, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		names = append(names, permission.Permission)
+	}
+	return names
 }
@@ -9,6 +9,7 @@ type RoleConfig struct {
 }
 
 func (config *RoleConfig) TableMigration() {
-	config.DB.AutoMigrate(&Role{})
+	config.DB.AutoMigrate(&Role{}, &RolePermission{})
+	config.DB.Model(&RolePermission{}).AddForeignKey("role_id", "roles(id)", "CASCADE", "CASCADE")
 
 }
@@ -0,0 +1,62 @@
+package user
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// TwoFactor is a user's TOTP authenticator. It is pending until a first code confirms it,
+// disabling it clears the secret so the row is reused on the next enrolment.
+type TwoFactor struct {
+	gorm.Model
+	UserID      uint       `gorm:"not null;unique_index" json:"user_id"`
+	Secret      string     `json:"-"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	LastCounter int64      `json:"-"` // time step of the last accepted code, a code works once
+}
+
+func (twoFactor *TwoFactor) IsEnabled() bool {
+	return twoFactor.ID != 0 && twoFactor.Secret != "" && twoFactor.ConfirmedAt != nil
+}
+
+// RecoveryCode stands in for a TOTP code when the authenticator is lost, once. Only the
+// SHA-256 of the code is stored.
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash string     `gorm:"type:char(64);not null" json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+type TwoFactorCodeDTO struct {
+	Code string `json:"code" validate:"required"` // a TOTP code or a recovery code
+}
+
+type TwoFactorLoginDTO struct {
+	PreAuthToken string `json:"pre_auth_token" validate:"required"`
+	Code         string `json:"code" validate:"required"`
+}
+
+// TwoFactorChallengeDTO answers a login whose password was right but that still needs a
+// code: the pre-auth token only works on the /auth/2fa endpoints.
+type TwoFactorChallengeDTO struct {
+	TwoFactorRequired  bool   `json:"two_factor_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"` // the bank requires 2FA and the user has not enrolled yet
+	PreAuthToken       string `json:"pre_auth_token,omitempty"`
+	ExpiresIn          int64  `json:"expires_in,omitempty"`
+}
+
+type TwoFactorEnrollmentDTO struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to show as a QR code
+}
+
+type RecoveryCodesDTO struct {
+	RecoveryCodes []string `json:"recovery_codes"` // shown once, only their hashes are kept
+}
+
+type TwoFactorLoginResponseDTO struct {
+	TokenResponseDTO
+	RecoveryCodes []string `json:"recovery_codes,omitempty"` // when the login confirmed a new enrolment
+}
@@ -12,19 +12,56 @@ type User struct {
 	Password string `gorm:"not null" json:"password" validate:"required"`
 	Name     string `json:"name" validate:"required"`
 	Email    string `gorm:"unique_index;not null" json:"email" validate:"required"` // Unique email
+	Phone    string `gorm:"type:varchar(32)" json:"phone,omitempty"`                // E.164, where text messages go
 	IsActive bool   `gorm:"default:true" json:"is_active"`
 	RoleID   uint   `gorm:"not null" json:"role_id"`
 	Role     Role   `gorm:"foreignkey:RoleID;association_foreignkey:ID" json:"role"`
+	// set after too many failed logins, the user cannot log in until an admin unlocks it
+	LockedAt *time.Time `json:"locked_at,omitempty"`
 }
 
 type UserLoginInfo struct {
 	gorm.Model
-	UserId     uint      `gorm:"not null" json:"user_id"`
-	UserName   string    `gorm:"not null" json:"username"`
-	IsActive   bool      `gorm:"default:true" json:"is_active"`
-	RoleID     uint      `gorm:"not null" json:"role_id"`
-	LoginTime  time.Time `gorm:"not null" json:"login_time"`
-	LogoutTime time.Time `gorm:"default:null" json:"logout_time"`
+	UserId     uint       `gorm:"not null" json:"user_id"`
+	UserName   string     `gorm:"not null" json:"username"`
+	IsActive   bool       `gorm:"default:true" json:"is_active"`
+	RoleID     uint       `gorm:"not null" json:"role_id"`
+	BankId     uint       `json:"bank_id,omitempty"` // the bank or client the session acts for, kept on refresh
+	ClientId   uint       `json:"client_id,omitempty"`
+	LoginTime  time.Time  `gorm:"not null" json:"login_time"`
+	LogoutTime *time.Time `gorm:"default:null" json:"logout_time"` // set when the session is closed, its tokens stop working
+}
+
+// UserLockoutEvent records a user locked out by failed logins, and who unlocked it.
+type UserLockoutEvent struct {
+	gorm.Model
+	UserId           uint       `gorm:"not null;index" json:"user_id"`
+	UserName         string     `gorm:"not null" json:"username"`
+	IPAddress        string     `json:"ip_address"` // of the attempt that locked the user
+	FailedAttempts   int        `json:"failed_attempts"`
+	LockedAt         time.Time  `gorm:"not null" json:"locked_at"`
+	UnlockedAt       *time.Time `json:"unlocked_at"`
+	UnlockedByUserId uint       `json:"unlocked_by_user_id,omitempty"`
+}
+
+// RefreshToken is one link of a login session's rotating refresh token chain. Only the
+// SHA-256 of the token is stored; a token presented a second time revokes the session.
+type RefreshToken struct {
+	gorm.Model
+	LoginSessionId uint       `gorm:"not null;index" json:"login_session_id"`
+	TokenHash      string     `gorm:"type:char(64);unique_index;not null" json:"-"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt         *time.Time `json:"used_at"`
+}
+
+type RefreshTokenDTO struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type TokenResponseDTO struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // seconds until the access token expires
 }
 type UserDTO struct {
 	Username string `json:"username"`
@@ -36,10 +73,15 @@ type UserDTO struct {
 type UserLoginParamDTO struct {
 	Username string ` json:"username"  validate:"required"` // unique username
 	Password string `json:"password"  validate:"required"`
+	BankId   uint   `json:"bank_id"`   // picks the bank when the user operates for several
+	ClientId uint   `json:"client_id"` // picks the client when the user works for several
+	// where the attempt comes from, set by the controller for brute-force tracking
+	IPAddress string `json:"-"`
 }
 
 type UserPermissionDTO struct {
-	BankId       uint `json:"bank_id"  ` // unique username
-	ClientId     uint `json:"client_id" `
-	IsSuperAdmin bool `json:"is_super_admin"`
+	BankId       uint     `json:"bank_id"  ` // unique username
+	ClientId     uint     `json:"client_id" `
+	IsSuperAdmin bool     `json:"is_super_admin"`
+	Permissions  []string `json:"permissions"` // granted by the user's role
 }
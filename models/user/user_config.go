@@ -9,7 +9,14 @@ type UserConfig struct {
 }
 
 func (config *UserConfig) TableMigration() {
-	config.DB.AutoMigrate(&User{}, &UserLoginInfo{})
+	config.DB.AutoMigrate(&User{}, &UserLoginInfo{}, &UserLockoutEvent{}, &LoginThrottle{}, &RefreshToken{}, &Invitation{}, &TwoFactor{}, &RecoveryCode{}, &PasswordHistory{}, &PasswordResetToken{})
+	config.DB.Model(&RefreshToken{}).AddForeignKey("login_session_id", "user_login_infos(id)", "CASCADE", "CASCADE")
+	config.DB.Model(&Invitation{}).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE")
+	config.DB.Model(&TwoFactor{}).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE")
+	config.DB.Model(&RecoveryCode{}).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE")
+	config.DB.Model(&PasswordHistory{}).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE")
+	config.DB.Model(&PasswordResetToken{}).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE")
+	config.DB.Model(&UserLockoutEvent{}).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE")
 	config.DB.Model(&User{}).AddForeignKey("role_id", "roles(id)", "CASCADE", "CASCADE")
 	// config.DB.Model(&UserLoginInfo{}).AddForeignKey("user_id", "users(id)", "SET NULL", "CASCADE")
 }
@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"bankManagement/models/client"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Webhook event types a client endpoint can subscribe to.
+var (
+	EventPaymentApproved     = "payment.approved"
+	EventPaymentRejected     = "payment.rejected"
+	EventPaymentReceived     = "payment.received" // a payment from another client was credited
+	EventSalaryDisbursed     = "salary.disbursed"
+	EventSalaryBatchReviewed = "salary_batch.reviewed"
+)
+
+var EventTypes = []string{EventPaymentApproved, EventPaymentRejected, EventPaymentReceived, EventSalaryDisbursed, EventSalaryBatchReviewed}
+
+var DeliveryStatusPending = "Pending"
+var DeliveryStatusSending = "Sending" // claimed by a dispatcher, until its next_attempt_at
+var DeliveryStatusDelivered = "Delivered"
+var DeliveryStatusFailed = "Failed"
+
+// WebhookEndpoint is a URL of a client's that the events it subscribed to are POSTed to,
+// signed with its secret.
+type WebhookEndpoint struct {
+	gorm.Model
+	ClientID        uint          `gorm:"not null;index" json:"client_id"`
+	Client          client.Client `gorm:"foreignkey:ClientID" json:"-"`
+	URL             string        `gorm:"type:varchar(2048);not null" json:"url"`
+	Description     string        `json:"description"`
+	EventTypes      string        `gorm:"not null" json:"-"` // comma separated
+	Secret          string        `gorm:"not null" json:"-"`
+	IsActive        bool          `gorm:"not null;default:true" json:"is_active"`
+	CreatedByUserId uint          `gorm:"not null" json:"created_by_user_id"`
+	EventTypeList   []string      `gorm:"-" json:"event_types"`
+}
+
+func (endpoint *WebhookEndpoint) AfterFind() error {
+	endpoint.EventTypeList = strings.Split(endpoint.EventTypes, ",")
+	return nil
+}
+
+func (endpoint *WebhookEndpoint) Subscribes(eventType string) bool {
+	for _, subscribed := range strings.Split(endpoint.EventTypes, ",") {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event to POST to one endpoint, retried with backoff until the
+// endpoint answers 2xx. It doubles as the delivery log.
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primary_key" json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	EndpointID     uint       `gorm:"not null;unique_index:idx_webhook_delivery_event" json:"endpoint_id"`
+	EventID        uint       `gorm:"not null;unique_index:idx_webhook_delivery_event" json:"event_id"` // the outbox event it comes from
+	EventType      string     `gorm:"not null;unique_index:idx_webhook_delivery_event" json:"event_type"`
+	Payload        string     `gorm:"type:mediumtext" json:"-"`
+	Status         string     `gorm:"not null;index:idx_webhook_delivery_due" json:"status"`
+	NextAttemptAt  time.Time  `gorm:"index:idx_webhook_delivery_due" json:"next_attempt_at"`
+	Attempts       int        `json:"attempts"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	ResponseBody   string     `gorm:"type:text" json:"response_body,omitempty"` // the start of it
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+type WebhookEndpointDTO struct {
+	URL         string   `json:"url" validate:"required,url,max=2048"`
+	Description string   `json:"description" validate:"max=255"`
+	EventTypes  []string `json:"event_types" validate:"required,min=1,dive,required"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+// WebhookSecretDTO carries the signing secret, only shown when created or rotated.
+type WebhookSecretDTO struct {
+	Endpoint *WebhookEndpoint `json:"endpoint"`
+	Secret   string           `json:"secret"`
+}
+
+// WebhookBody is what is POSTed to the endpoint.
+type WebhookBody struct {
+	EventID   uint        `json:"event_id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
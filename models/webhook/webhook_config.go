@@ -0,0 +1,14 @@
+package webhook
+
+import "github.com/jinzhu/gorm"
+
+type WebhookConfig struct {
+	DB *gorm.DB
+}
+
+func (config *WebhookConfig) TableMigration() {
+	config.DB.AutoMigrate(&WebhookEndpoint{}, &WebhookDelivery{})
+
+	config.DB.Model(&WebhookEndpoint{}).AddForeignKey("client_id", "clients(id)", "CASCADE", "CASCADE")
+	config.DB.Model(&WebhookDelivery{}).AddForeignKey("endpoint_id", "webhook_endpoints(id)", "CASCADE", "CASCADE")
+}
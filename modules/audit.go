@@ -0,0 +1,17 @@
+package modules
+
+import (
+	"bankManagement/app"
+	"bankManagement/components/audit/controller"
+	"bankManagement/components/audit/service"
+	"bankManagement/repository"
+)
+
+// RegisterAuditModule hooks the audit log into the repository, it must run before any
+// module writes.
+func RegisterAuditModule(appObj *app.App) {
+	auditService := service.NewAuditService(appObj.DB, appObj.Repository, appObj.Log)
+	repository.SetChangeRecorder(auditService)
+	auditController := controller.NewAuditController(auditService, appObj.Log)
+	auditController.RegisterRoutes(appObj.Router)
+}
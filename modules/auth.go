@@ -4,10 +4,25 @@ import (
 	"bankManagement/app"
 	"bankManagement/components/auth/controller"
 	"bankManagement/components/auth/service"
+	notificationService "bankManagement/components/notification/service"
+	"bankManagement/middlewares/auth"
+	"bankManagement/utils/encrypt"
 )
 
-func RegisterAuthModule(appObj *app.App) {
-	authService := service.NewAuthService(appObj.DB, appObj.Repository, appObj.Log)
+func RegisterAuthModule(appObj *app.App, notifications *notificationService.NotificationService) {
+	keys, err := encrypt.NewKeySetFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	encrypt.SetKeySet(keys)
+	passwordPolicy, err := encrypt.NewPasswordPolicyFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	encrypt.SetPasswordPolicy(passwordPolicy)
+	authService := service.NewAuthService(appObj.DB, appObj.Repository, appObj.Log, notifications)
+	auth.SetSessionValidator(authService.ValidateSession)
+	auth.SetStepUpVerifier(authService.VerifyStepUp)
 	authController := controller.NewAuthController(authService, appObj.Log)
 	authController.RegisterRoutes(appObj.Router)
 }
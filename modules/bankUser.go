@@ -4,11 +4,23 @@ import (
 	"bankManagement/app"
 	"bankManagement/components/bankUser/controller"
 	"bankManagement/components/bankUser/service"
+	fxService "bankManagement/components/fx/service"
+	ledgerService "bankManagement/components/ledger/service"
+	notificationService "bankManagement/components/notification/service"
+	outboxService "bankManagement/components/outbox/service"
+	"bankManagement/utils/storage"
 )
 
-func RegisterBankUserModule(appObj *app.App) {
+func RegisterBankUserModule(appObj *app.App, events *outboxService.OutboxService, notifications *notificationService.NotificationService) {
 
-	userService := service.NewBankUserService(appObj.DB, appObj.Repository, appObj.Log)
+	ledger := ledgerService.NewLedgerService(appObj.DB, appObj.Repository, appObj.Log)
+	rates := fxService.NewRateProvider(appObj.DB, appObj.Repository, appObj.Log)
+	store, err := storage.NewDocumentStore(appObj.Log)
+	if err != nil {
+		panic(err)
+	}
+	userService := service.NewBankUserService(appObj.DB, appObj.Repository, appObj.Log, ledger, rates, store, events, notifications)
+	userService.SubscribeNotifications(events)
 	userController := controller.NewBankUserController(userService, appObj.Log)
 	userController.RegisterRoutes(appObj.Router)
 }
@@ -4,14 +4,18 @@ import (
 	"bankManagement/app"
 	"bankManagement/components/client/controller"
 	"bankManagement/components/client/service"
+	idempotencyService "bankManagement/components/idempotency/service"
+	ledgerService "bankManagement/components/ledger/service"
 )
 
 func RegisterClientModule(appObj *app.App) {
-	clientService := service.NewClientService(appObj.DB, appObj.Repository, appObj.Log)
-	ClientController := controller.NewClientController(clientService, appObj.Log)
+	ledger := ledgerService.NewLedgerService(appObj.DB, appObj.Repository, appObj.Log)
+	idempotency := idempotencyService.NewIdempotencyService(appObj.DB, appObj.Repository, appObj.Log)
+	clientService := service.NewClientService(appObj.DB, appObj.Repository, appObj.Log, ledger)
+	ClientController := controller.NewClientController(clientService, idempotency, appObj.Log)
 	ClientController.RegisterRoutes(appObj.Router)
 
-	payementservice := service.NewPaymentService(appObj.DB, appObj.Repository, appObj.Log)
-	paymentController := controller.NewPaymentController(payementservice, appObj.Log)
+	payementservice := service.NewPaymentService(appObj.DB, appObj.Repository, appObj.Log, ledger)
+	paymentController := controller.NewPaymentController(payementservice, idempotency, appObj.Log)
 	paymentController.RegisterRoutes(appObj.Router)
 }
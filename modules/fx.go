@@ -0,0 +1,13 @@
+package modules
+
+import (
+	"bankManagement/app"
+	"bankManagement/components/fx/controller"
+	"bankManagement/components/fx/service"
+)
+
+func RegisterFxModule(appObj *app.App) {
+	fxService := service.NewFxService(appObj.DB, appObj.Repository, appObj.Log)
+	fxController := controller.NewFxController(fxService, appObj.Log)
+	fxController.RegisterRoutes(appObj.Router)
+}
@@ -0,0 +1,13 @@
+package modules
+
+import (
+	"bankManagement/app"
+	"bankManagement/components/ledger/controller"
+	"bankManagement/components/ledger/service"
+)
+
+func RegisterLedgerModule(appObj *app.App) {
+	ledgerService := service.NewLedgerService(appObj.DB, appObj.Repository, appObj.Log)
+	ledgerController := controller.NewLedgerController(ledgerService, appObj.Log)
+	ledgerController.RegisterRoutes(appObj.Router)
+}
@@ -2,15 +2,23 @@ package modules
 
 import (
 	"bankManagement/app"
+	"bankManagement/models/audit"
 	"bankManagement/models/bank"
 	"bankManagement/models/beneficiary"
 	"bankManagement/models/client"
 	"bankManagement/models/document"
 	"bankManagement/models/employee"
+	"bankManagement/models/fx"
+	"bankManagement/models/idempotency"
+	"bankManagement/models/ledger"
+	"bankManagement/models/notification"
+	"bankManagement/models/outbox"
 	"bankManagement/models/payments"
+	"bankManagement/models/payroll"
 	"bankManagement/models/salaryDisbursement"
 	"bankManagement/models/transaction"
 	"bankManagement/models/user"
+	"bankManagement/models/webhook"
 	"bankManagement/utils/encrypt"
 
 	"github.com/gorilla/mux"
@@ -25,13 +33,20 @@ type ModuleConfig interface {
 }
 
 func RegisterAllModules(appObj *app.App) {
-	RegisterAuthModule(appObj)
+	RegisterAuditModule(appObj)
+	events := RegisterOutboxModule(appObj)
+	notifications := RegisterNotificationModule(appObj)
+	RegisterAuthModule(appObj, notifications)
 	RegisterTestModule(appObj)
-	RegisterUserModule(appObj)
+	RegisterUserModule(appObj, notifications)
 	RegisterClientModule(appObj)
 
 	RegisterBankModule(appObj)
-	RegisterBankUserModule(appObj)
+	RegisterBankUserModule(appObj, events, notifications)
+	RegisterLedgerModule(appObj)
+	RegisterFxModule(appObj)
+	RegisterPayrollModule(appObj)
+	RegisterWebhookModule(appObj, events)
 
 }
 
@@ -49,6 +64,14 @@ func RegisterTableMigrations(appObj *app.App) {
 	paymentConfig := payments.PaymentConfig{DB: appObj.DB}
 	salaryDisbursementConfig := salaryDisbursement.SalaryDisbursementConfig{DB: appObj.DB}
 	documentConfig := document.DocumentConfig{DB: appObj.DB}
+	ledgerConfig := ledger.LedgerConfig{DB: appObj.DB}
+	exchangeRateConfig := fx.ExchangeRateConfig{DB: appObj.DB}
+	idempotencyConfig := idempotency.IdempotencyConfig{DB: appObj.DB}
+	payrollConfig := payroll.PayrollConfig{DB: appObj.DB}
+	auditConfig := audit.AuditConfig{DB: appObj.DB}
+	outboxConfig := outbox.OutboxConfig{DB: appObj.DB}
+	webhookConfig := webhook.WebhookConfig{DB: appObj.DB}
+	notificationConfig := notification.NotificationConfig{DB: appObj.DB}
 
 	// Register each module's configuration in the correct order
 	registerAllConfigs(appObj, []ModuleConfig{
@@ -64,11 +87,21 @@ func RegisterTableMigrations(appObj *app.App) {
 		&documentConfig,
 		&paymentConfig,
 		&salaryDisbursementConfig,
+		&ledgerConfig,
+		&exchangeRateConfig,
+		&idempotencyConfig,
+		&payrollConfig,
+		&auditConfig,
+		&outboxConfig,
+		&webhookConfig,
+		&notificationConfig,
 	})
 
 }
 
 func SeedData(appObj *app.App) {
+	clientUserRole := user.Role{}
+	appObj.Log.Info(appObj.DB.Where("role_name = ?", user.RoleClientUser).First(&clientUserRole).Error)
 	data := []interface{}{
 		// &user.Role{RoleName: "SuperAdmin"},
 		// &user.Role{RoleName: "Bank User"},
@@ -96,7 +129,7 @@ func SeedData(appObj *app.App) {
 			Email:    "client_tcs@gmail.com",
 			IsActive: true,
 			Name:     "Client User TCS",
-			RoleID:   uint(encrypt.ClientUserRoleID),
+			RoleID:   clientUserRole.ID,
 		},
 	}
 
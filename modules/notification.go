@@ -0,0 +1,25 @@
+package modules
+
+import (
+	"bankManagement/app"
+	"bankManagement/components/notification/controller"
+	"bankManagement/components/notification/service"
+	"bankManagement/utils/notify"
+)
+
+// RegisterNotificationModule creates the notification service the other modules send their
+// emails and text messages through, on the channels configured in the environment.
+func RegisterNotificationModule(appObj *app.App) *service.NotificationService {
+	notifiers, err := notify.NewNotifiersFromEnv(appObj.Log)
+	if err != nil {
+		panic(err)
+	}
+	templates, err := notify.LoadTemplates()
+	if err != nil {
+		panic(err)
+	}
+	notificationService := service.NewNotificationService(appObj.DB, appObj.Repository, appObj.Log, notifiers, templates)
+	notificationController := controller.NewNotificationController(notificationService, appObj.Log)
+	notificationController.RegisterRoutes(appObj.Router)
+	return notificationService
+}
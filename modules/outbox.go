@@ -0,0 +1,18 @@
+package modules
+
+import (
+	"bankManagement/app"
+	"bankManagement/components/outbox/controller"
+	"bankManagement/components/outbox/service"
+)
+
+// RegisterOutboxModule creates the event bus the other modules publish to and subscribe
+// on; the scheduler retries the deliveries that failed.
+func RegisterOutboxModule(appObj *app.App) *service.OutboxService {
+	outboxService := service.NewOutboxService(appObj.DB, appObj.Repository, appObj.Log)
+	outboxController := controller.NewOutboxController(outboxService, appObj.Log)
+	outboxController.RegisterRoutes(appObj.Router)
+
+	appObj.Scheduler.Register(service.NewOutboxJob(outboxService))
+	return outboxService
+}
@@ -0,0 +1,19 @@
+package modules
+
+import (
+	"bankManagement/app"
+	clientService "bankManagement/components/client/service"
+	ledgerService "bankManagement/components/ledger/service"
+	"bankManagement/components/payroll/controller"
+	"bankManagement/components/payroll/service"
+)
+
+func RegisterPayrollModule(appObj *app.App) {
+	ledger := ledgerService.NewLedgerService(appObj.DB, appObj.Repository, appObj.Log)
+	clients := clientService.NewClientService(appObj.DB, appObj.Repository, appObj.Log, ledger)
+	payrollService := service.NewPayrollService(appObj.DB, appObj.Repository, appObj.Log, clients)
+	payrollController := controller.NewPayrollController(payrollService, appObj.Log)
+	payrollController.RegisterRoutes(appObj.Router)
+
+	appObj.Scheduler.Register(service.NewPayrollJob(payrollService))
+}
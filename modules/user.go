@@ -2,12 +2,13 @@ package modules
 
 import (
 	"bankManagement/app"
+	notificationService "bankManagement/components/notification/service"
 	"bankManagement/components/user/controller"
 	"bankManagement/components/user/service"
 )
 
-func RegisterUserModule(appObj *app.App) {
-	userService := service.NewUserService(appObj.DB, appObj.Repository, appObj.Log)
+func RegisterUserModule(appObj *app.App, notifications *notificationService.NotificationService) {
+	userService := service.NewUserService(appObj.DB, appObj.Repository, appObj.Log, notifications)
 	userController := controller.NewUserController(userService, appObj.Log)
 	userController.RegisterRoutes(appObj.Router)
 }
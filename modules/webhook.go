@@ -0,0 +1,19 @@
+package modules
+
+import (
+	"bankManagement/app"
+	outboxService "bankManagement/components/outbox/service"
+	"bankManagement/components/webhook/controller"
+	"bankManagement/components/webhook/service"
+)
+
+// RegisterWebhookModule has the clients' endpoints POSTed the payment and salary events from
+// the event bus; the scheduler retries the deliveries that failed.
+func RegisterWebhookModule(appObj *app.App, events *outboxService.OutboxService) {
+	webhookService := service.NewWebhookService(appObj.DB, appObj.Repository, appObj.Log)
+	webhookService.Subscribe(events)
+	webhookController := controller.NewWebhookController(webhookService, appObj.Log)
+	webhookController.RegisterRoutes(appObj.Router)
+
+	appObj.Scheduler.Register(service.NewWebhookJob(webhookService))
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"reflect"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// ChangeRecorder is told about every write made through the repository, inside the unit of
+// work making it, so a failed recording fails the write. The audit log is one.
+type ChangeRecorder interface {
+	// Records says whether changes to this model are recorded at all.
+	Records(value interface{}) bool
+	// RecordChange gets the row before and after the change, nil for a create or a delete.
+	RecordChange(uow *UOW, action string, before interface{}, after interface{}) error
+}
+
+var changeRecorder ChangeRecorder
+
+func SetChangeRecorder(recorder ChangeRecorder) {
+	changeRecorder = recorder
+}
+
+const actorKey = "audit:actor"
+
+// BindActor returns a handle whose units of work carry actor, who the recorded changes are
+// attributed to.
+func BindActor(db *gorm.DB, actor interface{}) *gorm.DB {
+	return db.Set(actorKey, actor)
+}
+
+// Actor is what BindActor attached to the handle the unit of work was begun from.
+func (u *UOW) Actor() interface{} {
+	actor, _ := u.DB.Get(actorKey)
+	return actor
+}
+
+func recording(value interface{}) bool {
+	return changeRecorder != nil && changeRecorder.Records(value)
+}
+
+// storedRow loads the row as it is before the change: by the primary key set on value,
+// else by id. It returns nil when there is no such row.
+func storedRow(uow *UOW, value interface{}, id interface{}) interface{} {
+	modelType := reflect.TypeOf(value)
+	for modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() != reflect.Struct {
+		return nil
+	}
+	stored := reflect.New(modelType).Interface()
+	conditions := map[string]interface{}{}
+	for _, field := range uow.DB.NewScope(value).PrimaryFields() {
+		if field.IsBlank {
+			conditions = nil
+			break
+		}
+		conditions[field.DBName] = field.Field.Interface()
+	}
+	query := uow.DB.New().Unscoped()
+	var err error
+	if len(conditions) > 0 {
+		err = query.Where(conditions).First(stored).Error
+	} else if id != nil {
+		err = query.First(stored, id).Error
+	} else {
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	return stored
+}
@@ -7,6 +7,7 @@ import (
 type Repository interface {
 	GetAll(uow *UOW, out interface{}, queryProcessors ...QueryProcessor) error
 	GetByID(uow *UOW, out interface{}, id ...interface{}) error
+	GetByIDForUpdate(uow *UOW, out interface{}, id ...interface{}) error
 	GetFirstWhere(uow *UOW, out interface{}, where ...interface{}) error
 	Add(uow *UOW, out interface{}) error
 	Limit(limit interface{}) QueryProcessor
@@ -14,6 +15,9 @@ type Repository interface {
 	Preload(field string, condition ...interface{}) QueryProcessor
 	Filter(condition string, args ...interface{}) QueryProcessor
 	Count(limit, offset int, totalCount *int) QueryProcessor
+	Order(order string) QueryProcessor
+	ForUpdate() QueryProcessor
+	Unscoped() QueryProcessor
 	Raw(uow *UOW, out interface{}, query string, input ...interface{}) error
 	Update(uow *UOW, updated_value interface{}) error
 	DeleteById(uow *UOW, out interface{}, id interface{}) error
@@ -38,7 +42,13 @@ func executeQueryProcessors(db *gorm.DB, out interface{}, queryProcessors ...Que
 }
 
 func (g *GormRepositoryMySQL) Add(uow *UOW, out interface{}) error {
-	return uow.DB.Create(out).Error
+	if err := uow.DB.Create(out).Error; err != nil {
+		return err
+	}
+	if !recording(out) {
+		return nil
+	}
+	return changeRecorder.RecordChange(uow, ChangeCreate, nil, out)
 }
 
 func (g *GormRepositoryMySQL) GetAll(uow *UOW, out interface{}, queryProcessors ...QueryProcessor) error {
@@ -53,18 +63,45 @@ func (g *GormRepositoryMySQL) GetByID(uow *UOW, out interface{}, id ...interface
 	return uow.DB.First(out, id).Error
 }
 
+// GetByIDForUpdate reads the row with SELECT ... FOR UPDATE, so it stays locked
+// against other writers and locking reads until the unit of work ends.
+func (g *GormRepositoryMySQL) GetByIDForUpdate(uow *UOW, out interface{}, id ...interface{}) error {
+	return uow.DB.Set("gorm:query_option", "FOR UPDATE").First(out, id).Error
+}
+
 func (g *GormRepositoryMySQL) GetFirstWhere(uow *UOW, out interface{}, where ...interface{}) error {
 	return uow.DB.First(out, where...).Error
 }
 
 func (g *GormRepositoryMySQL) Update(uow *UOW, updated_value interface{}) error {
-	return uow.DB.Save(updated_value).Error
+	if !recording(updated_value) {
+		return uow.DB.Save(updated_value).Error
+	}
+	before := storedRow(uow, updated_value, nil)
+	if err := uow.DB.Save(updated_value).Error; err != nil {
+		return err
+	}
+	if before == nil {
+		// Save inserts rows without a primary key
+		return changeRecorder.RecordChange(uow, ChangeCreate, nil, updated_value)
+	}
+	return changeRecorder.RecordChange(uow, ChangeUpdate, before, updated_value)
 }
 
 func (g *GormRepositoryMySQL) DeleteById(uow *UOW, out interface{}, id interface{}) error {
 	// var tempInt interface{}
 	// output:=g.GetByID(uow)
-	return uow.DB.Delete(out, id).Error
+	if !recording(out) {
+		return uow.DB.Delete(out, id).Error
+	}
+	before := storedRow(uow, out, id)
+	if err := uow.DB.Delete(out, id).Error; err != nil {
+		return err
+	}
+	if before == nil {
+		return nil
+	}
+	return changeRecorder.RecordChange(uow, ChangeDelete, before, nil)
 }
 
 func (g *GormRepositoryMySQL) Raw(uow *UOW, out interface{}, query string, input ...interface{}) error {
@@ -99,24 +136,47 @@ func (g *GormRepositoryMySQL) Preload(table string, condition ...interface{}) Qu
 	}
 }
 
+// Count stores the number of rows matched by the processors before it in totalCount,
+// then pages the query with limit and offset.
 func (g *GormRepositoryMySQL) Count(limit, offset int, totalCount *int) QueryProcessor {
 	return func(db *gorm.DB, out interface{}) (*gorm.DB, error) {
-		// db.Limit(-1)
-		// db.Offset(-1)
 		if totalCount != nil {
-			err := db.Model(out).Count(totalCount).Error
+			err := db.Model(out).Limit(-1).Offset(-1).Count(totalCount).Error
 			if err != nil {
 				return db, err
 			}
-
 		}
+		return db.Limit(limit).Offset(offset), nil
+	}
+}
+
+func (g *GormRepositoryMySQL) Order(order string) QueryProcessor {
+	return func(db *gorm.DB, out interface{}) (*gorm.DB, error) {
+		db = db.Order(order)
+		return db, nil
+	}
+}
+
+// ForUpdate turns the query into a locking read (SELECT ... FOR UPDATE).
+func (g *GormRepositoryMySQL) ForUpdate() QueryProcessor {
+	return func(db *gorm.DB, out interface{}) (*gorm.DB, error) {
+		db = db.Set("gorm:query_option", "FOR UPDATE")
+		return db, nil
+	}
+}
+
+// Unscoped includes the soft-deleted rows in the query.
+func (g *GormRepositoryMySQL) Unscoped() QueryProcessor {
+	return func(db *gorm.DB, out interface{}) (*gorm.DB, error) {
+		db = db.Unscoped()
 		return db, nil
 	}
 }
 
 type UOW struct {
-	DB       *gorm.DB
-	Commited bool
+	DB          *gorm.DB
+	Commited    bool
+	afterCommit []func()
 }
 
 func NewUnitOfWork(DB *gorm.DB) *UOW {
@@ -134,6 +194,18 @@ func (u *UOW) Commit() {
 	if u.Commited {
 		return
 	}
-	u.DB.Commit()
+	err := u.DB.Commit().Error
 	u.Commited = true
+	if err != nil {
+		return
+	}
+	for _, callback := range u.afterCommit {
+		callback()
+	}
+}
+
+// AfterCommit runs callback once the unit of work is committed, not at all when it is
+// rolled back.
+func (u *UOW) AfterCommit(callback func()) {
+	u.afterCommit = append(u.afterCommit, callback)
 }
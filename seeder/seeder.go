@@ -2,17 +2,20 @@ package seeder
 
 import (
 	"bankManagement/models/user"
-	"fmt"
+	"bankManagement/utils/log"
 
 	"github.com/jinzhu/gorm"
 )
 
+// SeedRoles creates the built-in roles and keeps each one holding every permission of
+// its scope, so permissions added to the registry reach them on the next start.
 func SeedRoles(db *gorm.DB) {
-	fmt.Println("Roles has been initialized....")
+	logger := log.GetLogger()
+	logger.Info("Roles has been initialized....")
 	roles := []user.Role{
-		{RoleName: "SUPER_ADMIN"},
-		{RoleName: "BANK_USER"},
-		{RoleName: "CLIENT_USER"},
+		{RoleName: user.RoleSuperAdmin, Scope: user.RoleScopeAdmin, Description: "Manages banks, exchange rates and roles", IsSystem: true},
+		{RoleName: user.RoleBankUser, Scope: user.RoleScopeBank, Description: "Full access to the bank's clients", IsSystem: true},
+		{RoleName: user.RoleClientUser, Scope: user.RoleScopeClient, Description: "Full access to the client's employees and payments", IsSystem: true},
 	}
 
 	for _, role := range roles {
@@ -20,11 +23,30 @@ func SeedRoles(db *gorm.DB) {
 		if err := db.Where("role_name = ?", role.RoleName).First(&existingRole).Error; err != nil {
 			if gorm.IsRecordNotFoundError(err) {
 				if err := db.Create(&role).Error; err == nil {
-					fmt.Printf("Inserted role: %s\n", role.RoleName)
+					logger.Info("Inserted role:", role.RoleName)
 				} else {
-					fmt.Printf("Error inserting role: %s\n", err)
+					logger.Error("Error inserting role:", err)
 				}
 			}
+			existingRole = role
+		} else {
+			existingRole.Scope, existingRole.Description, existingRole.IsSystem = role.Scope, role.Description, true
+			if err := db.Save(&existingRole).Error; err != nil {
+				logger.Error("Error updating role:", err)
+			}
+		}
+		if existingRole.ID == 0 {
+			continue
+		}
+		permissions := user.ScopePermissions(role.Scope)
+		for _, permission := range permissions {
+			grant := user.RolePermission{RoleID: existingRole.ID, Permission: permission}
+			if err := db.Where(grant).FirstOrCreate(&grant).Error; err != nil {
+				logger.Error("Error granting", permission, "to role", role.RoleName+":", err)
+			}
+		}
+		if err := db.Where("role_id = ? AND permission NOT IN (?)", existingRole.ID, permissions).Delete(&user.RolePermission{}).Error; err != nil {
+			logger.Error("Error revoking retired permissions of role", role.RoleName+":", err)
 		}
 	}
 }
@@ -1,33 +1,39 @@
 package encrypt
 
 import (
+	"bankManagement/constants"
+	"bankManagement/utils/log"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
-	"fmt"
+	"time"
 
 	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var signingKey = "This is Amazing Key"
-
-var AdminUserRoleID = 1
-var BankUserRoleID = 2
-var ClientUserRoleID = 3
-
 type Claims struct {
-	UserId         uint `json:"userId"`
-	RoleId         uint `json:"roleId"`
-	BankId         uint `json:"bankId,omitempty"`
-	ClientId       uint `json:"clientId,omitempty"`
-	IsSuperAdmin   bool `json:"is_super_admin,omitempty"`
-	LoginSessionId uint `json:"login_session_id"`
+	UserId         uint     `json:"userId"`
+	RoleId         uint     `json:"roleId"`
+	BankId         uint     `json:"bankId,omitempty"`
+	ClientId       uint     `json:"clientId,omitempty"`
+	IsSuperAdmin   bool     `json:"is_super_admin,omitempty"`
+	LoginSessionId uint     `json:"login_session_id"`
+	Permissions    []string `json:"permissions,omitempty"` // from the user's role when the token was issued
+	Purpose        string   `json:"purpose,omitempty"`     // empty on access tokens
 	jwt.StandardClaims
 }
 
+// PurposePreAuth marks the token of a login that passed the password but still owes a
+// two-factor code; it carries no session nor permissions.
+const PurposePreAuth = "pre_auth"
+
 func HashPassword(password string) string {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		fmt.Println("failed to hash password")
+		log.GetLogger().Error("failed to hash password")
 		panic(err)
 	}
 	return string(hash)
@@ -35,25 +41,46 @@ func HashPassword(password string) string {
 
 func CheckHashWithPassword(password string, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	if err != nil {
-		fmt.Println(err)
-		return false
-	}
-	return true
+	return err == nil
 }
 
-func GetJwtFromData(userId uint, RoleId uint, BankId uint, ClientId uint, isSuperAdmin bool, login_session_id uint) (string, error) {
-	claims := &Claims{UserId: userId, RoleId: RoleId, BankId: BankId, ClientId: ClientId, IsSuperAdmin: isSuperAdmin, LoginSessionId: login_session_id}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	finalToken, err := token.SignedString([]byte(signingKey))
+// GetJwtFromData issues a short lived access token, expiring after constants.AccessTokenTTL,
+// signed with the active key and naming it in the kid header.
+func GetJwtFromData(userId uint, RoleId uint, BankId uint, ClientId uint, isSuperAdmin bool, login_session_id uint, permissions []string) (string, error) {
+	now := time.Now()
+	claims := &Claims{UserId: userId, RoleId: RoleId, BankId: BankId, ClientId: ClientId, IsSuperAdmin: isSuperAdmin, LoginSessionId: login_session_id, Permissions: permissions,
+		StandardClaims: jwt.StandardClaims{IssuedAt: now.Unix(), ExpiresAt: now.Add(constants.AccessTokenTTL).Unix()},
+	}
+	if keySet == nil {
+		return "", errors.New("JWT signing keys are not configured")
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(keySet.active.Algorithm), claims)
+	token.Header["kid"] = keySet.active.ID
+	finalToken, err := token.SignedString(keySet.active.private)
 	return finalToken, err
 }
 
+// GetPreAuthJwt issues the token between the password and the two-factor code of a login,
+// expiring after constants.PreAuthTokenTTL.
+func GetPreAuthJwt(userId uint, RoleId uint, BankId uint, ClientId uint) (string, error) {
+	now := time.Now()
+	claims := &Claims{UserId: userId, RoleId: RoleId, BankId: BankId, ClientId: ClientId, Purpose: PurposePreAuth,
+		StandardClaims: jwt.StandardClaims{IssuedAt: now.Unix(), ExpiresAt: now.Add(constants.PreAuthTokenTTL).Unix()},
+	}
+	if keySet == nil {
+		return "", errors.New("JWT signing keys are not configured")
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(keySet.active.Algorithm), claims)
+	token.Header["kid"] = keySet.active.ID
+	return token.SignedString(keySet.active.private)
+}
+
 func ValidateJwtToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		return []byte(signingKey), nil
-	})
+	if keySet == nil {
+		return nil, errors.New("JWT signing keys are not configured")
+	}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keySet.verificationKey)
 	if err != nil {
 		return nil, err
 	}
@@ -62,3 +89,28 @@ func ValidateJwtToken(tokenString string) (*Claims, error) {
 	}
 	return claims, nil
 }
+
+// NewOpaqueToken returns a random token (refresh token, invitation...) and the hash to
+// store in its place.
+func NewOpaqueToken() (string, string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (claims *Claims) HasPermission(permission string) bool {
+	for _, granted := range claims.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package encrypt
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"time"
+)
+
+// JSONWebKey is the public half of a signing key as published in the JWKS (RFC 7517).
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n,omitempty"` // RSA modulus
+	E         string `json:"e,omitempty"` // RSA exponent
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// GetJWKS lists the public keys tokens can currently be verified with. HS256 secrets are
+// never published, other services can only verify asymmetric tokens.
+func GetJWKS() JSONWebKeySet {
+	jwks := JSONWebKeySet{Keys: []JSONWebKey{}}
+	if keySet == nil {
+		return jwks
+	}
+	for _, key := range append([]*SigningKey{keySet.active}, keySet.previous...) {
+		if !key.NotAfter.IsZero() && time.Now().After(key.NotAfter) {
+			continue
+		}
+		if jwk := publicJWK(key); jwk.KeyType != "" {
+			jwk.KeyID = key.ID
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
+
+// publicJWK returns an empty JWK for symmetric keys.
+func publicJWK(key *SigningKey) JSONWebKey {
+	jwk := JSONWebKey{Use: "sig", Algorithm: key.Algorithm, KeyID: key.ID}
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64URL(public.N.Bytes())
+		jwk.E = base64URL(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = public.Curve.Params().Name
+		jwk.X = base64URL(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64URL(public.Y.FillBytes(make([]byte, size)))
+	}
+	return jwk
+}
+
+// thumbprint is the RFC 7638 SHA-256 thumbprint: the required members in lexical order.
+func (jwk JSONWebKey) thumbprint() string {
+	var members interface{}
+	switch jwk.KeyType {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Curve, jwk.KeyType, jwk.X, jwk.Y}
+	}
+	encoded, _ := json.Marshal(members)
+	sum := sha256.Sum256(encoded)
+	return base64URL(sum[:])
+}
+
+func base64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
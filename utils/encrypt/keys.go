@@ -0,0 +1,200 @@
+package encrypt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// SigningKey is one JWT key. Asymmetric keys only need the public half to verify, so a
+// retired key can be configured from its public key alone.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	private   interface{} // []byte secret, *rsa.PrivateKey or *ecdsa.PrivateKey
+	public    interface{} // []byte secret, *rsa.PublicKey or *ecdsa.PublicKey
+	NotAfter  time.Time   // zero for the active key, end of the grace window for a retired one
+}
+
+// KeySet signs with the active key and verifies with it or, until their grace window
+// ends, with the previous keys.
+type KeySet struct {
+	active   *SigningKey
+	previous []*SigningKey
+}
+
+var keySet *KeySet
+
+// SetKeySet installs the keys used by GetJwtFromData and ValidateJwtToken.
+func SetKeySet(keys *KeySet) {
+	keySet = keys
+}
+
+func NewKeySet(active *SigningKey, previous ...*SigningKey) (*KeySet, error) {
+	if active == nil || active.private == nil {
+		return nil, errors.New("the active JWT key must be able to sign")
+	}
+	seen := map[string]bool{active.ID: true}
+	for _, key := range previous {
+		if seen[key.ID] {
+			return nil, fmt.Errorf("JWT key id %s is used twice", key.ID)
+		}
+		seen[key.ID] = true
+	}
+	return &KeySet{active: active, previous: previous}, nil
+}
+
+// NewKeySetFromEnv loads the keys from the environment:
+//
+//	JWT_SIGNING_ALG        HS256 (default), RS256 or ES256
+//	JWT_SIGNING_KEY        HS256 secret, at least 32 characters
+//	JWT_SIGNING_KEY_FILE   PEM private key for RS256/ES256
+//	JWT_KEY_ID             kid of the key, derived from the key when empty
+//
+// The key being rotated out is configured the same way with the JWT_PREVIOUS_ prefix
+// (JWT_PREVIOUS_SIGNING_KEY_FILE may hold only the public key). Its tokens are accepted
+// until JWT_PREVIOUS_KEY_VALID_UNTIL (RFC 3339), which is required: a grace period counted
+// from startup would start over with every restart.
+func NewKeySetFromEnv() (*KeySet, error) {
+	active, err := signingKeyFromEnv("JWT_", true)
+	if err != nil {
+		return nil, err
+	}
+	if active == nil {
+		return nil, errors.New("no JWT signing key configured, set JWT_SIGNING_KEY or JWT_SIGNING_KEY_FILE")
+	}
+	previous, err := signingKeyFromEnv("JWT_PREVIOUS_", false)
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return NewKeySet(active)
+	}
+	validUntil := os.Getenv("JWT_PREVIOUS_KEY_VALID_UNTIL")
+	if validUntil == "" {
+		return nil, errors.New("JWT_PREVIOUS_KEY_VALID_UNTIL is required with a previous JWT key, e.g. one access token lifetime after the rotation")
+	}
+	previous.NotAfter, err = time.Parse(time.RFC3339, validUntil)
+	if err != nil {
+		return nil, fmt.Errorf("JWT_PREVIOUS_KEY_VALID_UNTIL: %w", err)
+	}
+	return NewKeySet(active, previous)
+}
+
+// signingKeyFromEnv returns nil when no key is configured under the prefix.
+func signingKeyFromEnv(prefix string, mustSign bool) (*SigningKey, error) {
+	algorithm := os.Getenv(prefix + "SIGNING_ALG")
+	if algorithm == "" {
+		algorithm = jwt.SigningMethodHS256.Alg()
+	}
+	secret := os.Getenv(prefix + "SIGNING_KEY")
+	keyFile := os.Getenv(prefix + "SIGNING_KEY_FILE")
+	if secret == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	var key *SigningKey
+	var err error
+	switch algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		key, err = NewHMACKey(secret)
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg():
+		if keyFile == "" {
+			return nil, fmt.Errorf("%sSIGNING_KEY_FILE is required for %s", prefix, algorithm)
+		}
+		var pemData []byte
+		pemData, err = os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%sSIGNING_KEY_FILE: %w", prefix, err)
+		}
+		key, err = NewAsymmetricKey(algorithm, pemData, mustSign)
+	default:
+		return nil, fmt.Errorf("%sSIGNING_ALG %s is not supported, use HS256, RS256 or ES256", prefix, algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%sSIGNING_KEY: %w", prefix, err)
+	}
+	if kid := strings.TrimSpace(os.Getenv(prefix + "KEY_ID")); kid != "" {
+		key.ID = kid
+	}
+	return key, nil
+}
+
+func NewHMACKey(secret string) (*SigningKey, error) {
+	if len(secret) < 32 {
+		return nil, errors.New("HS256 secret must be at least 32 characters")
+	}
+	// the kid must not reveal the secret, a hash prefix is enough to tell keys apart
+	sum := sha256.Sum256([]byte(secret))
+	return &SigningKey{
+		ID:        hex.EncodeToString(sum[:8]),
+		Algorithm: jwt.SigningMethodHS256.Alg(),
+		private:   []byte(secret),
+		public:    []byte(secret),
+	}, nil
+}
+
+// NewAsymmetricKey parses a PEM private key, or a public key when mustSign is false. The
+// kid defaults to the RFC 7638 thumbprint of the public key.
+func NewAsymmetricKey(algorithm string, pemData []byte, mustSign bool) (*SigningKey, error) {
+	key := &SigningKey{Algorithm: algorithm}
+	switch algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		if private, err := jwt.ParseRSAPrivateKeyFromPEM(pemData); err == nil {
+			key.private, key.public = private, &private.PublicKey
+		} else if public, err := jwt.ParseRSAPublicKeyFromPEM(pemData); err == nil && !mustSign {
+			key.public = public
+		} else {
+			return nil, errors.New("not a PEM encoded RSA private key")
+		}
+		if key.public.(*rsa.PublicKey).N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+	case jwt.SigningMethodES256.Alg():
+		if private, err := jwt.ParseECPrivateKeyFromPEM(pemData); err == nil {
+			key.private, key.public = private, &private.PublicKey
+		} else if public, err := jwt.ParseECPublicKeyFromPEM(pemData); err == nil && !mustSign {
+			key.public = public
+		} else {
+			return nil, errors.New("not a PEM encoded EC private key")
+		}
+		if key.public.(*ecdsa.PublicKey).Curve != elliptic.P256() {
+			return nil, errors.New("ES256 needs a P-256 key")
+		}
+	default:
+		return nil, fmt.Errorf("%s is not an asymmetric algorithm", algorithm)
+	}
+	key.ID = publicJWK(key).thumbprint()
+	return key, nil
+}
+
+// verificationKey finds the key a token was signed with. The algorithm is pinned to the
+// key so a token cannot pick a weaker one, e.g. HS256 keyed with an RSA public key.
+func (keys *KeySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no key id")
+	}
+	for _, key := range append([]*SigningKey{keys.active}, keys.previous...) {
+		if key.ID != kid {
+			continue
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("token algorithm %s does not match key %s", token.Method.Alg(), kid)
+		}
+		if !key.NotAfter.IsZero() && time.Now().After(key.NotAfter) {
+			return nil, fmt.Errorf("signing key %s has been retired", kid)
+		}
+		return key.public, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %s", kid)
+}
@@ -0,0 +1,138 @@
+package encrypt
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicy is what every password a user sets must satisfy. HistorySize previous
+// passwords, the current one included, cannot be set again.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	HistorySize   int
+}
+
+// bcrypt ignores what comes after 72 bytes
+const passwordMaxBytes = 72
+
+var passwordPolicy = DefaultPasswordPolicy()
+
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength:     12,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		HistorySize:   5,
+	}
+}
+
+func SetPasswordPolicy(policy *PasswordPolicy) {
+	passwordPolicy = policy
+}
+
+func GetPasswordPolicy() *PasswordPolicy {
+	return passwordPolicy
+}
+
+// NewPasswordPolicyFromEnv overrides the defaults with PASSWORD_MIN_LENGTH,
+// PASSWORD_REQUIRE_UPPER, PASSWORD_REQUIRE_LOWER, PASSWORD_REQUIRE_DIGIT,
+// PASSWORD_REQUIRE_SYMBOL and PASSWORD_HISTORY_SIZE.
+func NewPasswordPolicyFromEnv() (*PasswordPolicy, error) {
+	policy := DefaultPasswordPolicy()
+	ints := map[string]*int{
+		"PASSWORD_MIN_LENGTH":   &policy.MinLength,
+		"PASSWORD_HISTORY_SIZE": &policy.HistorySize,
+	}
+	for name, field := range ints {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				return nil, fmt.Errorf("%s should be a positive number", name)
+			}
+			*field = parsed
+		}
+	}
+	bools := map[string]*bool{
+		"PASSWORD_REQUIRE_UPPER":  &policy.RequireUpper,
+		"PASSWORD_REQUIRE_LOWER":  &policy.RequireLower,
+		"PASSWORD_REQUIRE_DIGIT":  &policy.RequireDigit,
+		"PASSWORD_REQUIRE_SYMBOL": &policy.RequireSymbol,
+	}
+	for name, field := range bools {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s should be true or false", name)
+			}
+			*field = parsed
+		}
+	}
+	if policy.MinLength < 8 || policy.MinLength > passwordMaxBytes {
+		return nil, fmt.Errorf("PASSWORD_MIN_LENGTH should be between 8 and %d", passwordMaxBytes)
+	}
+	return policy, nil
+}
+
+// PasswordPolicyError lists every rule a password breaks.
+type PasswordPolicyError struct {
+	Problems []string
+}
+
+func (err *PasswordPolicyError) Error() string {
+	return "password " + strings.Join(err.Problems, ", ")
+}
+
+// ValidatePassword checks the password against the policy; it may not contain the
+// username either.
+func ValidatePassword(password string, username string) error {
+	policy := passwordPolicy
+	var problems []string
+	if len([]rune(password)) < policy.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", policy.MinLength))
+	}
+	if len(password) > passwordMaxBytes {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes long", passwordMaxBytes))
+	}
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char) || unicode.IsSpace(char):
+			hasSymbol = true
+		}
+	}
+	if policy.RequireUpper && !hasUpper {
+		problems = append(problems, "must contain an upper case letter")
+	}
+	if policy.RequireLower && !hasLower {
+		problems = append(problems, "must contain a lower case letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		problems = append(problems, "must contain a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		problems = append(problems, "must contain a symbol")
+	}
+	username = strings.ToLower(strings.TrimSpace(username))
+	if len(username) >= 3 && strings.Contains(strings.ToLower(password), username) {
+		problems = append(problems, "must not contain the username")
+	}
+	if len(problems) > 0 {
+		return &PasswordPolicyError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package log
+
+import "time"
+
+// GormLogger makes gorm log through a WebLogger: queries at debug level, without their
+// bound values which may hold credentials, and errors at error level.
+type GormLogger struct {
+	Log WebLogger
+}
+
+func (gormLogger GormLogger) Print(values ...interface{}) {
+	if len(values) < 2 {
+		gormLogger.Log.Debug(values...)
+		return
+	}
+	level, source := values[0], values[1]
+	switch {
+	case level == "sql" && len(values) >= 6:
+		duration, _ := values[2].(time.Duration)
+		gormLogger.Log.WithFields(Fields{
+			"source":      source,
+			"duration_ms": float64(duration.Microseconds()) / 1000,
+			"rows":        values[5],
+		}).Debug(values[3])
+	case level == "error":
+		gormLogger.Log.WithFields(Fields{"source": source}).Error(values[2:]...)
+	default:
+		gormLogger.Log.WithFields(Fields{"source": source}).Debug(values[2:]...)
+	}
+}
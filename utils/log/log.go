@@ -1,32 +1,248 @@
 package log
 
-import "fmt"
+import (
+	"bankManagement/constants"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
 
 type WebLogger interface {
+	Debug(value ...interface{})
 	Info(value ...interface{})
-	Error(value ...interface{})
 	Warning(value ...interface{})
+	Error(value ...interface{})
+	// WithFields returns a logger adding the fields to each of its lines.
+	WithFields(fields Fields) WebLogger
+	// WithContext returns a logger adding the request ID carried by ctx to each of its lines.
+	WithContext(ctx context.Context) WebLogger
+}
+
+type Fields map[string]interface{}
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarning
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warning", "error"}
+
+func (level Level) String() string {
+	return levelNames[level]
+}
+
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(level), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("log level should be one of %s", strings.Join(levelNames, ", "))
+}
+
+// Config is how a Log writes: lines below Level are dropped, and past SampleInitial lines
+// of the same level and message in a second only every SampleThereafter-th debug or info
+// line is kept. SampleInitial 0 keeps every line.
+type Config struct {
+	Level            Level
+	SampleInitial    int
+	SampleThereafter int
+	Output           io.Writer
 }
+
+func DefaultConfig() *Config {
+	return &Config{
+		Level:            LevelInfo,
+		SampleInitial:    100,
+		SampleThereafter: 100,
+		Output:           os.Stdout,
+	}
+}
+
+// Log writes each line as one JSON object: time, level, msg, then the fields sorted by
+// name. Secrets are redacted from the message and the fields.
 type Log struct {
+	output  *output
+	fields  Fields
+	sampler *sampler
+	level   Level
+}
+
+// output serialises the lines of a Log and of the loggers derived from it.
+type output struct {
+	sync.Mutex
+	writer io.Writer
+}
+
+func NewLogger(config *Config) *Log {
+	return &Log{
+		output:  &output{writer: config.Output},
+		fields:  Fields{},
+		sampler: newSampler(config.SampleInitial, config.SampleThereafter),
+		level:   config.Level,
+	}
+}
+
+// NewLoggerFromEnv overrides the defaults with LOG_LEVEL, LOG_SAMPLE_INITIAL and
+// LOG_SAMPLE_THEREAFTER.
+func NewLoggerFromEnv() (*Log, error) {
+	config := DefaultConfig()
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		level, err := ParseLevel(value)
+		if err != nil {
+			return nil, err
+		}
+		config.Level = level
+	}
+	ints := map[string]*int{
+		"LOG_SAMPLE_INITIAL":    &config.SampleInitial,
+		"LOG_SAMPLE_THEREAFTER": &config.SampleThereafter,
+	}
+	for name, field := range ints {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				return nil, fmt.Errorf("%s should be a positive number", name)
+			}
+			*field = parsed
+		}
+	}
+	return NewLogger(config), nil
+}
+
+var logger WebLogger = NewLogger(DefaultConfig())
+
+func SetLogger(webLogger WebLogger) {
+	logger = webLogger
+}
+
+func GetLogger() WebLogger {
+	return logger
+}
+
+func (l *Log) Debug(value ...interface{}) {
+	l.write(LevelDebug, value)
 }
 
-// logrus
 func (l *Log) Info(value ...interface{}) {
-	//operations...
-	fmt.Println("-----------INFO---------")
-	fmt.Println(value...)
+	l.write(LevelInfo, value)
+}
 
+func (l *Log) Warning(value ...interface{}) {
+	l.write(LevelWarning, value)
 }
+
 func (l *Log) Error(value ...interface{}) {
-	fmt.Println("<<<<<<<<<<<Error<<<<<<<<<")
-	fmt.Println(value...)
-	fmt.Println("<<<<<<<<<<<Error<<<<<<<<<")
+	l.write(LevelError, value)
 }
-func (l *Log) Warning(value ...interface{}) {
-	fmt.Println("<<<<<<<<<<<Warning<<<<<<<<<")
-	fmt.Println(value...)
-	fmt.Println("<<<<<<<<<<<Warning<<<<<<<<<")
+
+func (l *Log) WithFields(fields Fields) WebLogger {
+	derived := *l
+	derived.fields = make(Fields, len(l.fields)+len(fields))
+	for name, value := range l.fields {
+		derived.fields[name] = value
+	}
+	for name, value := range fields {
+		derived.fields[name] = value
+	}
+	return &derived
 }
-func GetLogger() WebLogger {
-	return &Log{}
+
+func (l *Log) WithContext(ctx context.Context) WebLogger {
+	requestId := RequestIDFromContext(ctx)
+	if requestId == "" {
+		return l
+	}
+	return l.WithFields(Fields{constants.RequestIDKey: requestId})
+}
+
+func (l *Log) write(level Level, value []interface{}) {
+	if level < l.level {
+		return
+	}
+	message := formatMessage(value)
+	if level <= LevelInfo && !l.sampler.keep(level, message) {
+		return
+	}
+	var line bytes.Buffer
+	line.WriteString(`{"time":`)
+	writeJSON(&line, time.Now().Format(time.RFC3339Nano))
+	line.WriteString(`,"level":`)
+	writeJSON(&line, level.String())
+	line.WriteString(`,"msg":`)
+	writeJSON(&line, message)
+	names := make([]string, 0, len(l.fields))
+	for name := range l.fields {
+		if name != "time" && name != "level" && name != "msg" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		line.WriteString(",")
+		writeJSON(&line, name)
+		line.WriteString(":")
+		writeJSON(&line, redactField(name, l.fields[name]))
+	}
+	line.WriteString("}\n")
+	l.output.Lock()
+	defer l.output.Unlock()
+	l.output.writer.Write(line.Bytes())
+}
+
+// formatMessage joins the values with spaces like fmt.Println. Structs, maps and slices
+// are written as JSON so their secret fields can be redacted.
+func formatMessage(value []interface{}) string {
+	parts := make([]string, 0, len(value))
+	for _, part := range value {
+		switch typed := part.(type) {
+		case string:
+			parts = append(parts, typed)
+		case error:
+			parts = append(parts, typed.Error())
+		case fmt.Stringer:
+			parts = append(parts, typed.String())
+		default:
+			if isComposite(part) {
+				parts = append(parts, toRedactedJSON(part))
+			} else {
+				parts = append(parts, fmt.Sprint(part))
+			}
+		}
+	}
+	return redactText(strings.Join(parts, " "))
+}
+
+func writeJSON(line *bytes.Buffer, value interface{}) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprint(value))
+	}
+	line.Write(encoded)
+}
+
+// ContextWithRequestID returns ctx carrying the request ID the loggers derived with
+// WithContext add to their lines.
+func ContextWithRequestID(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, constants.RequestIDKey, requestId)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestId, _ := ctx.Value(constants.RequestIDKey).(string)
+	return requestId
 }
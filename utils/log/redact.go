@@ -0,0 +1,113 @@
+package log
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// field names holding credentials, matched case-insensitively and ignoring - and _:
+// exactly for secretNames, anywhere in the name for secretParts
+var secretNames = map[string]bool{"code": true, "recoverycodes": true}
+var secretParts = []string{"password", "secret", "token", "authorization", "cookie", "signature", "codehash", "accesskey"}
+
+// secrets that can show up inside a message: bearer credentials, JWTs, and key=value pairs
+// of a secret name
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	regexp.MustCompile(`(?i)\b(password|secret|token|authorization)(["']?\s*[:=]\s*["']?)[^\s"'&,}]+`),
+}
+
+func isSecret(name string) bool {
+	name = strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(name))
+	if secretNames[name] {
+		return true
+	}
+	for _, part := range secretParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactText(text string) string {
+	text = secretPatterns[0].ReplaceAllString(text, "$1 "+redacted)
+	text = secretPatterns[1].ReplaceAllString(text, redacted)
+	return secretPatterns[2].ReplaceAllString(text, "$1$2"+redacted)
+}
+
+// redactField is the value a field is logged with.
+func redactField(name string, value interface{}) interface{} {
+	if isSecret(name) {
+		return redacted
+	}
+	switch typed := value.(type) {
+	case string:
+		return redactText(typed)
+	case error:
+		return redactText(typed.Error())
+	}
+	if isComposite(value) {
+		return json.RawMessage(toRedactedJSON(value))
+	}
+	return value
+}
+
+func isComposite(value interface{}) bool {
+	valueType := reflect.TypeOf(value)
+	for valueType != nil && valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	if valueType == nil {
+		return false
+	}
+	switch valueType.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return valueType.Kind() != reflect.Slice || valueType.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// toRedactedJSON writes the value as JSON with its secret fields, at any depth, redacted.
+func toRedactedJSON(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return redacted
+	}
+	var decoded interface{}
+	decoder := json.NewDecoder(strings.NewReader(string(encoded)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return redacted
+	}
+	encoded, err = json.Marshal(redactValue(decoded))
+	if err != nil {
+		return redacted
+	}
+	return string(encoded)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for name, field := range typed {
+			if isSecret(name) {
+				typed[name] = redacted
+				continue
+			}
+			typed[name] = redactValue(field)
+		}
+	case []interface{}:
+		for i := range typed {
+			typed[i] = redactValue(typed[i])
+		}
+	case string:
+		return redactText(typed)
+	}
+	return value
+}
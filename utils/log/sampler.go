@@ -0,0 +1,39 @@
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+// sampler keeps the first initial lines of each level and message in a second, then every
+// thereafter-th, so a hot loop cannot flood the output.
+type sampler struct {
+	sync.Mutex
+	initial    int
+	thereafter int
+	second     int64
+	counts     map[string]int
+}
+
+func newSampler(initial int, thereafter int) *sampler {
+	return &sampler{initial: initial, thereafter: thereafter, counts: map[string]int{}}
+}
+
+func (s *sampler) keep(level Level, message string) bool {
+	if s.initial == 0 {
+		return true
+	}
+	s.Lock()
+	defer s.Unlock()
+	if now := time.Now().Unix(); now != s.second {
+		s.second = now
+		s.counts = map[string]int{}
+	}
+	key := level.String() + "|" + message
+	s.counts[key]++
+	count := s.counts[key]
+	if count <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (count-s.initial)%s.thereafter == 0
+}
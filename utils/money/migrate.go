@@ -0,0 +1,82 @@
+package money
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+type columnType struct {
+	DataType string
+}
+
+// migratingSuffix names the column the minor units are copied into while a column is
+// migrated. While it exists the migration is unfinished.
+const migratingSuffix = "__minor"
+
+// MigrateFloatColumn converts a legacy float column holding major units (1000.50)
+// into a BIGINT of minor units (100050). definition is the new column definition,
+// e.g. "BIGINT NOT NULL". It is a no-op once the column is migrated.
+//
+// MySQL commits every ALTER TABLE on its own, so the amounts are not converted in place:
+// they are copied into a new column, the float column is dropped and the new one takes
+// its name. Each step leaves the table in a state the next boot picks up from, without
+// converting an amount twice.
+func MigrateFloatColumn(db *gorm.DB, table string, column string, definition string) error {
+	migrating := column + migratingSuffix
+	current, err := getColumnType(db, table, column)
+	if err != nil {
+		return err
+	}
+	copied, err := getColumnType(db, table, migrating)
+	if err != nil {
+		return err
+	}
+	isFloat := current == "double" || current == "float" || current == "decimal"
+	if copied == "" && !isFloat {
+		return nil
+	}
+
+	if copied == "" {
+		err = db.Exec(fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` %s", table, migrating, definition)).Error
+		if err != nil {
+			return fmt.Errorf("migrating %s.%s: %w", table, column, err)
+		}
+	}
+	// the float column is only dropped once copied; without it, the copy is complete and
+	// the column there, if any, was added back empty by AutoMigrate
+	if isFloat {
+		factor := 1
+		for i := 0; i < Exponent(DefaultCurrency); i++ {
+			factor *= 10
+		}
+		err = db.Exec(fmt.Sprintf("UPDATE `%s` SET `%s` = ROUND(`%s` * %d)", table, migrating, column, factor)).Error
+		if err != nil {
+			return fmt.Errorf("migrating %s.%s: %w", table, column, err)
+		}
+	}
+	if current != "" {
+		err = db.Exec(fmt.Sprintf("ALTER TABLE `%s` DROP COLUMN `%s`", table, column)).Error
+		if err != nil {
+			return fmt.Errorf("migrating %s.%s: %w", table, column, err)
+		}
+	}
+	err = db.Exec(fmt.Sprintf("ALTER TABLE `%s` CHANGE `%s` `%s` %s", table, migrating, column, definition)).Error
+	if err != nil {
+		return fmt.Errorf("migrating %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// getColumnType returns the data type of the column, empty when there is no such column.
+func getColumnType(db *gorm.DB, table string, column string) (string, error) {
+	current := columnType{}
+	err := db.Raw("SELECT DATA_TYPE AS data_type FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?", table, column).Scan(&current).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return current.DataType, nil
+}
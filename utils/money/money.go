@@ -0,0 +1,335 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used whenever an amount arrives without a currency code.
+var DefaultCurrency = "INR"
+
+// minor unit exponent per currency, anything not listed uses 2
+var currencyExponents = map[string]int{
+	"JPY": 0,
+	"KWD": 3,
+	"BHD": 3,
+}
+
+// Money is an exact amount in minor units (paise, cents...) of a currency.
+// It is stored in SQL as a BIGINT of minor units; the currency lives on the owning row.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+func New(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: normalizeCurrency(currency)}
+}
+
+func Zero(currency string) Money {
+	return New(0, currency)
+}
+
+// Parse reads a decimal string such as "1000", "1000.5" or "-12.34" into minor units.
+// More decimals than the currency allows is an error, nothing is rounded silently.
+func Parse(value string, currency string) (Money, error) {
+	currency = normalizeCurrency(currency)
+	exponent := Exponent(currency)
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Money{}, errors.New("money: empty amount")
+	}
+	negative := false
+	if value[0] == '-' || value[0] == '+' {
+		negative = value[0] == '-'
+		value = value[1:]
+	}
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(fraction) > exponent {
+		return Money{}, fmt.Errorf("money: %s allows at most %d decimal places", currency, exponent)
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("money: invalid amount %q", value)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func MustParse(value string, currency string) Money {
+	amount, err := Parse(value, currency)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
+
+func Exponent(currency string) int {
+	if exponent, ok := currencyExponents[normalizeCurrency(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+func normalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return currency
+}
+
+func (m Money) CurrencyCode() string {
+	return normalizeCurrency(m.Currency)
+}
+
+// InCurrency binds an amount to the currency of the account it belongs to. An unbound
+// amount (parsed without currency, so with DefaultCurrency precision) is rescaled; an
+// amount already in another currency is an error, use Convert for that.
+func (m Money) InCurrency(currency string) (Money, error) {
+	currency = normalizeCurrency(currency)
+	if m.Currency != "" {
+		if normalizeCurrency(m.Currency) != currency {
+			return Money{}, fmt.Errorf("money: amount is in %s, expected %s", normalizeCurrency(m.Currency), currency)
+		}
+		return Money{Minor: m.Minor, Currency: currency}, nil
+	}
+	return Parse(Money{Minor: m.Minor, Currency: DefaultCurrency}.Decimal(), currency)
+}
+
+// Convert multiplies the amount by rate (units of currency per unit of m's currency, as a
+// decimal string such as "83.1250") and rounds half away from zero to the target minor unit.
+func (m Money) Convert(rate string, currency string) (Money, error) {
+	ratio, ok := new(big.Rat).SetString(rate)
+	if !ok || ratio.Sign() <= 0 {
+		return Money{}, fmt.Errorf("money: invalid exchange rate %q", rate)
+	}
+	currency = normalizeCurrency(currency)
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Minor), ratio)
+	scale := Exponent(currency) - Exponent(m.Currency)
+	factor := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(scale))), nil))
+	if scale >= 0 {
+		value.Mul(value, factor)
+	} else {
+		value.Quo(value, factor)
+	}
+	return Money{Minor: roundRat(value), Currency: currency}, nil
+}
+
+func roundRat(value *big.Rat) int64 {
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	doubled := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2))
+	if doubled.Cmp(value.Denom()) >= 0 {
+		if value.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return quotient.Int64()
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+func (m Money) IsZero() bool     { return m.Minor == 0 }
+func (m Money) IsPositive() bool { return m.Minor > 0 }
+func (m Money) IsNegative() bool { return m.Minor < 0 }
+
+func (m Money) SameCurrency(other Money) bool {
+	return m.CurrencyCode() == other.CurrencyCode()
+}
+
+func (m Money) mustMatch(other Money) {
+	if !m.SameCurrency(other) {
+		panic(fmt.Sprintf("money: currency mismatch %s and %s", m.CurrencyCode(), other.CurrencyCode()))
+	}
+}
+
+func (m Money) Add(other Money) Money {
+	m.mustMatch(other)
+	return Money{Minor: m.Minor + other.Minor, Currency: m.CurrencyCode()}
+}
+
+func (m Money) Sub(other Money) Money {
+	m.mustMatch(other)
+	return Money{Minor: m.Minor - other.Minor, Currency: m.CurrencyCode()}
+}
+
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.CurrencyCode()}
+}
+
+func (m Money) Abs() Money {
+	if m.Minor < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+// Cmp returns -1, 0 or 1 like strings.Compare.
+func (m Money) Cmp(other Money) int {
+	m.mustMatch(other)
+	switch {
+	case m.Minor < other.Minor:
+		return -1
+	case m.Minor > other.Minor:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) LessThan(other Money) bool {
+	return m.Cmp(other) < 0
+}
+
+// Div splits the amount n ways, rounding half away from zero. Used for averages in reports.
+func (m Money) Div(n int64) Money {
+	if n == 0 {
+		return Zero(m.Currency)
+	}
+	quotient := m.Minor / n
+	remainder := m.Minor % n
+	if remainder*2 >= n || -remainder*2 >= n {
+		if (m.Minor < 0) != (n < 0) {
+			quotient--
+		} else {
+			quotient++
+		}
+	}
+	return Money{Minor: quotient, Currency: m.CurrencyCode()}
+}
+
+// Decimal formats the amount as a plain decimal string, e.g. "1000.50".
+func (m Money) Decimal() string {
+	exponent := Exponent(m.Currency)
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	digits := strconv.FormatInt(minor, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.CurrencyCode()
+}
+
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency,omitempty"`
+}
+
+// MarshalJSON writes {"amount":"1000.50","currency":"INR"}. The amount is a string so
+// clients never round-trip it through a float.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.CurrencyCode()})
+}
+
+// UnmarshalJSON accepts {"amount":..,"currency":..}, a bare decimal string or a bare number.
+// Without an explicit currency the amount stays unbound (Currency == "") until the
+// service binds it to the account it belongs to with InCurrency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = []byte(strings.TrimSpace(string(data)))
+	if string(data) == "null" {
+		return nil
+	}
+	currency := m.Currency
+	raw := data
+	if len(data) > 0 && data[0] == '{' {
+		tempMoney := moneyJSON{}
+		if err := json.Unmarshal(data, &tempMoney); err != nil {
+			return err
+		}
+		raw = tempMoney.Amount
+		if tempMoney.Currency != "" {
+			currency = tempMoney.Currency
+		}
+	}
+	value := strings.Trim(string(raw), `"`)
+	parsed, err := Parse(value, currency)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(currency) == "" {
+		parsed.Currency = ""
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the minor units.
+func (m Money) Value() (driver.Value, error) {
+	return m.Minor, nil
+}
+
+// Scan reads minor units back. SUM() over a BIGINT column comes back as a decimal string.
+func (m *Money) Scan(value interface{}) error {
+	var minor int64
+	switch v := value.(type) {
+	case nil:
+		minor = 0
+	case int64:
+		minor = v
+	case []byte:
+		parsed, err := scanInteger(string(v))
+		if err != nil {
+			return err
+		}
+		minor = parsed
+	case string:
+		parsed, err := scanInteger(v)
+		if err != nil {
+			return err
+		}
+		minor = parsed
+	default:
+		return fmt.Errorf("money: cannot scan %T", value)
+	}
+	m.Minor = minor
+	m.Currency = normalizeCurrency(m.Currency)
+	return nil
+}
+
+func scanInteger(value string) (int64, error) {
+	whole, fraction, _ := strings.Cut(value, ".")
+	if strings.Trim(fraction, "0") != "" {
+		return 0, fmt.Errorf("money: minor units must be whole, got %q", value)
+	}
+	return strconv.ParseInt(whole, 10, 64)
+}
+
+// ValidatorValue lets go-playground/validator apply numeric tags (gt=0...) to the minor units.
+func ValidatorValue(field reflect.Value) interface{} {
+	if m, ok := field.Interface().(Money); ok {
+		return m.Minor
+	}
+	return nil
+}
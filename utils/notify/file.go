@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileNotifier writes the messages to a file, or to the console, instead of sending them:
+// development gets to read the emails and text messages without an SMTP server or gateway.
+type FileNotifier struct {
+	mutex sync.Mutex
+	path  string    // appended to on every send
+	out   io.Writer // used when there is no path
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func NewConsoleNotifier() *FileNotifier {
+	return &FileNotifier{out: os.Stdout}
+}
+
+func (notifier *FileNotifier) Send(message Message) error {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	out := notifier.out
+	if notifier.path != "" {
+		file, err := os.OpenFile(notifier.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	_, err := fmt.Fprintf(out, "----- %s %s to %s -----\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), message.Channel, message.To, message.Subject, message.Text)
+	return err
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type GatewayConfig struct {
+	URL   string
+	Token string // sent as a bearer token when set
+	From  string // the sender name or number, when the gateway wants one
+}
+
+// GatewayNotifier sends text messages through an HTTP SMS gateway: it POSTs
+// {"to", "from", "text"} as JSON and takes any 2xx as accepted.
+type GatewayNotifier struct {
+	config GatewayConfig
+	client *http.Client
+}
+
+func NewGatewayNotifier(config GatewayConfig) *GatewayNotifier {
+	return &GatewayNotifier{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (notifier *GatewayNotifier) Send(message Message) error {
+	body, err := json.Marshal(map[string]string{
+		"to":   message.To,
+		"from": notifier.config.From,
+		"text": message.Text,
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, notifier.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if notifier.config.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+notifier.config.Token)
+	}
+	response, err := notifier.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		answer, _ := io.ReadAll(io.LimitReader(response.Body, 256))
+		return fmt.Errorf("sms gateway answered %d: %s", response.StatusCode, answer)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Package notify sends rendered notifications over their channel: email through SMTP, SMS
+// through an HTTP gateway, or a file or the console in development.
+package notify
+
+import (
+	"bankManagement/utils/log"
+	"fmt"
+	"os"
+	"sync"
+)
+
+var ChannelEmail = "email"
+var ChannelSMS = "sms"
+
+var Channels = []string{ChannelEmail, ChannelSMS}
+
+// Message is one rendered notification to one address.
+type Message struct {
+	Channel string
+	To      string // the email address or the phone number
+	Subject string
+	Text    string
+	HTML    string // empty on the channels that only carry text
+}
+
+type Notifier interface {
+	// Send hands the message to the channel, an error means the channel did not take it.
+	Send(message Message) error
+}
+
+// NewNotifiersFromEnv returns the notifier of each configured channel.
+//
+// NOTIFY_EMAIL picks how emails go out: "smtp" (smtp_email, smtp_password, smtp_host and
+// SMTP_PORT, the default when smtp_host is set), "file", "console" (the default otherwise)
+// or "none". NOTIFY_SMS is "http" (SMS_GATEWAY_URL, SMS_GATEWAY_TOKEN, SMS_GATEWAY_FROM),
+// "file", "console" or "none", the default. The file sink appends to NOTIFY_FILE.
+func NewNotifiersFromEnv(log log.WebLogger) (map[string]Notifier, error) {
+	notifiers := map[string]Notifier{}
+	emailDriver := os.Getenv("NOTIFY_EMAIL")
+	if emailDriver == "" {
+		emailDriver = "console"
+		if os.Getenv("smtp_host") != "" {
+			emailDriver = "smtp"
+		}
+	}
+	smsDriver := os.Getenv("NOTIFY_SMS")
+	if smsDriver == "" {
+		smsDriver = "none"
+	}
+	for channel, driver := range map[string]string{ChannelEmail: emailDriver, ChannelSMS: smsDriver} {
+		var notifier Notifier
+		switch {
+		case driver == "none":
+			continue
+		case driver == "console":
+			notifier = NewConsoleNotifier()
+		case driver == "file":
+			path := os.Getenv("NOTIFY_FILE")
+			if path == "" {
+				path = "notifications.log"
+			}
+			notifier = NewFileNotifier(path)
+		case driver == "smtp" && channel == ChannelEmail:
+			port := os.Getenv("SMTP_PORT")
+			if port == "" {
+				port = "587"
+			}
+			notifier = NewSMTPNotifier(SMTPConfig{
+				Host:     os.Getenv("smtp_host"),
+				Port:     port,
+				Username: os.Getenv("smtp_email"),
+				Password: os.Getenv("smtp_password"),
+				From:     os.Getenv("smtp_email"),
+			})
+		case driver == "http" && channel == ChannelSMS:
+			if os.Getenv("SMS_GATEWAY_URL") == "" {
+				return nil, fmt.Errorf("NOTIFY_SMS=http needs SMS_GATEWAY_URL")
+			}
+			notifier = NewGatewayNotifier(GatewayConfig{
+				URL:   os.Getenv("SMS_GATEWAY_URL"),
+				Token: os.Getenv("SMS_GATEWAY_TOKEN"),
+				From:  os.Getenv("SMS_GATEWAY_FROM"),
+			})
+		default:
+			return nil, fmt.Errorf("unknown %s notifier %q", channel, driver)
+		}
+		log.Info("Notifications on ", channel, " sent with ", driver)
+		notifiers[channel] = notifier
+	}
+	return notifiers, nil
+}
+
+// FakeNotifier keeps the messages rather than sending them, for tests. Set Err to have the
+// sends fail.
+type FakeNotifier struct {
+	mutex sync.Mutex
+	sent  []Message
+	Err   error
+}
+
+func (notifier *FakeNotifier) Send(message Message) error {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	if notifier.Err != nil {
+		return notifier.Err
+	}
+	notifier.sent = append(notifier.sent, message)
+	return nil
+}
+
+// Sent returns the messages sent so far.
+func (notifier *FakeNotifier) Sent() []Message {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	return append([]Message(nil), notifier.sent...)
+}
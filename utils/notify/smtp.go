@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPNotifier sends emails, as text and HTML alternatives when the message has both.
+type SMTPNotifier struct {
+	config SMTPConfig
+	auth   smtp.Auth
+}
+
+func NewSMTPNotifier(config SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{
+		config: config,
+		auth:   smtp.PlainAuth("", config.Username, config.Password, config.Host),
+	}
+}
+
+func (notifier *SMTPNotifier) Send(message Message) error {
+	body, err := notifier.compose(message)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(notifier.config.Host+":"+notifier.config.Port, notifier.auth, notifier.config.From, []string{message.To}, body)
+}
+
+func (notifier *SMTPNotifier) compose(message Message) ([]byte, error) {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\n",
+		notifier.config.From, message.To, mime.QEncoding.Encode("utf-8", message.Subject), time.Now().Format(time.RFC1123Z))
+	if message.HTML == "" {
+		fmt.Fprintf(&buffer, "Content-Type: text/plain; charset=utf-8\r\n\r\n%s", message.Text)
+		return buffer.Bytes(), nil
+	}
+
+	var parts bytes.Buffer
+	writer := multipart.NewWriter(&parts)
+	fmt.Fprintf(&buffer, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", message.Text},
+		{"text/html; charset=utf-8", message.HTML},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		partWriter.Write([]byte(part.content))
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	buffer.Write(parts.Bytes())
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmlTemplate "html/template"
+	"strings"
+	textTemplate "text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// Templates renders the notification of each event type. The file of an event defines
+// "<event>/subject", "<event>/text" and "<event>/html", and "<event>/sms" for a text message
+// shorter than the email text. The html is escaped as HTML and put in the "layout".
+type Templates struct {
+	text *textTemplate.Template
+	html *htmlTemplate.Template
+}
+
+func LoadTemplates() (*Templates, error) {
+	text, err := textTemplate.ParseFS(templateFiles, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmlTemplate.ParseFS(templateFiles, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	return &Templates{text: text, html: html}, nil
+}
+
+// Has tells whether there is a template for the event type.
+func (templates *Templates) Has(eventType string) bool {
+	return templates.text.Lookup(eventType+"/subject") != nil
+}
+
+// Render renders the event's message for the channel.
+func (templates *Templates) Render(eventType string, channel string, data interface{}) (Message, error) {
+	if !templates.Has(eventType) {
+		return Message{}, fmt.Errorf("no notification template for %s", eventType)
+	}
+	message := Message{Channel: channel}
+	var err error
+	message.Subject, err = templates.renderText(eventType+"/subject", data)
+	if err != nil {
+		return Message{}, err
+	}
+	message.Subject = strings.TrimSpace(message.Subject)
+	if channel == ChannelSMS && templates.text.Lookup(eventType+"/sms") != nil {
+		message.Text, err = templates.renderText(eventType+"/sms", data)
+		message.Text = strings.TrimSpace(message.Text)
+		return message, err
+	}
+	message.Text, err = templates.renderText(eventType+"/text", data)
+	message.Text = strings.TrimSpace(message.Text)
+	if err != nil || channel != ChannelEmail {
+		return message, err
+	}
+
+	var body bytes.Buffer
+	err = templates.html.ExecuteTemplate(&body, eventType+"/html", data)
+	if err != nil {
+		return Message{}, err
+	}
+	var page bytes.Buffer
+	err = templates.html.ExecuteTemplate(&page, "layout", map[string]interface{}{
+		"Subject": message.Subject,
+		"Body":    htmlTemplate.HTML(body.String()),
+	})
+	if err != nil {
+		return Message{}, err
+	}
+	message.HTML = page.String()
+	return message, nil
+}
+
+func (templates *Templates) renderText(name string, data interface{}) (string, error) {
+	var out bytes.Buffer
+	err := templates.text.ExecuteTemplate(&out, name, data)
+	return out.String(), err
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"bankManagement/utils/log"
+	"sync"
+	"time"
+)
+
+// Job is a unit of background work the scheduler runs on every tick.
+// Run receives the tick time so jobs agree on what "now" is.
+type Job interface {
+	Name() string
+	Run(now time.Time) error
+}
+
+// Scheduler runs its registered jobs one after another every interval, in a
+// single goroutine tracked by the app's WaitGroup.
+type Scheduler struct {
+	sync.Mutex
+	interval time.Duration
+	jobs     []Job
+	log      log.WebLogger
+	wg       *sync.WaitGroup
+	stop     chan struct{}
+}
+
+func NewScheduler(interval time.Duration, log log.WebLogger, wg *sync.WaitGroup) *Scheduler {
+	return &Scheduler{
+		interval: interval,
+		log:      log,
+		wg:       wg,
+	}
+}
+
+func (s *Scheduler) Register(job Job) {
+	s.Lock()
+	defer s.Unlock()
+	s.jobs = append(s.jobs, job)
+}
+
+// Start launches the ticking goroutine. Calling it twice is a no-op.
+func (s *Scheduler) Start() {
+	s.Lock()
+	defer s.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.wg.Add(1)
+	go s.loop(s.stop)
+	s.log.Info("Scheduler started, ticking every", s.interval)
+}
+
+// Stop ends the ticking goroutine once the current tick is finished.
+func (s *Scheduler) Stop() {
+	s.Lock()
+	defer s.Unlock()
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	s.stop = nil
+}
+
+func (s *Scheduler) loop(stop chan struct{}) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	s.tick(time.Now())
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			s.tick(now)
+		}
+	}
+}
+
+func (s *Scheduler) tick(now time.Time) {
+	s.Lock()
+	jobs := append([]Job{}, s.jobs...)
+	s.Unlock()
+	for _, job := range jobs {
+		s.runJob(job, now)
+	}
+}
+
+// runJob keeps one failing or panicking job from taking the scheduler down.
+func (s *Scheduler) runJob(job Job, now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.log.Error("Scheduled job", job.Name(), "panicked:", r)
+		}
+	}()
+	if err := job.Run(now); err != nil {
+		s.log.Error("Scheduled job", job.Name(), "failed:", err)
+	}
+}
@@ -0,0 +1,43 @@
+package storage_test
+
+import (
+	"bankManagement/utils/storage"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	root := t.TempDir()
+	store, err := storage.NewLocalStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exerciseStore(t, store)
+
+	// no temp file is left behind next to the blobs
+	err = filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() && filepath.Base(path)[0] == '.' {
+			t.Errorf("left behind %s", path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalStoreRefusesKeysOutsideItsRoot(t *testing.T) {
+	store, err := storage.NewLocalStore(filepath.Join(t.TempDir(), "documents"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"../escaped", "documents/../../escaped", "/etc/passwd", ""} {
+		if err := store.Put(key, []byte("x"), "text/plain"); err == nil {
+			t.Errorf("put accepted the key %q", key)
+		}
+		if _, err := store.Get(key); err == nil {
+			t.Errorf("get accepted the key %q", key)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type S3Config struct {
+	Endpoint        string // e.g. https://s3.ap-south-1.amazonaws.com or http://localhost:9000 for MinIO
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3Store speaks the S3 REST API with path-style URLs and Signature V4, so it works
+// with AWS as well as local stand-ins such as MinIO.
+type S3Store struct {
+	config S3Config
+	client *http.Client
+}
+
+// NewS3Store validates the config; a nil client uses a client with a 60s timeout.
+func NewS3Store(config S3Config, client *http.Client) (*S3Store, error) {
+	if config.Endpoint == "" || config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("storage: S3 endpoint, bucket and credentials are required")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &S3Store{config: config, client: client}, nil
+}
+
+func (store *S3Store) Put(key string, content []byte, contentType string) error {
+	response, err := store.do(http.MethodPut, key, content, contentType)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	return checkS3Response(response, http.StatusOK)
+}
+
+func (store *S3Store) Get(key string) (io.ReadCloser, error) {
+	response, err := store.do(http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNotFound {
+		response.Body.Close()
+		return nil, ErrNotFound
+	}
+	if err := checkS3Response(response, http.StatusOK); err != nil {
+		response.Body.Close()
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+func (store *S3Store) Delete(key string) error {
+	response, err := store.do(http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkS3Response(response, http.StatusNoContent, http.StatusOK)
+}
+
+func (store *S3Store) do(method string, key string, content []byte, contentType string) (*http.Response, error) {
+	endpoint, err := url.Parse(store.config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("storage: invalid S3 endpoint: %w", err)
+	}
+	canonicalURI := endpoint.Path + "/" + s3Escape(store.config.Bucket) + "/" + s3EscapePath(key)
+	request, err := http.NewRequest(method, endpoint.Scheme+"://"+endpoint.Host+canonicalURI, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	store.sign(request, canonicalURI, content, time.Now().UTC())
+	return store.client.Do(request)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header.
+func (store *S3Store) sign(request *http.Request, canonicalURI string, content []byte, now time.Time) {
+	payloadHash := sha256Hex(content)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		canonicalURI,
+		"", // no query string
+		"host:" + request.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + store.config.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+store.config.SecretAccessKey), date)
+	for _, part := range []string{store.config.Region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		store.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func checkS3Response(response *http.Response, expected ...int) error {
+	for _, status := range expected {
+		if response.StatusCode == status {
+			return nil
+		}
+	}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	return fmt.Errorf("storage: S3 returned %s: %s", response.Status, strings.TrimSpace(string(body)))
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+func s3EscapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3Escape percent-encodes everything but the RFC 3986 unreserved characters, as SigV4 requires.
+func s3Escape(value string) string {
+	var escaped strings.Builder
+	for _, char := range []byte(value) {
+		if ('A' <= char && char <= 'Z') || ('a' <= char && char <= 'z') || ('0' <= char && char <= '9') ||
+			char == '-' || char == '_' || char == '.' || char == '~' {
+			escaped.WriteByte(char)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", char)
+		}
+	}
+	return escaped.String()
+}
@@ -0,0 +1,52 @@
+// Package storage keeps uploaded files (KYC documents...) out of the database. Blobs are
+// content addressed: the key is derived from the SHA-256 of the bytes, so uploading the
+// same file twice stores it once.
+package storage
+
+import (
+	"bankManagement/utils/log"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+)
+
+var ErrNotFound = errors.New("storage: blob not found")
+
+type DocumentStore interface {
+	// Put stores content under key; storing an existing key again is a no-op.
+	Put(key string, content []byte, contentType string) error
+	// Get streams the blob, the caller closes the reader.
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the blob, deleting a missing key is not an error.
+	Delete(key string) error
+}
+
+// ContentKey returns the hex SHA-256 of content and the key it is stored under.
+func ContentKey(content []byte) (hash string, key string) {
+	sum := sha256.Sum256(content)
+	hash = hex.EncodeToString(sum[:])
+	return hash, "documents/sha256/" + hash[:2] + "/" + hash
+}
+
+// NewDocumentStore picks the backend from DOCUMENT_STORE: "s3" talks to any S3
+// compatible endpoint (S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY_ID,
+// S3_SECRET_ACCESS_KEY), anything else writes below DOCUMENT_STORE_DIR.
+func NewDocumentStore(log log.WebLogger) (DocumentStore, error) {
+	if os.Getenv("DOCUMENT_STORE") == "s3" {
+		return NewS3Store(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		}, nil)
+	}
+	dir := os.Getenv("DOCUMENT_STORE_DIR")
+	if dir == "" {
+		dir = "documents"
+	}
+	log.Info("Documents stored on local disk in ", dir)
+	return NewLocalStore(dir)
+}
@@ -0,0 +1,87 @@
+package storage_test
+
+import (
+	"bankManagement/utils/storage"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestContentKey(t *testing.T) {
+	hash, key := storage.ContentKey([]byte("passport scan"))
+	again, sameKey := storage.ContentKey([]byte("passport scan"))
+	if hash != again || key != sameKey {
+		t.Fatalf("the same bytes got keys %s and %s", key, sameKey)
+	}
+	if _, other := storage.ContentKey([]byte("utility bill")); other == key {
+		t.Fatalf("different bytes share the key %s", key)
+	}
+	sum := sha256.Sum256([]byte("passport scan"))
+	if hash != hex.EncodeToString(sum[:]) || key != "documents/sha256/"+hash[:2]+"/"+hash {
+		t.Errorf("ContentKey returned %s, %s", hash, key)
+	}
+}
+
+// exerciseStore puts, gets, streams and deletes content addressed blobs through the store.
+func exerciseStore(t *testing.T, store storage.DocumentStore) {
+	t.Helper()
+	content := []byte("%PDF-1.7 passport scan")
+	_, key := storage.ContentKey(content)
+	if err := store.Put(key, content, "application/pdf"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	// uploading the same file again is the same key, and no error
+	if err := store.Put(key, content, "application/pdf"); err != nil {
+		t.Fatalf("put again: %v", err)
+	}
+	if got := readBlob(t, store, key); !bytes.Equal(got, content) {
+		t.Errorf("get returned %q, put %q", got, content)
+	}
+
+	// a blob larger than any buffer on the way streams through intact
+	large := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+	largeHash, largeKey := storage.ContentKey(large)
+	if err := store.Put(largeKey, large, "application/octet-stream"); err != nil {
+		t.Fatalf("put large: %v", err)
+	}
+	reader, err := store.Get(largeKey)
+	if err != nil {
+		t.Fatalf("get large: %v", err)
+	}
+	digest := sha256.New()
+	written, err := io.Copy(digest, reader)
+	reader.Close()
+	if err != nil || written != int64(len(large)) || hex.EncodeToString(digest.Sum(nil)) != largeHash {
+		t.Errorf("streamed %d of %d bytes (%v), the hash does not match its key", written, len(large), err)
+	}
+
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.Get(key); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("get after delete returned %v, expected ErrNotFound", err)
+	}
+	if err := store.Delete(key); err != nil {
+		t.Errorf("deleting a missing key returned %v", err)
+	}
+	if got := readBlob(t, store, largeKey); len(got) != len(large) {
+		t.Errorf("deleting one blob touched another")
+	}
+}
+
+func readBlob(t *testing.T, store storage.DocumentStore, key string) []byte {
+	t.Helper()
+	reader, err := store.Get(key)
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("reading %s: %v", key, err)
+	}
+	return content
+}
@@ -0,0 +1,61 @@
+// Package testdb gives a test a MySQL database of its own, migrated and seeded like the
+// app's. TEST_DATABASE_DSN names a server the tests may create databases on, in the form
+// main.go connects with but without the database, e.g. root:admin@123@(localhost:3306)/ .
+// The tests that need a database are skipped without it.
+package testdb
+
+import (
+	"bankManagement/app"
+	"bankManagement/modules"
+	"bankManagement/repository"
+	"bankManagement/seeder"
+	"bankManagement/utils/log"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+)
+
+const connectionOptions = "?charset=utf8&parseTime=True&loc=Local"
+
+// Open creates a database for the test and returns an app on it, without routes. The
+// database is dropped when the test ends.
+func Open(t testing.TB) *app.App {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	server, err := gorm.Open("mysql", dsn+connectionOptions)
+	if err != nil {
+		t.Fatalf("connecting to TEST_DATABASE_DSN: %v", err)
+	}
+	name := fmt.Sprintf("bank_test_%d", time.Now().UnixNano())
+	if err := server.Exec("CREATE DATABASE `" + name + "`").Error; err != nil {
+		server.Close()
+		t.Fatalf("creating the test database: %v", err)
+	}
+	db, err := gorm.Open("mysql", dsn+name+connectionOptions)
+	if err != nil {
+		server.Close()
+		t.Fatalf("connecting to the test database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		server.Exec("DROP DATABASE `" + name + "`")
+		server.Close()
+	})
+
+	logger := log.NewLogger(&log.Config{Level: log.LevelError, Output: io.Discard})
+	log.SetLogger(logger)
+	db.SetLogger(log.GormLogger{Log: logger})
+	appObj := app.NewApp("test", db, logger, &sync.WaitGroup{}, repository.NewGormRepositoryMySQL())
+	modules.RegisterTableMigrations(appObj)
+	seeder.SeedRoles(db)
+	return appObj
+}
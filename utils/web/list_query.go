@@ -0,0 +1,176 @@
+package web
+
+import (
+	"bankManagement/constants"
+	"bankManagement/repository"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ListOptions whitelists what a list endpoint can be sorted and filtered on, mapping
+// each query parameter name to its column.
+type ListOptions struct {
+	SortFields   map[string]string
+	FilterFields map[string]string
+	DefaultSort  string // e.g. "-created_at"
+}
+
+type listFilter struct {
+	column string
+	values []interface{}
+}
+
+// ListQuery is the parsed paging, sorting and filtering of a list request:
+//
+//	?page=2&size=50          1-based page, size capped at constants.MaxPageSize
+//	?sort=-created_at,name   comma separated, "-" for descending
+//	?status=Pending,Approved whitelisted field, comma separated values match any
+//
+// TotalCount is filled in when the query runs.
+type ListQuery struct {
+	Page       int
+	Size       int
+	TotalCount int
+	order      []string
+	filters    []listFilter
+}
+
+func ParseListQuery(r *http.Request, options ListOptions) (*ListQuery, error) {
+	params := r.URL.Query()
+	query := &ListQuery{Page: 1, Size: constants.DefaultPageSize}
+	var err error
+	if value := params.Get("page"); value != "" {
+		query.Page, err = strconv.Atoi(value)
+		if err != nil || query.Page < 1 {
+			return nil, fmt.Errorf("page should be a positive int")
+		}
+	}
+	if value := params.Get("size"); value != "" {
+		query.Size, err = strconv.Atoi(value)
+		if err != nil || query.Size < 1 || query.Size > constants.MaxPageSize {
+			return nil, fmt.Errorf("size should be between 1 and %d", constants.MaxPageSize)
+		}
+	}
+
+	sortParam := params.Get("sort")
+	if sortParam == "" {
+		sortParam = options.DefaultSort
+	}
+	for _, field := range strings.Split(sortParam, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		direction := "ASC"
+		if strings.HasPrefix(field, "-") {
+			direction = "DESC"
+			field = field[1:]
+		}
+		column, ok := options.SortFields[field]
+		if !ok {
+			return nil, fmt.Errorf("cannot sort by %q, allowed: %s", field, allowedFields(options.SortFields))
+		}
+		query.order = append(query.order, column+" "+direction)
+	}
+	// a unique tie-breaker keeps pages stable
+	if !sortsById(query.order) {
+		query.order = append(query.order, "id ASC")
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "page" || name == "size" || name == "sort" {
+			continue
+		}
+		column, ok := options.FilterFields[name]
+		if !ok {
+			return nil, fmt.Errorf("cannot filter by %q, allowed: %s", name, allowedFields(options.FilterFields))
+		}
+		filter := listFilter{column: column}
+		for _, value := range strings.Split(params.Get(name), ",") {
+			filter.values = append(filter.values, filterValue(strings.TrimSpace(value)))
+		}
+		query.filters = append(query.filters, filter)
+	}
+	return query, nil
+}
+
+// QueryProcessors filters, counts into TotalCount, pages and sorts. Pass them after the
+// endpoint's own scoping filters. A nil query lists everything.
+func (query *ListQuery) QueryProcessors(repo repository.Repository) []repository.QueryProcessor {
+	if query == nil {
+		return nil
+	}
+	var queryProcessors []repository.QueryProcessor
+	for _, filter := range query.filters {
+		queryProcessors = append(queryProcessors, repo.Filter(filter.column+" IN (?)", filter.values))
+	}
+	queryProcessors = append(queryProcessors, repo.Count(query.Size, (query.Page-1)*query.Size, &query.TotalCount))
+	for _, order := range query.order {
+		queryProcessors = append(queryProcessors, repo.Order(order))
+	}
+	return queryProcessors
+}
+
+// SetListHeaders sends X-Total-Count and a Link header with the first, prev, next and
+// last pages of the request.
+func SetListHeaders(w http.ResponseWriter, r *http.Request, query *ListQuery) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(query.TotalCount))
+	lastPage := (query.TotalCount + query.Size - 1) / query.Size
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	link := func(page int, rel string) string {
+		params := r.URL.Query()
+		params.Set("page", strconv.Itoa(page))
+		params.Set("size", strconv.Itoa(query.Size))
+		target := url.URL{Path: r.URL.Path, RawQuery: params.Encode()}
+		return fmt.Sprintf(`<%s>; rel="%s"`, target.String(), rel)
+	}
+	links := []string{link(1, "first")}
+	if query.Page > 1 {
+		links = append(links, link(min(query.Page-1, lastPage), "prev"))
+	}
+	if query.Page < lastPage {
+		links = append(links, link(query.Page+1, "next"))
+	}
+	links = append(links, link(lastPage, "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+func sortsById(order []string) bool {
+	for _, column := range order {
+		if strings.HasPrefix(column, "id ") {
+			return true
+		}
+	}
+	return false
+}
+
+// filterValue lets boolean columns be filtered with true/false.
+func filterValue(value string) interface{} {
+	switch strings.ToLower(value) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return value
+}
+
+func allowedFields(fields map[string]string) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -3,6 +3,7 @@ package web
 import (
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 
@@ -29,3 +30,13 @@ func NewParser(request *http.Request) *Parser {
 	}
 
 }
+
+// ClientIP is the address the request came from. X-Forwarded-For is not trusted, a client
+// could set it to dodge per-address limits.
+func ClientIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
@@ -1,12 +1,17 @@
 package web
 
-import "github.com/go-playground/validator/v10"
+import (
+	"bankManagement/utils/money"
+
+	"github.com/go-playground/validator/v10"
+)
 
 var validate *validator.Validate
 
 func GetValidator() *validator.Validate {
 	if validate == nil {
 		validate = validator.New(validator.WithRequiredStructEnabled())
+		validate.RegisterCustomTypeFunc(money.ValidatorValue, money.Money{})
 	}
 	return validate
 }
@@ -14,3 +19,16 @@ func GetValidator() *validator.Validate {
 func GetValidationError(err error) string {
 	return err.(validator.ValidationErrors)[0].Error()
 }
+
+// GetValidationErrors lists every failed field, for reports that show all problems at once.
+func GetValidationErrors(err error) []string {
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return []string{err.Error()}
+	}
+	messages := make([]string, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		messages = append(messages, fieldError.Error())
+	}
+	return messages
+}